@@ -2,7 +2,6 @@ package controller
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"golang-api/api/responses"
 	"golang-api/config"
@@ -15,38 +14,30 @@ import (
 )
 
 func LoginUser(w http.ResponseWriter, r *http.Request) {
-	var user map[string]interface{}
-
-	// Membaca data JSON dari body permintaan
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		responses.ErrorResponse(w, "Gagal membaca data pengguna dari permintaan", http.StatusBadRequest)
-		return
-	}
-
-	// Mendapatkan email dan password dari data pengguna
-	email, ok := user["email"].(string)
-	if !ok {
-		responses.ErrorResponse(w, "Email harus diisi", http.StatusBadRequest)
-		return
+	var user struct {
+		Email    string `json:"email" validate:"required"`
+		Password string `json:"password" validate:"required"`
 	}
 
-	password, ok := user["password"].(string)
-	if !ok {
-		responses.ErrorResponse(w, "Password harus diisi", http.StatusBadRequest)
+	// Membaca data JSON dari body permintaan
+	if !decodeRequest(w, r, &user) {
 		return
 	}
+	email, password := user.Email, user.Password
 
 	// Mengecek apakah pengguna ada di database dan mengambil ID dan password dari database
 	var userID int
 	var dbPassword string
 	var dbName string
-	err := config.DB.QueryRow("SELECT id, name, password FROM users WHERE email=?", email).Scan(&userID, &dbName, &dbPassword)
+	var storeID sql.NullInt64
+	var headOffice bool
+	err := config.DB.QueryRow("SELECT id, name, password, store_id, head_office FROM users WHERE email=? AND deleted_at IS NULL", email).Scan(&userID, &dbName, &dbPassword, &storeID, &headOffice)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			responses.ErrorResponse(w, "User tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -56,6 +47,12 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// pengguna yang bukan kantor pusat harus terhubung ke store sebelum dapat login
+	if !headOffice && !storeID.Valid {
+		responses.ErrorResponse(w, "Akun belum terhubung ke store, hubungi kantor pusat", http.StatusForbidden)
+		return
+	}
+
 	// Jika login berhasil, buat token JWT
 	token := jwt.New(jwt.SigningMethodHS256)
 
@@ -63,6 +60,11 @@ func LoginUser(w http.ResponseWriter, r *http.Request) {
 	claims := token.Claims.(jwt.MapClaims)
 	claims["user_id"] = userID
 	claims["username"] = dbName
+	claims["head_office"] = headOffice
+	claims["store_id"] = nil // pengguna kantor pusat tidak terikat ke satu store
+	if storeID.Valid && !headOffice {
+		claims["store_id"] = storeID.Int64
+	}
 	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // Token berlaku selama 24 jam
 
 	// Menandatangani token dengan secret key
@@ -0,0 +1,514 @@
+package controller
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// internalBarcodePrefix adalah prefix GS1 20 yang dicadangkan untuk kode internal toko,
+// sehingga tidak bentrok dengan barcode pabrik.
+const internalBarcodePrefix = "20"
+
+// prefix default barcode timbangan. Prefix 20 tidak dipakai karena sudah menjadi kode internal.
+const (
+	defaultScaleWeightPrefixes = "21,22,23,24,25"
+	defaultScalePricePrefixes  = "26,27,28,29"
+)
+
+// jenis nilai yang ditanam pada barcode timbangan
+const (
+	scaleBarcodeWeight = "weight"
+	scaleBarcodePrice  = "price"
+)
+
+// scaleBarcode adalah isi barcode EAN-13 yang dicetak timbangan: 2 digit prefix,
+// 5 digit PLU, 5 digit nilai (berat dalam gram atau harga dalam rupiah) dan check digit.
+type scaleBarcode struct {
+	Type  string `json:"type"`
+	PLU   string `json:"plu"`
+	Value int    `json:"value"`
+}
+
+// pola modul EAN-13 untuk setiap digit: set L dan G di sisi kiri, set R di sisi kanan
+var (
+	ean13LCodes = []string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+	ean13GCodes = []string{"0100111", "0110011", "0011011", "0100001", "0011101", "0111001", "0000101", "0010001", "0001001", "0010111"}
+	ean13RCodes = []string{"1110010", "1100110", "1101100", "1000010", "1011100", "1001110", "1010000", "1000100", "1001000", "1110100"}
+
+	// digit pertama EAN-13 ditentukan oleh kombinasi set L/G pada enam digit kiri
+	ean13Parity = []string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+)
+
+// isDigits memeriksa apakah string hanya berisi angka.
+func isDigits(code string) bool {
+	if code == "" {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ean13CheckDigit menghitung check digit untuk 12 digit pertama EAN-13.
+func ean13CheckDigit(digits string) int {
+	sum := 0
+	for i, c := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(c-'0') * weight
+	}
+	return (10 - sum%10) % 10
+}
+
+// validEAN13 memeriksa panjang dan check digit kode EAN-13.
+func validEAN13(code string) bool {
+	if len(code) != 13 || !isDigits(code) {
+		return false
+	}
+	return ean13CheckDigit(code[:12]) == int(code[12]-'0')
+}
+
+// validBarcode memastikan barcode tidak kosong dan, jika berupa 13 digit angka,
+// memiliki check digit EAN-13 yang benar.
+func validBarcode(code string) bool {
+	if strings.TrimSpace(code) == "" || len(code) > 64 {
+		return false
+	}
+	if len(code) == 13 && isDigits(code) {
+		return validEAN13(code)
+	}
+	return true
+}
+
+// barcodeTaken memeriksa apakah barcode sudah dipakai produk lain selain excludeID.
+func barcodeTaken(code string, excludeID int64) (bool, error) {
+	var count int
+	err := config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE barcode = ? AND id <> ?", code, excludeID).Scan(&count)
+	return count > 0, err
+}
+
+// validPLU memeriksa bahwa PLU terdiri dari 5 digit angka, sesuai posisinya di barcode timbangan.
+func validPLU(plu string) bool {
+	return len(plu) == 5 && isDigits(plu)
+}
+
+// pluTaken memeriksa apakah PLU sudah dipakai produk lain selain excludeID.
+func pluTaken(plu string, excludeID int64) (bool, error) {
+	var count int
+	err := config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE plu = ? AND id <> ?", plu, excludeID).Scan(&count)
+	return count > 0, err
+}
+
+// scaleBarcodePrefixes membaca daftar prefix dari variabel environment, dipisahkan koma.
+func scaleBarcodePrefixes(env string, defaults string) []string {
+	value := os.Getenv(env)
+	if value == "" {
+		value = defaults
+	}
+
+	var prefixes []string
+	for _, prefix := range strings.Split(value, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if len(prefix) == 2 && isDigits(prefix) && prefix != internalBarcodePrefix {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// parseScaleBarcode membaca barcode timbangan. Prefix berat dan harga diatur lewat
+// SCALE_WEIGHT_PREFIXES dan SCALE_PRICE_PREFIXES.
+func parseScaleBarcode(code string) (scaleBarcode, bool) {
+	if !validEAN13(code) {
+		return scaleBarcode{}, false
+	}
+
+	barcode := scaleBarcode{PLU: code[2:7]}
+	barcode.Value, _ = strconv.Atoi(code[7:12])
+
+	for _, prefix := range scaleBarcodePrefixes("SCALE_WEIGHT_PREFIXES", defaultScaleWeightPrefixes) {
+		if code[:2] == prefix {
+			barcode.Type = scaleBarcodeWeight
+			return barcode, true
+		}
+	}
+	for _, prefix := range scaleBarcodePrefixes("SCALE_PRICE_PREFIXES", defaultScalePricePrefixes) {
+		if code[:2] == prefix {
+			barcode.Type = scaleBarcodePrice
+			return barcode, true
+		}
+	}
+	return scaleBarcode{}, false
+}
+
+// weightQty mengubah berat dalam gram dari barcode timbangan menjadi qty sesuai satuan produk.
+func weightQty(grams int, unit string) float64 {
+	if unit == "g" || unit == "ml" {
+		return float64(grams)
+	}
+	return float64(grams) / 1000
+}
+
+// scaleQty menghitung qty dan total harga dari barcode timbangan. Untuk barcode berat total
+// adalah harga satuan dikali berat, sedangkan untuk barcode harga total mengikuti harga yang
+// dicetak dan qty dihitung balik dari harga satuan.
+func scaleQty(barcode scaleBarcode, unit string, precision int, price int) (float64, int) {
+	scale := math.Pow(10, float64(precision))
+	if barcode.Type == scaleBarcodeWeight {
+		qty := math.Round(weightQty(barcode.Value, unit)*scale) / scale
+		return qty, int(math.Round(qty * float64(price)))
+	}
+
+	if price <= 0 {
+		return 0, barcode.Value
+	}
+	qty := math.Round(float64(barcode.Value)/float64(price)*scale) / scale
+	return qty, barcode.Value
+}
+
+// internalEAN13 membuat kode EAN-13 internal dari ID produk.
+func internalEAN13(productID int64) string {
+	digits := fmt.Sprintf("%s%010d", internalBarcodePrefix, productID)
+	return digits + strconv.Itoa(ean13CheckDigit(digits))
+}
+
+// ean13Modules mengubah kode EAN-13 menjadi 95 modul, true berarti batang hitam.
+func ean13Modules(code string) []bool {
+	pattern := "101"
+	parity := ean13Parity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		digit := code[i] - '0'
+		if parity[i-1] == 'L' {
+			pattern += ean13LCodes[digit]
+		} else {
+			pattern += ean13GCodes[digit]
+		}
+	}
+	pattern += "01010"
+	for i := 7; i <= 12; i++ {
+		pattern += ean13RCodes[code[i]-'0']
+	}
+	pattern += "101"
+
+	modules := make([]bool, len(pattern))
+	for i, c := range pattern {
+		modules[i] = c == '1'
+	}
+	return modules
+}
+
+// ean13GuardModule menandai modul pembatas (awal, tengah, akhir) yang digambar lebih panjang.
+func ean13GuardModule(i int) bool {
+	return i < 3 || (i >= 45 && i < 50) || i >= 92
+}
+
+// renderBarcodeSVG menggambar label EAN-13 dalam format SVG.
+func renderBarcodeSVG(code string) []byte {
+	const moduleWidth = 2
+	const quietZone = 11
+	const barHeight = 60
+	const guardExtra = 8
+
+	width := (95 + quietZone*2) * moduleWidth
+	height := barHeight + guardExtra + 14
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	fmt.Fprintf(&buffer, `<rect width="%d" height="%d" fill="#fff"/>`, width, height)
+
+	for i, bar := range ean13Modules(code) {
+		if !bar {
+			continue
+		}
+		h := barHeight
+		if ean13GuardModule(i) {
+			h += guardExtra
+		}
+		fmt.Fprintf(&buffer, `<rect x="%d" y="0" width="%d" height="%d" fill="#000"/>`, (quietZone+i)*moduleWidth, moduleWidth, h)
+	}
+
+	// angka yang bisa dibaca manusia: digit pertama di kiri, lalu dua kelompok enam digit
+	textY := barHeight + guardExtra + 10
+	fmt.Fprintf(&buffer, `<g font-family="monospace" font-size="14" text-anchor="middle" fill="#000">`)
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d">%s</text>`, (quietZone-5)*moduleWidth, textY, code[:1])
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d">%s</text>`, (quietZone+24)*moduleWidth, textY, code[1:7])
+	fmt.Fprintf(&buffer, `<text x="%d" y="%d">%s</text>`, (quietZone+71)*moduleWidth, textY, code[7:])
+	buffer.WriteString(`</g></svg>`)
+
+	return buffer.Bytes()
+}
+
+// barcodeDigitFont adalah font bitmap 3x5 untuk angka 0-9 pada label PNG.
+var barcodeDigitFont = [10][5]string{
+	{"111", "101", "101", "101", "111"},
+	{"010", "110", "010", "010", "111"},
+	{"111", "001", "111", "100", "111"},
+	{"111", "001", "111", "001", "111"},
+	{"101", "101", "111", "001", "001"},
+	{"111", "100", "111", "001", "111"},
+	{"111", "100", "111", "101", "111"},
+	{"111", "001", "010", "010", "010"},
+	{"111", "101", "111", "101", "111"},
+	{"111", "101", "111", "001", "111"},
+}
+
+// drawBarcodeDigits menulis angka dengan barcodeDigitFont mulai dari titik (x, y).
+func drawBarcodeDigits(img *image.Gray, x int, y int, scale int, digits string) {
+	for _, c := range digits {
+		glyph := barcodeDigitFont[c-'0']
+		for row := 0; row < 5; row++ {
+			for col := 0; col < 3; col++ {
+				if glyph[row][col] != '1' {
+					continue
+				}
+				for dy := 0; dy < scale; dy++ {
+					for dx := 0; dx < scale; dx++ {
+						img.SetGray(x+col*scale+dx, y+row*scale+dy, color.Gray{Y: 0})
+					}
+				}
+			}
+		}
+		x += 4 * scale
+	}
+}
+
+// renderBarcodePNG menggambar label EAN-13 dalam format PNG.
+func renderBarcodePNG(code string) ([]byte, error) {
+	const moduleWidth = 2
+	const quietZone = 11
+	const barHeight = 60
+	const guardExtra = 8
+	const fontScale = 2
+
+	width := (95 + quietZone*2) * moduleWidth
+	height := barHeight + guardExtra + 5*fontScale + 6
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for i, bar := range ean13Modules(code) {
+		if !bar {
+			continue
+		}
+		h := barHeight
+		if ean13GuardModule(i) {
+			h += guardExtra
+		}
+		for x := (quietZone + i) * moduleWidth; x < (quietZone+i+1)*moduleWidth; x++ {
+			for y := 0; y < h; y++ {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			}
+		}
+	}
+
+	textY := barHeight + guardExtra + 2
+	drawBarcodeDigits(img, 2*moduleWidth, textY, fontScale, code[:1])
+	drawBarcodeDigits(img, (quietZone+12)*moduleWidth, textY, fontScale, code[1:7])
+	drawBarcodeDigits(img, (quietZone+59)*moduleWidth, textY, fontScale, code[7:])
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// LookupProductBarcode mencari produk atau varian berdasarkan barcode yang dipindai kasir.
+// Barcode timbangan dicari lewat PLU dan ikut mengembalikan qty serta harga yang tertanam.
+func LookupProductBarcode(w http.ResponseWriter, r *http.Request) {
+	type Scale struct {
+		scaleBarcode
+		Qty        float64 `json:"qty"`
+		TotalPrice int     `json:"total_price"`
+	}
+
+	type Product struct {
+		ID           int64   `json:"id"`
+		ParentID     *int64  `json:"parent_id"`
+		SKU          string  `json:"sku"`
+		Barcode      *string `json:"barcode"`
+		PLU          *string `json:"plu"`
+		Name         string  `json:"name"`
+		Unit         string  `json:"unit"`
+		QtyPrecision int     `json:"qty_precision"`
+		Stock        string  `json:"stock"`
+		Price        string  `json:"price"`
+		Image        string  `json:"image"`
+		Scale        *Scale  `json:"scale,omitempty"`
+	}
+
+	code := mux.Vars(r)["code"]
+	if code == "" {
+		responses.ErrorResponse(w, "Barcode harus diisi", http.StatusBadRequest)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := "SELECT id, parent_id, sku, barcode, plu, name, unit, qty_precision, stock, price, image FROM products p"
+	args := []interface{}{}
+	if storeID != nil {
+		query = `
+		    SELECT p.id, p.parent_id, p.sku, p.barcode, p.plu, p.name, p.unit, p.qty_precision, ` + qtyText("COALESCE(ps.stock, 0)") + `, COALESCE(ps.price, p.price), p.image
+		    FROM products p
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?`
+		args = append(args, *storeID)
+	}
+
+	// barcode yang tersimpan di produk didahulukan, baru kemudian dibaca sebagai barcode timbangan
+	var product Product
+	scanProduct := func(where string, value string) error {
+		return config.DB.QueryRow(query+" WHERE p.deleted_at IS NULL AND "+where, append(args, value)...).
+			Scan(&product.ID, &product.ParentID, &product.SKU, &product.Barcode, &product.PLU, &product.Name, &product.Unit, &product.QtyPrecision, &product.Stock, &product.Price, &product.Image)
+	}
+
+	err = scanProduct("p.barcode = ?", code)
+	if err == sql.ErrNoRows {
+		if scale, ok := parseScaleBarcode(code); ok {
+			err = scanProduct("p.plu = ?", scale.PLU)
+			if err == nil {
+				// harga yang rusak tidak boleh membuat total timbangan menjadi 0
+				price, convErr := strconv.Atoi(product.Price)
+				if convErr != nil {
+					responses.InternalError(w, fmt.Errorf("harga produk %d tidak valid: %w", product.ID, convErr))
+					return
+				}
+				qty, totalPrice := scaleQty(scale, product.Unit, product.QtyPrecision, price)
+				product.Scale = &Scale{scaleBarcode: scale, Qty: qty, TotalPrice: totalPrice}
+			}
+		}
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk dengan barcode "+code+" tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", product, http.StatusOK)
+}
+
+// GenerateProductBarcode membuat kode EAN-13 internal untuk produk yang belum memiliki barcode.
+func GenerateProductBarcode(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var barcode sql.NullString
+	err = config.DB.QueryRow("SELECT barcode FROM products WHERE id = ?", productID).Scan(&barcode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if barcode.Valid && barcode.String != "" {
+		responses.ErrorResponse(w, "Produk sudah memiliki barcode "+barcode.String, http.StatusConflict)
+		return
+	}
+
+	code := internalEAN13(productID)
+	_, err = config.DB.Exec("UPDATE products SET barcode = ?, updated_at = NOW() WHERE id = ? AND barcode IS NULL", code, productID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			responses.ErrorResponse(w, "Barcode "+code+" sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID      int64  `json:"id"`
+		Barcode string `json:"barcode"`
+	}{
+		ID:      productID,
+		Barcode: code,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// ProductBarcodeLabel mengembalikan gambar label barcode EAN-13 produk dalam format png (default) atau svg.
+func ProductBarcodeLabel(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		responses.FieldErrorResponse(w, "format", "Invalid 'format' parameter, gunakan png atau svg")
+		return
+	}
+
+	var barcode sql.NullString
+	err = config.DB.QueryRow("SELECT barcode FROM products WHERE id = ?", productID).Scan(&barcode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !barcode.Valid || barcode.String == "" {
+		responses.ErrorResponse(w, "Produk belum memiliki barcode", http.StatusNotFound)
+		return
+	}
+	if !validEAN13(barcode.String) {
+		responses.ErrorResponse(w, "Barcode produk bukan EAN-13 sehingga tidak dapat digambar", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(renderBarcodeSVG(barcode.String))
+		return
+	}
+
+	label, err := renderBarcodePNG(barcode.String)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(label)
+}
@@ -3,22 +3,107 @@ package controller
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"golang-api/api/responses"
 	"golang-api/config"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 )
 
+// categoryNode adalah satu kategori pada pohon kategori.
+type categoryNode struct {
+	ID       int64           `json:"id"`
+	ParentID *int64          `json:"parent_id"`
+	Name     string          `json:"name"`
+	Children []*categoryNode `json:"children"`
+}
+
+// loadCategoryTree mengambil semua kategori dan menyusunnya menjadi pohon. Jumlah kategori
+// relatif kecil sehingga pohon cukup dibangun di memori. Mengembalikan semua node per ID
+// dan daftar kategori teratas.
+func loadCategoryTree(db sqlQueryer) (map[int64]*categoryNode, []*categoryNode, error) {
+	rows, err := db.Query("SELECT id, parent_id, name FROM categories WHERE deleted_at IS NULL ORDER BY name, id")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	nodes := map[int64]*categoryNode{}
+	var ordered []*categoryNode
+	for rows.Next() {
+		node := &categoryNode{Children: []*categoryNode{}}
+		if err := rows.Scan(&node.ID, &node.ParentID, &node.Name); err != nil {
+			return nil, nil, err
+		}
+		nodes[node.ID] = node
+		ordered = append(ordered, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	roots := []*categoryNode{}
+	for _, node := range ordered {
+		if parent, ok := nodes[derefInt64(node.ParentID)]; ok && node.ParentID != nil {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return nodes, roots, nil
+}
+
+// derefInt64 mengembalikan nilai pointer atau 0 jika nil.
+func derefInt64(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// categoryDescendants mengembalikan ID kategori beserta semua turunannya.
+func categoryDescendants(nodes map[int64]*categoryNode, categoryID int64) []int64 {
+	node, ok := nodes[categoryID]
+	if !ok {
+		return []int64{categoryID}
+	}
+
+	ids := []int64{}
+	queue := []*categoryNode{node}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		ids = append(ids, current.ID)
+		queue = append(queue, current.Children...)
+	}
+	return ids
+}
+
+// categoryIsDescendant memeriksa apakah categoryID adalah ancestorID sendiri atau turunannya.
+// Dipakai untuk mencegah siklus saat kategori dipindahkan.
+func categoryIsDescendant(nodes map[int64]*categoryNode, categoryID int64, ancestorID int64) bool {
+	// batas langkah mencegah perulangan tanpa akhir jika data sudah terlanjur memiliki siklus
+	current := &categoryID
+	for steps := 0; current != nil && steps <= len(nodes); steps++ {
+		if *current == ancestorID {
+			return true
+		}
+		node, ok := nodes[*current]
+		if !ok {
+			return false
+		}
+		current = node.ParentID
+	}
+	return false
+}
+
 func CreateCategories(w http.ResponseWriter, r *http.Request) {
 	// Inisialisasi koneksi ke database
 	var categories struct {
-		CategoryID int64  `json:"categoryId"`
-		Name       string `json:"name"`
+		CategoryID *int64 `json:"categoryId" validate:"exists=categories"` // kategori induk, kosong untuk kategori teratas
+		Name       string `json:"name" validate:"required,max=255"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&categories); err != nil {
@@ -27,23 +112,29 @@ func CreateCategories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// categoryId 0 sama dengan kosong, lalu data divalidasi sesuai aturan di atas
+	if categories.CategoryID != nil && *categories.CategoryID == 0 {
+		categories.CategoryID = nil
+	}
+	if !validateRequest(w, &categories) {
+		return
+	}
+
 	// upload gambar ke firebase dan return url yang disimpn ke var  imageURL
 
 	// Waktu saat ini
 	currentTime := time.Now()
 
 	// Simpan produk ke database dengan menggunakan data yang telah Anda validasi
-	result, err := config.DB.Exec("INSERT INTO categories ( name, created_at, updated_at) VALUES (?, ?, ?)",
-		categories.Name, currentTime, currentTime)
+	result, err := config.DB.Exec("INSERT INTO categories (parent_id, name, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		categories.CategoryID, categories.Name, currentTime, currentTime)
 	if err != nil {
 		// Menangani kesalahan jika gagal menyimpan produk ke database
-		errorMessage := "Gagal menyimpan produk ke database"
-		if strings.Contains(err.Error(), "Duplicate entry") {
-			errorMessage = "Produk dengan nama yang sama sudah ada."
-		} else {
-			errorMessage = "Terjadi kesalahan saat menyimpan produk ke database."
+		if isDuplicateEntry(err) {
+			responses.ErrorResponse(w, "Kategori dengan nama yang sama sudah ada.", http.StatusConflict)
+			return
 		}
-		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -56,11 +147,13 @@ func CreateCategories(w http.ResponseWriter, r *http.Request) {
 
 	// Membuat objek data produk untuk dikirim dalam respons
 	productData := struct {
-		ID   int64  `json:"id"`
-		Name string `json:"name"`
+		ID       int64  `json:"id"`
+		ParentID *int64 `json:"parent_id"`
+		Name     string `json:"name"`
 	}{
-		ID:   lastInsertID,
-		Name: categories.Name,
+		ID:       lastInsertID,
+		ParentID: categories.CategoryID,
+		Name:     categories.Name,
 	}
 
 	responses.SuccessResponse(w, "Success", productData, http.StatusCreated)
@@ -69,35 +162,35 @@ func CreateCategories(w http.ResponseWriter, r *http.Request) {
 func ListCategories(w http.ResponseWriter, r *http.Request) {
 	type Category struct {
 		ID       int64     `json:"id"`
+		ParentID *int64    `json:"parent_id"`
 		Name     string    `json:"name"`
-		Category *Category `json:"category"`
+		Category *Category `json:"category"` // kategori induk beserta induknya sampai kategori teratas
 	}
 
-	limitStr := r.URL.Query().Get("limit")
-	skipStr := r.URL.Query().Get("skip")
 	categoryIDStr := r.URL.Query().Get("categoryId")
 	searchQuery := r.URL.Query().Get("q")
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'limit' parameter", http.StatusBadRequest)
-		return
-	}
-
-	skip, err := strconv.Atoi(skipStr)
+	page, err := parsePagination(r)
 	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'skip' parameter", http.StatusBadRequest)
+		badRequest(w, err)
 		return
 	}
 
-	query := "SELECT id, name FROM categories WHERE 1=1"
+	query := ""
 	var args []interface{}
 
+	// deleted=true menampilkan kategori yang sudah dihapus agar dapat dipulihkan
+	if r.URL.Query().Get("deleted") == "true" {
+		query += " AND deleted_at IS NOT NULL"
+	} else {
+		query += " AND deleted_at IS NULL"
+	}
+
 	if categoryIDStr != "" {
 		query += " AND (id = ? OR id IS NULL)"
 		categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
 		if err != nil {
-			responses.ErrorResponse(w, "Invalid 'categoryId' parameter", http.StatusBadRequest)
+			responses.FieldErrorResponse(w, "categoryId", "Invalid 'categoryId' parameter")
 			return
 		}
 		args = append(args, categoryID)
@@ -108,38 +201,67 @@ func ListCategories(w http.ResponseWriter, r *http.Request) {
 		args = append(args, "%"+searchQuery+"%")
 	}
 
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, limit, skip)
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM categories WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
 
-	rows, err := config.DB.Query(query, args...)
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", false, nil)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query("SELECT id, parent_id, name FROM categories WHERE 1=1"+query+keyset+" ORDER BY id"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
 		return
 	}
 	defer rows.Close()
 
+	nodes, _, err := loadCategoryTree(config.DB)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
 	var categories []Category
 
 	for rows.Next() {
 		var category Category
-		err := rows.Scan(&category.ID, &category.Name)
+		err := rows.Scan(&category.ID, &category.ParentID, &category.Name)
 		if err != nil {
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			responses.InternalError(w, err)
 			return
 		}
 
+		// isi rantai kategori induk
+		child := &category
+		for steps := 0; child.ParentID != nil && steps < len(nodes); steps++ {
+			parent, ok := nodes[*child.ParentID]
+			if !ok {
+				break
+			}
+			child.Category = &Category{ID: parent.ID, ParentID: parent.ParentID, Name: parent.Name}
+			child = child.Category
+		}
+
 		categories = append(categories, category)
 	}
 
-	total := len(categories)
+	var next *string
+	if page.hasMore(len(categories)) {
+		categories = categories[:page.Limit]
+		next = nextCursor("id", "", categories[len(categories)-1].ID)
+	}
 
 	response := map[string]interface{}{
 		"data": map[string]interface{}{
-			"meta": map[string]interface{}{
-				"total": total,
-				"limit": limit,
-				"skip":  skip,
-			},
+			"meta":       page.meta(total, next),
 			"categories": categories,
 		},
 	}
@@ -149,9 +271,11 @@ func ListCategories(w http.ResponseWriter, r *http.Request) {
 }
 func DetailCategories(w http.ResponseWriter, r *http.Request) {
 	type Category struct {
-		ID       int64     `json:"id"`
-		Name     string    `json:"name"`
-		Category *Category `json:"category,omitempty"`
+		ID       int64           `json:"id"`
+		ParentID *int64          `json:"parent_id"`
+		Name     string          `json:"name"`
+		Category *Category       `json:"category,omitempty"`
+		Children []*categoryNode `json:"children,omitempty"`
 	}
 	vars := mux.Vars(r)
 	categoryID := vars["id"]
@@ -164,18 +288,33 @@ func DetailCategories(w http.ResponseWriter, r *http.Request) {
 	var category Category
 
 	// Menggunakan prepared statement untuk menghindari SQL Injection
-	err := config.DB.QueryRow("SELECT id, name FROM categories WHERE id=?", categoryID).Scan(&category.ID, &category.Name)
+	err := config.DB.QueryRow("SELECT id, parent_id, name FROM categories WHERE id=? AND deleted_at IS NULL", categoryID).Scan(&category.ID, &category.ParentID, &category.Name)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			errorMessage := fmt.Sprintf("Category tidak ditemukan: %v", err)
-
-			responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
+			responses.ErrorResponse(w, "Category tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
+	// kategori induk dan subkategori langsung
+	nodes, _, err := loadCategoryTree(config.DB)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if category.ParentID != nil {
+		if parent, ok := nodes[*category.ParentID]; ok {
+			category.Category = &Category{ID: parent.ID, ParentID: parent.ParentID, Name: parent.Name}
+		}
+	}
+	if node, ok := nodes[category.ID]; ok {
+		for _, child := range node.Children {
+			category.Children = append(category.Children, &categoryNode{ID: child.ID, ParentID: child.ParentID, Name: child.Name, Children: []*categoryNode{}})
+		}
+	}
+
 	// Mengembalikan data kategori sebagai JSON
 	w.Header().Set("Content-Type", "application/json")
 	responses.SuccessResponse(w, "Success", category, http.StatusOK)
@@ -192,20 +331,18 @@ func UpdateCategories(w http.ResponseWriter, r *http.Request) {
 	// Mendapatkan data kategori dari body permintaan
 	var updatedCategories struct {
 		ID   int64  `json:"id"` // Ganti 'id' dengan 'ID'
-		Name string `json:"name"`
+		Name string `json:"name" validate:"required,max=255"`
 		// Anda dapat menambahkan lebih banyak field kategori sesuai kebutuhan
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&updatedCategories); err != nil {
-		errorMessage := fmt.Sprintf("Gagal membaca data kategori dari permintaan: %v", err)
-		responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+	if !decodeRequest(w, r, &updatedCategories) {
 		return
 	}
 
 	// Menggunakan prepared statement untuk menghindari SQL Injection
-	stmt, err := config.DB.Prepare("UPDATE categories SET name=?, updated_at=NOW() WHERE id=?")
+	stmt, err := config.DB.Prepare("UPDATE categories SET name=?, updated_at=NOW() WHERE id=? AND deleted_at IS NULL")
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 	defer stmt.Close()
@@ -213,7 +350,7 @@ func UpdateCategories(w http.ResponseWriter, r *http.Request) {
 	// Memperbarui kategori di database
 	_, err = stmt.Exec(updatedCategories.Name, categoryID)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -239,13 +376,154 @@ func DeleteCategories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Menghapus category dari database
-	_, err := config.DB.Exec("DELETE FROM categories WHERE id=?", CategoryId)
+	categoryID, err := strconv.ParseInt(CategoryId, 10, 64)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.ErrorResponse(w, "ID kategori tidak valid", http.StatusBadRequest)
+		return
+	}
 
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Category hanya ditandai terhapus; tautan kelompok modifier dihapus permanen oleh job purge
+	deleted, err := softDeleteRow(tx, "categories", categoryID)
+	if err != nil {
+		responses.InternalError(w, err)
+
+		return
+	}
+	if !deleted {
+		responses.ErrorResponse(w, "Category tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	// Subkategori dipindahkan ke induk dari category yang dihapus
+	_, err = tx.Exec("UPDATE categories c JOIN categories deleted ON deleted.id = ? SET c.parent_id = deleted.parent_id, c.updated_at = NOW() WHERE c.parent_id = deleted.id", categoryID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
 		return
 	}
 
 	responses.OtherResponses(w, "Success", http.StatusCreated)
 }
+
+// CategoryTree menampilkan kategori sebagai pohon. Jika rootId diisi, hanya subpohon dari kategori tersebut.
+func CategoryTree(w http.ResponseWriter, r *http.Request) {
+	nodes, roots, err := loadCategoryTree(config.DB)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if rootIDStr := r.URL.Query().Get("rootId"); rootIDStr != "" {
+		rootID, err := strconv.ParseInt(rootIDStr, 10, 64)
+		if err != nil {
+			responses.FieldErrorResponse(w, "rootId", "Invalid 'rootId' parameter")
+			return
+		}
+		root, ok := nodes[rootID]
+		if !ok {
+			responses.ErrorResponse(w, "Category tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		roots = []*categoryNode{root}
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta": map[string]interface{}{
+				"total": len(nodes),
+			},
+			"categories": roots,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// MoveCategories memindahkan kategori beserta seluruh subkategorinya ke induk lain.
+// parent_id null memindahkan kategori menjadi kategori teratas.
+func MoveCategories(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID kategori tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		ParentID *int64 `json:"parent_id"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// kunci tabel kategori agar dua pemindahan bersamaan tidak membentuk siklus
+	locked, err := tx.Query("SELECT id FROM categories FOR UPDATE")
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	locked.Close()
+
+	nodes, _, err := loadCategoryTree(tx)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if _, ok := nodes[categoryID]; !ok {
+		responses.ErrorResponse(w, "Category tidak ditemukan", http.StatusNotFound)
+		return
+	}
+	if request.ParentID != nil {
+		if _, ok := nodes[*request.ParentID]; !ok {
+			responses.ErrorResponse(w, "Category induk dengan ID "+strconv.FormatInt(*request.ParentID, 10)+" tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		if categoryIsDescendant(nodes, *request.ParentID, categoryID) {
+			responses.ErrorResponse(w, "Category tidak dapat dipindahkan ke dirinya sendiri atau ke subkategorinya", http.StatusConflict)
+			return
+		}
+	}
+
+	_, err = tx.Exec("UPDATE categories SET parent_id = ?, updated_at = NOW() WHERE id = ?", request.ParentID, categoryID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	node := nodes[categoryID]
+	responseData := struct {
+		ID       int64  `json:"id"`
+		ParentID *int64 `json:"parent_id"`
+		Name     string `json:"name"`
+	}{
+		ID:       categoryID,
+		ParentID: request.ParentID,
+		Name:     node.Name,
+	}
+
+	responses.SuccessResponse(w, "Kategori berhasil dipindahkan", responseData, http.StatusOK)
+}
@@ -0,0 +1,408 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// jenis produk. Produk komposit (bundel, kit atau resep) tidak memiliki stok sendiri,
+// penjualannya mengurangi stok komponen sesuai product_components.
+const (
+	productTypeStandard  = "standard"
+	productTypeComposite = "composite"
+)
+
+// sqlQueryer dipenuhi oleh *sql.DB dan *sql.Tx sehingga komponen bisa dibaca di dalam atau di luar transaksi.
+type sqlQueryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// productComponent adalah satu baris bill of materials produk komposit.
+type productComponent struct {
+	ComponentID int64   `json:"component_id"`
+	SKU         string  `json:"sku"`
+	Name        string  `json:"name"`
+	Unit        string  `json:"unit"`
+	Qty         float64 `json:"qty"`
+	CostPrice   float64 `json:"cost_price"`
+	LineCost    float64 `json:"line_cost"`
+}
+
+// loadComponents mengambil komponen produk komposit beserta biaya per komponen.
+func loadComponents(db sqlQueryer, productID int64) ([]productComponent, error) {
+	rows, err := db.Query(`
+	    SELECT pc.component_id, p.sku, p.name, p.unit, pc.qty, p.cost_price
+	    FROM product_components pc
+	    JOIN products p ON pc.component_id = p.id
+	    WHERE pc.product_id = ?
+	    ORDER BY pc.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := []productComponent{}
+	for rows.Next() {
+		var component productComponent
+		if err := rows.Scan(&component.ComponentID, &component.SKU, &component.Name, &component.Unit, &component.Qty, &component.CostPrice); err != nil {
+			return nil, err
+		}
+		component.LineCost = component.Qty * component.CostPrice
+		components = append(components, component)
+	}
+	return components, rows.Err()
+}
+
+// rollUpCompositeCost menghitung cost_price produk komposit dari jumlah biaya komponennya.
+func rollUpCompositeCost(tx *sql.Tx, productID int64) (float64, error) {
+	components, err := loadComponents(tx, productID)
+	if err != nil {
+		return 0, err
+	}
+
+	var cost float64
+	for _, component := range components {
+		cost += component.LineCost
+	}
+
+	_, err = tx.Exec("UPDATE products SET cost_price = ?, updated_at = NOW() WHERE id = ?", cost, productID)
+	return cost, err
+}
+
+// rollUpCompositesUsing memperbarui cost_price semua produk komposit yang memakai componentID,
+// dipanggil setelah cost_price komponen berubah.
+func rollUpCompositesUsing(tx *sql.Tx, componentID int64) error {
+	rows, err := tx.Query("SELECT product_id FROM product_components WHERE component_id = ?", componentID)
+	if err != nil {
+		return err
+	}
+
+	var productIDs []int64
+	for rows.Next() {
+		var productID int64
+		if err := rows.Scan(&productID); err != nil {
+			rows.Close()
+			return err
+		}
+		productIDs = append(productIDs, productID)
+	}
+	rows.Close()
+
+	for _, productID := range productIDs {
+		if _, err := rollUpCompositeCost(tx, productID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetProductComponents mengganti seluruh komponen produk dan menjadikannya produk komposit.
+// Daftar komponen kosong mengembalikan produk menjadi produk standard.
+func SetProductComponents(w http.ResponseWriter, r *http.Request) {
+	type Component struct {
+		ComponentID int64   `json:"component_id" validate:"required,exists=products"`
+		Qty         float64 `json:"qty" validate:"positive"`
+	}
+
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Components []Component `json:"components"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var productType, stock string
+	var variantCount, usedAsComponent int
+	err = tx.QueryRow(`
+	    SELECT p.type, p.stock,
+	           (SELECT COUNT(*) FROM products v WHERE v.parent_id = p.id AND v.deleted_at IS NULL),
+	           (SELECT COUNT(*) FROM product_components pc WHERE pc.component_id = p.id)
+	    FROM products p WHERE p.id = ? AND p.deleted_at IS NULL FOR UPDATE`, productID).Scan(&productType, &stock, &variantCount, &usedAsComponent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if len(request.Components) > 0 {
+		if variantCount > 0 {
+			responses.ErrorResponse(w, "Produk yang memiliki varian tidak dapat dijadikan produk komposit", http.StatusBadRequest)
+			return
+		}
+		// komposit bertingkat tidak didukung agar pengurangan stok cukup satu level
+		if usedAsComponent > 0 {
+			responses.ErrorResponse(w, "Produk ini dipakai sebagai komponen produk lain sehingga tidak dapat dijadikan produk komposit", http.StatusConflict)
+			return
+		}
+		// stok lama produk standard tidak akan pernah berkurang lagi setelah menjadi komposit
+		if productType == productTypeStandard {
+			if currentStock, _ := strconv.ParseFloat(stock, 64); currentStock != 0 {
+				responses.ErrorResponse(w, "Stok produk harus 0 sebelum dijadikan produk komposit", http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	seen := map[int64]bool{}
+	for _, component := range request.Components {
+		if !validQtyPrecision(component.Qty, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Qty komponen maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+		if component.ComponentID == productID {
+			responses.ErrorResponse(w, "Produk tidak dapat menjadi komponen dirinya sendiri", http.StatusBadRequest)
+			return
+		}
+		if seen[component.ComponentID] {
+			errorMessage := fmt.Sprintf("Komponen dengan ID %d muncul lebih dari sekali", component.ComponentID)
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+		seen[component.ComponentID] = true
+
+		// komponen harus produk standard tanpa varian karena stoknya yang akan dikurangi
+		var componentType string
+		var componentVariants int
+		err := tx.QueryRow("SELECT type, (SELECT COUNT(*) FROM products v WHERE v.parent_id = p.id AND v.deleted_at IS NULL) FROM products p WHERE p.id = ? AND p.deleted_at IS NULL", component.ComponentID).
+			Scan(&componentType, &componentVariants)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				errorMessage := fmt.Sprintf("Komponen dengan ID %d tidak ditemukan", component.ComponentID)
+				responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
+				return
+			}
+			responses.InternalError(w, err)
+			return
+		}
+		if componentType != productTypeStandard || componentVariants > 0 {
+			errorMessage := fmt.Sprintf("Komponen dengan ID %d harus produk standard tanpa varian", component.ComponentID)
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM product_components WHERE product_id = ?", productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	currentTime := time.Now()
+	for _, component := range request.Components {
+		_, err := tx.Exec("INSERT INTO product_components (product_id, component_id, qty, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			productID, component.ComponentID, component.Qty, currentTime, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Gagal menyimpan komponen produk: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	productType = productTypeStandard
+	if len(request.Components) > 0 {
+		productType = productTypeComposite
+	}
+	_, err = tx.Exec("UPDATE products SET type = ?, updated_at = NOW() WHERE id = ?", productType, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	var costPrice float64
+	if productType == productTypeComposite {
+		costPrice, err = rollUpCompositeCost(tx, productID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	components, err := loadComponents(tx, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ProductID  int64              `json:"product_id"`
+		Type       string             `json:"type"`
+		CostPrice  float64            `json:"cost_price"`
+		Components []productComponent `json:"components"`
+	}{
+		ProductID:  productID,
+		Type:       productType,
+		CostPrice:  costPrice,
+		Components: components,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// ListProductComponents menampilkan komponen produk komposit. Jika ada store, ikut ditampilkan
+// stok komponen di store tersebut dan berapa produk yang masih bisa dibuat dari stok itu.
+func ListProductComponents(w http.ResponseWriter, r *http.Request) {
+	type Component struct {
+		productComponent
+		Stock *float64 `json:"stock,omitempty"`
+	}
+
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	var productType string
+	var costPrice float64
+	err = config.DB.QueryRow("SELECT type, cost_price FROM products WHERE id = ?", productID).Scan(&productType, &costPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	productComponents, err := loadComponents(config.DB, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	components := []Component{}
+	var available *float64
+	for _, productComponent := range productComponents {
+		component := Component{productComponent: productComponent}
+
+		if storeID != nil {
+			var stock float64
+			err := config.DB.QueryRow("SELECT COALESCE((SELECT stock FROM product_stocks WHERE store_id = ? AND product_id = ?), 0)", *storeID, component.ComponentID).Scan(&stock)
+			if err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+			component.Stock = &stock
+
+			// jumlah produk yang bisa dibuat dibatasi oleh komponen yang paling sedikit
+			canMake := math.Max(math.Floor(stock/component.Qty), 0)
+			if available == nil || canMake < *available {
+				available = &canMake
+			}
+		}
+
+		components = append(components, component)
+	}
+
+	responseData := struct {
+		ProductID  int64       `json:"product_id"`
+		Type       string      `json:"type"`
+		CostPrice  float64     `json:"cost_price"`
+		Available  *float64    `json:"available,omitempty"`
+		Components []Component `json:"components"`
+	}{
+		ProductID:  productID,
+		Type:       productType,
+		CostPrice:  costPrice,
+		Available:  available,
+		Components: components,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// RollUpProductCost menghitung ulang cost_price produk komposit dari cost_price komponennya saat ini.
+func RollUpProductCost(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var productType string
+	err = tx.QueryRow("SELECT type FROM products WHERE id = ? FOR UPDATE", productID).Scan(&productType)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+	if productType != productTypeComposite {
+		responses.ErrorResponse(w, "Produk bukan produk komposit", http.StatusBadRequest)
+		return
+	}
+
+	costPrice, err := rollUpCompositeCost(tx, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	components, err := loadComponents(tx, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ProductID  int64              `json:"product_id"`
+		CostPrice  float64            `json:"cost_price"`
+		Components []productComponent `json:"components"`
+	}{
+		ProductID:  productID,
+		CostPrice:  costPrice,
+		Components: components,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"errors"
+	"golang-api/api/responses"
+	"net/http"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry adalah nomor error MySQL untuk pelanggaran unique key.
+const mysqlDuplicateEntry = 1062
+
+// isDuplicateEntry memeriksa apakah err berasal dari data yang melanggar unique key, sehingga
+// dapat dilaporkan sebagai konflik tanpa meneruskan pesan error MySQL ke klien.
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// fieldError adalah kesalahan validasi pada satu field atau parameter request. Helper seperti
+// parsePagination mengembalikannya agar handler dapat melaporkan field yang salah.
+type fieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *fieldError) Error() string {
+	return e.Message
+}
+
+// invalidField membuat fieldError untuk field dengan pesan message.
+func invalidField(field string, message string) error {
+	return &fieldError{Field: field, Message: message}
+}
+
+// badRequest mengembalikan respons 400 untuk err. fieldError dilaporkan sebagai kesalahan
+// validasi per field, error lain sebagai bad_request.
+func badRequest(w http.ResponseWriter, err error) {
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		responses.FieldErrorResponse(w, fieldErr.Field, fieldErr.Message)
+		return
+	}
+	responses.ErrorResponse(w, err.Error(), http.StatusBadRequest)
+}
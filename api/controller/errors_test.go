@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBadRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  string
+		wantField string
+	}{
+		{"plain error", fmt.Errorf("file tidak valid"), "bad_request", ""},
+		{"field error", invalidField("limit", "Invalid 'limit' parameter"), "validation_failed", "limit"},
+		{"wrapped field error", fmt.Errorf("baris 2: %w", invalidField("sku", "sku harus diisi")), "validation_failed", "sku"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			badRequest(recorder, tt.err)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+			var body struct {
+				Error struct {
+					Code   string `json:"code"`
+					Fields []struct {
+						Field string `json:"field"`
+					} `json:"fields"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+			if tt.wantField != "" && (len(body.Error.Fields) != 1 || body.Error.Fields[0].Field != tt.wantField) {
+				t.Errorf("fields = %+v, want %q", body.Error.Fields, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// format ekspor yang didukung endpoint daftar dan laporan
+const (
+	exportCSV  = "csv"
+	exportXLSX = "xlsx"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// exportFlushRows adalah jumlah baris yang ditulis sebelum data dikirim ke klien, sehingga
+// ekspor besar tidak perlu ditampung di memori.
+const exportFlushRows = 500
+
+// exportLocale menentukan format angka dan tanggal di file CSV. XLSX menyimpan angka dan tanggal
+// sebagai nilai asli sehingga Excel menampilkannya sesuai pengaturan komputer pengguna.
+type exportLocale struct {
+	Delimiter  rune
+	Decimal    string
+	DateLayout string
+}
+
+var exportLocales = map[string]exportLocale{
+	// Excel berbahasa Indonesia memakai koma sebagai desimal dan titik koma sebagai pemisah kolom
+	"id": {Delimiter: ';', Decimal: ",", DateLayout: "02/01/2006 15:04:05"},
+	"en": {Delimiter: ',', Decimal: ".", DateLayout: "2006-01-02 15:04:05"},
+}
+
+// exportFormat membaca format ekspor dari parameter format atau header Accept. Mengembalikan
+// string kosong jika klien meminta JSON biasa.
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format == "json" {
+			return "", nil
+		}
+		if format != exportCSV && format != exportXLSX {
+			return "", invalidField("format", "Invalid 'format' parameter, gunakan json, csv atau xlsx")
+		}
+		return format, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, "text/csv"):
+		return exportCSV, nil
+	case strings.Contains(accept, xlsxContentType):
+		return exportXLSX, nil
+	}
+	return "", nil
+}
+
+// parseExportLocale membaca parameter locale (id atau en). Tanpa parameter dipakai EXPORT_LOCALE,
+// default id.
+func parseExportLocale(r *http.Request) (exportLocale, error) {
+	name := r.URL.Query().Get("locale")
+	if name == "" {
+		name = os.Getenv("EXPORT_LOCALE")
+	}
+	if name == "" {
+		name = "id"
+	}
+	locale, ok := exportLocales[name]
+	if !ok {
+		return locale, invalidField("locale", "Invalid 'locale' parameter, gunakan id atau en")
+	}
+	return locale, nil
+}
+
+// exportWriter menulis baris ekspor langsung ke response. Nilai baris boleh berupa string,
+// angka, time.Time, pointer ke salah satunya, atau nil untuk sel kosong.
+type exportWriter interface {
+	WriteRow(values ...interface{}) error
+	Close() error
+}
+
+// newExportWriter menyiapkan header response dan menulis baris judul kolom. Waktu ditampilkan
+// di zona waktu loc. Setelah fungsi ini dipanggil status response sudah terkirim, sehingga
+// kesalahan berikutnya hanya dapat menghentikan file di tengah jalan.
+func newExportWriter(w http.ResponseWriter, r *http.Request, format string, name string, columns []string, loc *time.Location) (exportWriter, error) {
+	locale, err := parseExportLocale(r)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().In(loc).Format("20060102150405"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	var writer exportWriter
+	if format == exportXLSX {
+		w.Header().Set("Content-Type", xlsxContentType)
+		w.WriteHeader(http.StatusOK)
+		writer, err = newXLSXWriter(w, name, loc)
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		writer, err = newCSVWriter(w, locale, loc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return writer, writer.WriteRow(header...)
+}
+
+// flushResponse mengirim data yang sudah ditulis ke klien jika response mendukungnya.
+func flushResponse(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// exportValue melepas pointer sehingga nilai dapat diformat.
+func exportValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *string:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *int64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *float64:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return *v
+	case int:
+		return int64(v)
+	}
+	return value
+}
+
+// csvExportWriter menulis ekspor CSV dengan format angka dan tanggal sesuai locale.
+type csvExportWriter struct {
+	response http.ResponseWriter
+	writer   *csv.Writer
+	locale   exportLocale
+	loc      *time.Location
+	rows     int
+}
+
+func newCSVWriter(w http.ResponseWriter, locale exportLocale, loc *time.Location) (*csvExportWriter, error) {
+	// BOM agar Excel membaca file sebagai UTF-8
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(w)
+	writer.Comma = locale.Delimiter
+	return &csvExportWriter{response: w, writer: writer, locale: locale, loc: loc}, nil
+}
+
+func (c *csvExportWriter) WriteRow(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		switch v := exportValue(value).(type) {
+		case nil:
+			record[i] = ""
+		case string:
+			record[i] = escapeCSVCell(v)
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			record[i] = strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", c.locale.Decimal, 1)
+		case time.Time:
+			record[i] = v.In(c.loc).Format(c.locale.DateLayout)
+		default:
+			record[i] = escapeCSVCell(fmt.Sprint(v))
+		}
+	}
+	if err := c.writer.Write(record); err != nil {
+		return err
+	}
+
+	c.rows++
+	if c.rows%exportFlushRows == 0 {
+		c.writer.Flush()
+		flushResponse(c.response)
+		return c.writer.Error()
+	}
+	return nil
+}
+
+// escapeCSVCell menambahkan tanda kutip tunggal di depan teks yang diawali =, +, -, @, tab atau CR agar
+// tidak dijalankan sebagai rumus saat file dibuka di spreadsheet (CSV injection). Angka tidak
+// melewati fungsi ini sehingga nilai negatif tetap terbaca sebagai angka.
+func escapeCSVCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// xlsxExportWriter menulis workbook XLSX dengan satu sheet. Bagian tetap workbook ditulis lebih
+// dulu, lalu baris sheet ditulis satu per satu ke entri zip yang sama.
+type xlsxExportWriter struct {
+	response http.ResponseWriter
+	zip      *zip.Writer
+	sheet    *bufio.Writer
+	loc      *time.Location
+	rows     int
+}
+
+// xlsxEpoch adalah tanggal nol serial tanggal Excel.
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func newXLSXWriter(w http.ResponseWriter, name string, loc *time.Location) (*xlsxExportWriter, error) {
+	archive := zip.NewWriter(w)
+
+	var sheetName strings.Builder
+	xml.EscapeText(&sheetName, []byte(name))
+
+	// style 1 adalah format tanggal dan jam bawaan Excel (numFmtId 22)
+	parts := []struct{ path, content string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + sheetName.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+		{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts><fills count="1"><fill><patternFill patternType="none"/></fill></fills><borders count="1"><border/></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(file)
+	_, err = sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxExportWriter{response: w, zip: archive, sheet: sheet, loc: loc}, nil
+}
+
+func (x *xlsxExportWriter) WriteRow(values ...interface{}) error {
+	x.sheet.WriteString("<row>")
+	for _, value := range values {
+		switch v := exportValue(value).(type) {
+		case nil:
+			x.sheet.WriteString("<c/>")
+		case int64:
+			x.sheet.WriteString("<c><v>" + strconv.FormatInt(v, 10) + "</v></c>")
+		case float64:
+			x.sheet.WriteString("<c><v>" + strconv.FormatFloat(v, 'f', -1, 64) + "</v></c>")
+		case time.Time:
+			// serial tanggal Excel dihitung dari jam lokal, tanpa zona waktu
+			local := v.In(x.loc)
+			wall := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+			serial := wall.Sub(xlsxEpoch).Hours() / 24
+			x.sheet.WriteString(`<c s="1"><v>` + strconv.FormatFloat(serial, 'f', -1, 64) + "</v></c>")
+		default:
+			// teks selalu ditulis sebagai inline string sehingga tidak pernah dihitung sebagai rumus
+			x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(x.sheet, []byte(fmt.Sprint(v)))
+			x.sheet.WriteString("</t></is></c>")
+		}
+	}
+	if _, err := x.sheet.WriteString("</row>"); err != nil {
+		return err
+	}
+
+	x.rows++
+	if x.rows%exportFlushRows == 0 {
+		if err := x.sheet.Flush(); err != nil {
+			return err
+		}
+		if err := x.zip.Flush(); err != nil {
+			return err
+		}
+		flushResponse(x.response)
+	}
+	return nil
+}
+
+func (x *xlsxExportWriter) Close() error {
+	if _, err := x.sheet.WriteString("</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEscapeCSVCell(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Kopi Susu", "Kopi Susu"},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+62812", "'+62812"},
+		{"-1+1", "'-1+1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeCSVCell(tt.value); got != tt.want {
+			t.Errorf("escapeCSVCell(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVWriterEscapesTextOnly(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer, err := newCSVWriter(recorder, exportLocales["en"], time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := "=cmd"
+	if err := writer.WriteRow(&name, "-5", -5.5, int64(-3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.TrimPrefix(recorder.Body.String(), "\uFEFF")
+	if want := "'=cmd,'-5,-5.5,-3\n"; got != want {
+		t.Errorf("row = %q, want %q", got, want)
+	}
+}
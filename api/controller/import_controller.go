@@ -0,0 +1,794 @@
+package controller
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"io"
+	"math"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxImportSize adalah ukuran file import terbesar yang diterima
+	maxImportSize = 10 << 20
+	// maxImportRows adalah jumlah baris data terbanyak dalam satu file import
+	maxImportRows = 5000
+)
+
+// importColumns memetakan nama kolom di file ke field import. Nama kolom dibandingkan tanpa
+// memperhatikan huruf besar kecil, dan judul kolom file ekspor produk ikut dikenali.
+var importColumns = map[string]string{
+	"sku":           "sku",
+	"kode":          "sku",
+	"name":          "name",
+	"nama":          "name",
+	"nama produk":   "name",
+	"category":      "category",
+	"category_id":   "category",
+	"kategori":      "category",
+	"price":         "price",
+	"harga":         "price",
+	"stock":         "stock",
+	"stok":          "stock",
+	"cost_price":    "cost_price",
+	"harga pokok":   "cost_price",
+	"hpp":           "cost_price",
+	"barcode":       "barcode",
+	"unit":          "unit",
+	"satuan":        "unit",
+	"qty_precision": "qty_precision",
+	"presisi":       "qty_precision",
+}
+
+// importFields adalah field yang dapat diisi lewat import.
+var importFields = map[string]bool{
+	"sku": true, "name": true, "category": true, "price": true, "stock": true,
+	"cost_price": true, "barcode": true, "unit": true, "qty_precision": true,
+}
+
+// importRow adalah satu baris file import yang sudah divalidasi. Field pointer bernilai nil jika
+// kolomnya tidak ada atau kosong, sehingga produk yang sudah ada tidak ikut berubah.
+type importRow struct {
+	Row    int      `json:"row"`
+	SKU    string   `json:"sku"`
+	Name   string   `json:"name"`
+	Action string   `json:"action"`
+	Errors []string `json:"errors"`
+
+	productID    int64
+	productType  string
+	categoryID   *int64
+	price        *int
+	stock        *float64
+	costPrice    *float64
+	barcode      *string
+	unit         *string
+	qtyPrecision *int
+}
+
+// readImportFile membaca baris file import. File .xlsx dibaca dari sheet pertama, file lain
+// dibaca sebagai CSV dengan pemisah titik koma atau koma. Mengembalikan true jika angka di file
+// mengikuti locale (CSV), atau false jika angka sudah berupa nilai asli (XLSX).
+func readImportFile(file multipart.File, header *multipart.FileHeader) ([][]string, bool, error) {
+	if strings.EqualFold(path.Ext(header.Filename), ".xlsx") {
+		rows, err := readXLSXRows(file, header.Size)
+		return rows, false, err
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, true, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+
+	// pemisah kolom ditebak dari baris judul
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, true, fmt.Errorf("File CSV tidak valid: %v", err)
+	}
+	return rows, true, nil
+}
+
+// readXLSXRows membaca semua baris dari sheet pertama workbook XLSX.
+func readXLSXRows(file io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, errors.New("File XLSX tidak valid")
+	}
+
+	files := map[string]*zip.File{}
+	var sheets []string
+	for _, f := range archive.File {
+		files[f.Name] = f
+		if strings.HasPrefix(f.Name, "xl/worksheets/") && strings.HasSuffix(f.Name, ".xml") {
+			sheets = append(sheets, f.Name)
+		}
+	}
+	sheetName := "xl/worksheets/sheet1.xml"
+	if files[sheetName] == nil {
+		if len(sheets) == 0 {
+			return nil, errors.New("File XLSX tidak memiliki sheet")
+		}
+		sort.Strings(sheets)
+		sheetName = sheets[0]
+	}
+
+	// teks sel biasanya disimpan sekali di sharedStrings dan dirujuk lewat indeksnya
+	var sharedStrings []string
+	if f := files["xl/sharedStrings.xml"]; f != nil {
+		var sst struct {
+			Items []struct {
+				Text string `xml:"t"`
+				Runs []struct {
+					Text string `xml:"t"`
+				} `xml:"r"`
+			} `xml:"si"`
+		}
+		if err := decodeZipXML(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			sharedStrings = append(sharedStrings, text)
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(files[sheetName], &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := [][]string{}
+	for _, sheetRow := range sheet.Rows {
+		row := []string{}
+		for i, cell := range sheetRow.Cells {
+			column := i
+			if cell.Ref != "" {
+				column = xlsxColumnIndex(cell.Ref)
+			}
+			for len(row) <= column {
+				row = append(row, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(sharedStrings) {
+					return nil, errors.New("File XLSX tidak valid")
+				}
+				row[column] = sharedStrings[index]
+			case "inlineStr":
+				row[column] = cell.Inline
+			default:
+				row[column] = cell.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeZipXML membaca satu file XML di dalam arsip zip.
+func decodeZipXML(f *zip.File, target interface{}) error {
+	reader, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := xml.NewDecoder(reader).Decode(target); err != nil {
+		return errors.New("File XLSX tidak valid")
+	}
+	return nil
+}
+
+// xlsxColumnIndex mengubah referensi sel seperti "C12" menjadi indeks kolom mulai dari 0.
+func xlsxColumnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if !unicode.IsLetter(r) {
+			break
+		}
+		index = index*26 + int(unicode.ToUpper(r)-'A'+1)
+	}
+	return index - 1
+}
+
+// parseImportNumber membaca angka dari sel import. Untuk CSV berlocale id, titik dianggap
+// pemisah ribuan dan koma sebagai desimal jika ada koma; untuk locale en koma dianggap pemisah ribuan.
+func parseImportNumber(value string, localized bool, locale exportLocale) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if localized {
+		if locale.Decimal == "," {
+			if strings.Contains(value, ",") {
+				value = strings.ReplaceAll(value, ".", "")
+				value = strings.Replace(value, ",", ".", 1)
+			}
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, errors.New("bukan angka")
+	}
+	return number, nil
+}
+
+// importCategories mengambil kategori yang masih aktif, per ID dan per nama (huruf kecil). Nama
+// yang dipakai lebih dari satu kategori bernilai 0 agar dilaporkan sebagai ambigu.
+func importCategories() (map[int64]bool, map[string]int64, error) {
+	rows, err := config.DB.Query("SELECT id, name FROM categories WHERE deleted_at IS NULL")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	byID := map[int64]bool{}
+	byName := map[string]int64{}
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, nil, err
+		}
+		byID[id] = true
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := byName[key]; ok {
+			byName[key] = 0
+		} else {
+			byName[key] = id
+		}
+	}
+	return byID, byName, rows.Err()
+}
+
+// importExisting mencari produk aktif yang memiliki SKU di file. SKU yang dipakai lebih dari satu
+// produk dicatat dengan ID 0 agar dilaporkan sebagai ambigu.
+func importExisting(skus []string) (map[string]importRow, error) {
+	existing := map[string]importRow{}
+	if len(skus) == 0 {
+		return existing, nil
+	}
+
+	args := make([]interface{}, len(skus))
+	for i, sku := range skus {
+		args[i] = sku
+	}
+	rows, err := config.DB.Query("SELECT id, sku, type, qty_precision FROM products WHERE deleted_at IS NULL AND sku IN (?"+strings.Repeat(", ?", len(skus)-1)+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product importRow
+		var precision int
+		if err := rows.Scan(&product.productID, &product.SKU, &product.productType, &precision); err != nil {
+			return nil, err
+		}
+		product.qtyPrecision = &precision
+		if _, ok := existing[product.SKU]; ok {
+			product.productID = 0
+		}
+		existing[product.SKU] = product
+	}
+	return existing, rows.Err()
+}
+
+// ImportProducts membuat atau memperbarui produk dari file CSV atau XLSX (field form "file").
+// Produk dicocokkan berdasarkan SKU: SKU yang sudah ada diperbarui, selain itu dibuat produk baru.
+// Kolom dikenali dari judulnya, atau dipetakan lewat field form "mapping" berupa JSON, misalnya
+// {"name": "Nama Barang"}. Sel kosong tidak mengubah data produk yang sudah ada. Dengan
+// dryRun=true hanya laporan validasi per baris yang dikembalikan; tanpa dryRun baris yang valid
+// disimpan dalam satu transaksi dan baris yang tidak valid dilewati.
+func ImportProducts(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		responses.ErrorResponse(w, "File import maksimal 10 MB", http.StatusBadRequest)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dryRun") == "true" || r.FormValue("dryRun") == "true"
+
+	locale, err := parseExportLocale(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		responses.ErrorResponse(w, "File import harus diunggah pada field 'file'", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	records, localized, err := readImportFile(file, header)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	if len(records) < 2 {
+		responses.ErrorResponse(w, "File import harus berisi baris judul dan minimal satu baris produk", http.StatusBadRequest)
+		return
+	}
+	if len(records)-1 > maxImportRows {
+		responses.ErrorResponse(w, fmt.Sprintf("File import maksimal %d baris produk", maxImportRows), http.StatusBadRequest)
+		return
+	}
+
+	// pemetaan kolom: judul kolom dikenali otomatis, mapping menggantikannya
+	columns := map[string]int{}
+	for i, title := range records[0] {
+		if field, ok := importColumns[strings.ToLower(strings.TrimSpace(title))]; ok {
+			if _, taken := columns[field]; !taken {
+				columns[field] = i
+			}
+		}
+	}
+	if mappingStr := r.FormValue("mapping"); mappingStr != "" {
+		var mapping map[string]string
+		if err := json.Unmarshal([]byte(mappingStr), &mapping); err != nil {
+			responses.ErrorResponse(w, "Mapping kolom harus berupa JSON, misalnya {\"name\": \"Nama Barang\"}", http.StatusBadRequest)
+			return
+		}
+		for field, title := range mapping {
+			if !importFields[field] {
+				responses.ErrorResponse(w, "Field '"+field+"' pada mapping tidak dikenal", http.StatusBadRequest)
+				return
+			}
+			found := false
+			for i, header := range records[0] {
+				if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(title)) {
+					columns[field] = i
+					found = true
+					break
+				}
+			}
+			if !found {
+				responses.ErrorResponse(w, "Kolom '"+title+"' tidak ditemukan di file", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if _, ok := columns["sku"]; !ok {
+		if _, ok := columns["name"]; !ok {
+			responses.ErrorResponse(w, "File import harus memiliki kolom SKU atau nama", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// stok diimpor ke store yang aktif
+	var storeID int64
+	if _, ok := columns["stock"]; ok {
+		storeID, err = requireStore(r)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+	}
+
+	categoryIDs, categoryNames, err := importCategories()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	var skus []string
+	for _, record := range records[1:] {
+		if i, ok := columns["sku"]; ok && i < len(record) && strings.TrimSpace(record[i]) != "" {
+			skus = append(skus, strings.TrimSpace(record[i]))
+		}
+	}
+	existing, err := importExisting(skus)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	rows := []importRow{}
+	seenSKU := map[string]int{}
+	seenBarcode := map[string]int{}
+	var valid, invalid int
+
+	for n, record := range records[1:] {
+		cell := func(field string) string {
+			if i, ok := columns[field]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		// baris kosong dilewati, misalnya baris terakhir file CSV
+		empty := true
+		for _, value := range record {
+			if strings.TrimSpace(value) != "" {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+
+		row := importRow{Row: n + 2, SKU: cell("sku"), Name: cell("name"), Errors: []string{}}
+		addError := func(format string, args ...interface{}) {
+			row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+		}
+
+		precision := 0
+		if row.SKU != "" {
+			if previous, ok := seenSKU[row.SKU]; ok {
+				addError("SKU %s sudah dipakai di baris %d", row.SKU, previous)
+			}
+			seenSKU[row.SKU] = row.Row
+
+			if product, ok := existing[row.SKU]; ok {
+				if product.productID == 0 {
+					addError("SKU %s dipakai lebih dari satu produk", row.SKU)
+				}
+				row.productID = product.productID
+				row.productType = product.productType
+				precision = *product.qtyPrecision
+			}
+		}
+		row.Action = "create"
+		if row.productID != 0 {
+			row.Action = "update"
+		}
+
+		if row.Action == "create" && row.Name == "" {
+			addError("Nama produk harus diisi untuk produk baru")
+		}
+		if row.Name != "" && len(row.Name) > 255 {
+			addError("Nama produk maksimal 255 karakter")
+		}
+
+		if value := cell("category"); value != "" {
+			if id, err := strconv.ParseInt(value, 10, 64); err == nil && categoryIDs[id] {
+				row.categoryID = &id
+			} else if id, ok := categoryNames[strings.ToLower(value)]; ok && id != 0 {
+				row.categoryID = &id
+			} else if ok {
+				addError("Nama kategori %s dipakai lebih dari satu kategori, gunakan ID kategori", value)
+			} else {
+				addError("Kategori %s tidak ditemukan", value)
+			}
+		}
+
+		if value := cell("price"); value != "" {
+			price, err := parseImportNumber(value, localized, locale)
+			if err != nil || price < 0 || price != math.Trunc(price) {
+				addError("Harga harus berupa bilangan bulat dan tidak boleh negatif")
+			} else {
+				priceInt := int(price)
+				row.price = &priceInt
+			}
+		} else if row.Action == "create" {
+			addError("Harga harus diisi untuk produk baru")
+		}
+
+		if value := cell("cost_price"); value != "" {
+			costPrice, err := parseImportNumber(value, localized, locale)
+			if err != nil || costPrice < 0 {
+				addError("Harga pokok harus berupa angka dan tidak boleh negatif")
+			} else {
+				costPrice = roundMoney(costPrice)
+				row.costPrice = &costPrice
+			}
+		}
+
+		if value := cell("unit"); value != "" {
+			if !productUnits[value] {
+				addError("Satuan harus salah satu dari pcs, kg, g, l, ml atau m")
+			} else {
+				row.unit = &value
+			}
+		}
+
+		if value := cell("qty_precision"); value != "" {
+			qtyPrecision, err := strconv.Atoi(value)
+			if err != nil || qtyPrecision < 0 || qtyPrecision > maxQtyPrecision {
+				addError("Presisi qty harus antara 0 dan 3")
+			} else {
+				row.qtyPrecision = &qtyPrecision
+				precision = qtyPrecision
+			}
+		}
+
+		if value := cell("stock"); value != "" {
+			stock, err := parseImportNumber(value, localized, locale)
+			switch {
+			case err != nil || stock < 0:
+				addError("Stok harus berupa angka dan tidak boleh negatif")
+			case !validQtyPrecision(stock, precision):
+				addError("Stok maksimal %d angka desimal", precision)
+			case row.productType == productTypeComposite:
+				addError("Produk komposit tidak memiliki stok sendiri")
+			default:
+				stock = roundQty(stock)
+				row.stock = &stock
+			}
+		}
+
+		if value := cell("barcode"); value != "" {
+			if !validBarcode(value) {
+				addError("Barcode %s tidak valid", value)
+			} else if previous, ok := seenBarcode[value]; ok {
+				addError("Barcode %s sudah dipakai di baris %d", value, previous)
+			} else {
+				taken, err := barcodeTaken(value, row.productID)
+				if err != nil {
+					responses.InternalError(w, err)
+					return
+				}
+				if taken {
+					addError("Barcode %s sudah dipakai produk lain", value)
+				}
+				row.barcode = &value
+			}
+			seenBarcode[value] = row.Row
+		}
+
+		if len(row.Errors) > 0 {
+			row.Action = "skip"
+			invalid++
+		} else {
+			valid++
+		}
+		rows = append(rows, row)
+	}
+
+	report := map[string]interface{}{
+		"dry_run": dryRun,
+		"total":   len(rows),
+		"valid":   valid,
+		"invalid": invalid,
+		"rows":    rows,
+	}
+
+	if dryRun {
+		responses.SuccessResponse(w, "Validasi import selesai, tidak ada data yang disimpan", report, http.StatusOK)
+		return
+	}
+
+	created, updated, err := applyProductImport(rows, storeID, currentUserID(r))
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan import produk, tidak ada data yang disimpan: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+	report["created"] = created
+	report["updated"] = updated
+
+	responses.SuccessResponse(w, "Import produk selesai", report, http.StatusOK)
+}
+
+// importSKUAttempts adalah batas percobaan membuat SKU otomatis yang belum dipakai.
+const importSKUAttempts = 20
+
+// generateImportSKU membuat SKU dari huruf pertama nama produk dan enam digit acak. SKU diperiksa
+// terhadap tabel products di dalam transaksi dan terhadap SKU lain di file import.
+func generateImportSKU(tx *sql.Tx, name string, used map[string]bool) (string, error) {
+	prefix := "P"
+	if first, _ := utf8.DecodeRuneInString(name); unicode.IsLetter(first) || unicode.IsDigit(first) {
+		prefix = strings.ToUpper(string(first))
+	}
+
+	for i := 0; i < importSKUAttempts; i++ {
+		sku := fmt.Sprintf("%s%06d", prefix, rand.Intn(1000000))
+		if used[sku] {
+			continue
+		}
+		var count int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM products WHERE sku = ?", sku).Scan(&count); err != nil {
+			return "", err
+		}
+		if count == 0 {
+			used[sku] = true
+			return sku, nil
+		}
+	}
+	return "", fmt.Errorf("gagal membuat SKU unik untuk %s, isi kolom SKU", name)
+}
+
+// applyProductImport menyimpan baris import yang valid dalam satu transaksi. Perubahan stok
+// dicatat sebagai pergerakan stok di store yang aktif.
+func applyProductImport(rows []importRow, storeID int64, userID *int) (int, int, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	currentTime := time.Now()
+	var created, updated int
+
+	// SKU di file tidak boleh dipakai oleh SKU yang dibuat otomatis
+	usedSKU := map[string]bool{}
+	for _, row := range rows {
+		if row.SKU != "" {
+			usedSKU[row.SKU] = true
+		}
+	}
+
+	for _, row := range rows {
+		switch row.Action {
+		case "create":
+			// SKU dibuat otomatis jika tidak diisi
+			sku := row.SKU
+			if sku == "" {
+				sku, err = generateImportSKU(tx, row.Name, usedSKU)
+				if err != nil {
+					return 0, 0, fmt.Errorf("baris %d: %v", row.Row, err)
+				}
+			}
+			unit := "pcs"
+			if row.unit != nil {
+				unit = *row.unit
+			}
+			qtyPrecision := 0
+			if row.qtyPrecision != nil {
+				qtyPrecision = *row.qtyPrecision
+			}
+			costPrice := 0.0
+			if row.costPrice != nil {
+				costPrice = *row.costPrice
+			}
+
+			result, err := tx.Exec("INSERT INTO products (category_id, name, sku, barcode, unit, qty_precision, price, cost_price, stock, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, '', ?, ?)",
+				row.categoryID, row.Name, sku, row.barcode, unit, qtyPrecision, *row.price, costPrice, currentTime, currentTime)
+			if err != nil {
+				return 0, 0, fmt.Errorf("baris %d: %v", row.Row, err)
+			}
+			productID, err := result.LastInsertId()
+			if err != nil {
+				return 0, 0, err
+			}
+
+			err = recordPriceHistory(tx, priceHistoryEntry{ProductID: productID, Price: row.price, Source: priceSourceImport, UserID: userID})
+			if err != nil {
+				return 0, 0, err
+			}
+
+			if row.stock != nil && *row.stock > 0 {
+				err = postStockMovement(tx, stockMovement{
+					StoreID:   storeID,
+					ProductID: productID,
+					Type:      movementInitialStock,
+					Qty:       *row.stock,
+					UnitCost:  &costPrice,
+					Note:      "Stok awal dari import produk",
+				})
+				if err != nil {
+					return 0, 0, fmt.Errorf("baris %d: %v", row.Row, err)
+				}
+			}
+			created++
+
+		case "update":
+			sets := []string{}
+			args := []interface{}{}
+			if row.Name != "" {
+				sets = append(sets, "name = ?")
+				args = append(args, row.Name)
+			}
+			if row.categoryID != nil {
+				sets = append(sets, "category_id = ?")
+				args = append(args, *row.categoryID)
+			}
+			if row.price != nil {
+				// price_updated_at diisi sebelum price agar masih membandingkan dengan harga lama
+				sets = append(sets, "price_updated_at = IF(price = ?, price_updated_at, NOW())", "price = ?")
+				args = append(args, strconv.Itoa(*row.price), *row.price)
+			}
+			if row.costPrice != nil {
+				sets = append(sets, "cost_price = ?")
+				args = append(args, *row.costPrice)
+			}
+			if row.barcode != nil {
+				sets = append(sets, "barcode = ?")
+				args = append(args, *row.barcode)
+			}
+			if row.unit != nil {
+				sets = append(sets, "unit = ?")
+				args = append(args, *row.unit)
+			}
+			if row.qtyPrecision != nil {
+				sets = append(sets, "qty_precision = ?")
+				args = append(args, *row.qtyPrecision)
+			}
+
+			// harga lama dibaca lebih dulu untuk riwayat harga
+			oldPrice, err := lockProductPrice(tx, row.productID)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			if len(sets) > 0 {
+				args = append(args, row.productID)
+				_, err := tx.Exec("UPDATE products SET "+strings.Join(sets, ", ")+", updated_at = NOW() WHERE id = ?", args...)
+				if err != nil {
+					return 0, 0, fmt.Errorf("baris %d: %v", row.Row, err)
+				}
+			}
+			if row.price != nil {
+				err = recordPriceHistory(tx, priceHistoryEntry{ProductID: row.productID, OldPrice: &oldPrice, Price: row.price, Source: priceSourceImport, UserID: userID})
+				if err != nil {
+					return 0, 0, err
+				}
+			}
+			if row.costPrice != nil {
+				if err := rollUpCompositesUsing(tx, row.productID); err != nil {
+					return 0, 0, err
+				}
+			}
+
+			// stok di file adalah stok akhir, yang dicatat adalah selisihnya
+			if row.stock != nil {
+				var currentStock float64
+				err := tx.QueryRow("SELECT COALESCE((SELECT stock FROM product_stocks WHERE store_id = ? AND product_id = ? FOR UPDATE), 0)", storeID, row.productID).Scan(&currentStock)
+				if err != nil {
+					return 0, 0, err
+				}
+				if delta := roundQty(*row.stock - currentStock); delta != 0 {
+					err = postStockMovement(tx, stockMovement{
+						StoreID:   storeID,
+						ProductID: row.productID,
+						Type:      movementManualAdjustment,
+						Qty:       delta,
+						Note:      "Perubahan stok dari import produk",
+					})
+					if err != nil {
+						return 0, 0, fmt.Errorf("baris %d: %v", row.Row, err)
+					}
+				}
+			}
+			updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
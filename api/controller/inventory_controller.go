@@ -0,0 +1,457 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// jenis pergerakan stok yang dicatat di tabel stock_movements
+const (
+	movementPurchaseReceipt     = "purchase_receipt"
+	movementSale                = "sale"
+	movementStocktakeAdjustment = "stocktake_adjustment"
+	movementInitialStock        = "initial_stock"
+	movementManualAdjustment    = "manual_adjustment"
+	movementTransferOut         = "transfer_out"
+	movementTransferIn          = "transfer_in"
+)
+
+// stockMovement adalah satu baris pergerakan stok. Qty positif untuk stok masuk
+// dan negatif untuk stok keluar.
+type stockMovement struct {
+	StoreID       int64
+	ProductID     int64
+	Type          string
+	Qty           float64
+	UnitCost      *float64
+	ReferenceType string
+	ReferenceID   int64
+	Note          string
+}
+
+// metode harga pokok penjualan produk
+const (
+	costingAverage = "average"
+	costingFIFO    = "fifo"
+)
+
+// maxQtyPrecision adalah jumlah digit desimal terbesar yang bisa disimpan kolom qty (DECIMAL(15,3)).
+const maxQtyPrecision = 3
+
+// roundQty membulatkan qty ke presisi kolom qty di database.
+func roundQty(qty float64) float64 {
+	return math.Round(qty*1000) / 1000
+}
+
+// validQtyPrecision memastikan qty tidak memiliki digit desimal lebih banyak dari precision.
+func validQtyPrecision(qty float64, precision int) bool {
+	scale := math.Pow(10, float64(precision))
+	return math.Abs(qty*scale-math.Round(qty*scale)) < 1e-6
+}
+
+// formatQty mengubah qty menjadi teks tanpa nol di belakang koma, misalnya 2 atau 0.75.
+func formatQty(qty float64) string {
+	return strconv.FormatFloat(roundQty(qty), 'f', -1, 64)
+}
+
+// qtyText membungkus ekspresi qty SQL agar dibaca sebagai teks tanpa nol di belakang koma,
+// misalnya kolom DECIMAL 10.000 menjadi 10.
+func qtyText(expr string) string {
+	return "TRIM(TRAILING '.' FROM TRIM(TRAILING '0' FROM CAST(" + expr + " AS DECIMAL(15,3))))"
+}
+
+// postStockMovement mencatat pergerakan stok dan memperbarui stok produk di dalam transaksi tx.
+// Stok store disimpan di product_stocks, sedangkan products.stock adalah total semua store.
+func postStockMovement(tx *sql.Tx, movement stockMovement) error {
+	_, err := postStockMovementCost(tx, movement)
+	return err
+}
+
+// postStockMovementCost sama seperti postStockMovement dan juga mengembalikan harga pokok stok
+// yang keluar (0 untuk stok masuk). Stok masuk menjadi lapisan biaya baru dengan UnitCost atau
+// cost_price produk, stok keluar mengurangi lapisan terlama lebih dulu. Harga pokok dihitung
+// dari lapisan tersebut untuk produk fifo dan dari cost_price untuk produk average.
+func postStockMovementCost(tx *sql.Tx, movement stockMovement) (float64, error) {
+	// products.stock bertipe teks, jadi total baru dihitung di sini agar tidak tersimpan sebagai 10.000
+	var currentStock, costPrice float64
+	var costingMethod string
+	err := tx.QueryRow("SELECT CAST(stock AS DECIMAL(15,3)), cost_price, costing_method FROM products WHERE id = ? FOR UPDATE", movement.ProductID).Scan(&currentStock, &costPrice, &costingMethod)
+	if err != nil {
+		return 0, err
+	}
+
+	var cost float64
+	if movement.Qty > 0 {
+		unitCost := costPrice
+		if movement.UnitCost != nil {
+			unitCost = *movement.UnitCost
+		}
+		if unitCost < 0 {
+			return 0, fmt.Errorf("harga pokok produk %d tidak boleh negatif", movement.ProductID)
+		}
+		_, err = tx.Exec("INSERT INTO cost_layers (store_id, product_id, qty, qty_remaining, unit_cost, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			movement.StoreID, movement.ProductID, movement.Qty, movement.Qty, unitCost, time.Now())
+		if err != nil {
+			return 0, err
+		}
+	} else if movement.Qty < 0 {
+		layerCost, consumed, err := consumeCostLayers(tx, movement.StoreID, movement.ProductID, -movement.Qty)
+		if err != nil {
+			return 0, err
+		}
+
+		// stok yang keluar melebihi lapisan yang tersisa (stok minus) dinilai dengan cost_price
+		cost = -movement.Qty * costPrice
+		if costingMethod == costingFIFO {
+			cost = layerCost + (-movement.Qty-consumed)*costPrice
+		}
+		cost = roundMoney(cost)
+
+		// stok keluar tanpa biaya (misalnya penjualan) dicatat dengan harga pokok per unitnya
+		if movement.UnitCost == nil {
+			unitCost := roundMoney(cost / -movement.Qty)
+			movement.UnitCost = &unitCost
+		}
+	}
+
+	_, err = tx.Exec("INSERT INTO stock_movements (store_id, product_id, type, qty, unit_cost, reference_type, reference_id, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		movement.StoreID, movement.ProductID, movement.Type, movement.Qty, movement.UnitCost, movement.ReferenceType, movement.ReferenceID, movement.Note, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+	    INSERT INTO product_stocks (store_id, product_id, stock, updated_at) VALUES (?, ?, ?, NOW())
+	    ON DUPLICATE KEY UPDATE stock = stock + VALUES(stock), updated_at = NOW()`,
+		movement.StoreID, movement.ProductID, movement.Qty)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE products SET stock = ?, updated_at = NOW() WHERE id = ?", formatQty(currentStock+movement.Qty), movement.ProductID)
+	return cost, err
+}
+
+// consumeCostLayers mengurangi qty dari lapisan biaya store mulai dari yang terlama. Mengembalikan
+// total biaya qty yang diambil dan jumlah qty yang tertutup lapisan; sisanya berarti stok minus.
+func consumeCostLayers(tx *sql.Tx, storeID int64, productID int64, qty float64) (float64, float64, error) {
+	type costLayer struct {
+		ID        int64
+		Remaining float64
+		UnitCost  float64
+	}
+
+	rows, err := tx.Query("SELECT id, qty_remaining, unit_cost FROM cost_layers WHERE store_id = ? AND product_id = ? AND qty_remaining > 0 ORDER BY created_at, id FOR UPDATE", storeID, productID)
+	if err != nil {
+		return 0, 0, err
+	}
+	var layers []costLayer
+	for rows.Next() {
+		var layer costLayer
+		if err := rows.Scan(&layer.ID, &layer.Remaining, &layer.UnitCost); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		layers = append(layers, layer)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	var cost, consumed float64
+	for _, layer := range layers {
+		if roundQty(qty-consumed) <= 0 {
+			break
+		}
+		take := math.Min(layer.Remaining, roundQty(qty-consumed))
+		_, err := tx.Exec("UPDATE cost_layers SET qty_remaining = qty_remaining - ? WHERE id = ?", take, layer.ID)
+		if err != nil {
+			return 0, 0, err
+		}
+		cost += take * layer.UnitCost
+		consumed += take
+	}
+	return cost, roundQty(consumed), nil
+}
+
+// roundMoney membulatkan nilai biaya ke presisi kolom biaya di database (DECIMAL(15,2)).
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// applyReceiptCost menghitung ulang cost_price produk dengan metode rata-rata tertimbang
+// berdasarkan stok yang ada dan barang yang baru diterima. Dipanggil sebelum stok diperbarui.
+func applyReceiptCost(tx *sql.Tx, productID int64, qty float64, unitCost float64) error {
+	var stock float64
+	var costPrice float64
+	err := tx.QueryRow("SELECT CAST(stock AS DECIMAL(15,3)), cost_price FROM products WHERE id = ? FOR UPDATE", productID).Scan(&stock, &costPrice)
+	if err != nil {
+		return err
+	}
+
+	// stok negatif tidak ikut dihitung sebagai nilai persediaan
+	if stock < 0 {
+		stock = 0
+	}
+
+	newCost := unitCost
+	if stock+qty > 0 {
+		newCost = (stock*costPrice + qty*unitCost) / (stock + qty)
+	}
+
+	_, err = tx.Exec("UPDATE products SET cost_price = ? WHERE id = ?", newCost, productID)
+	if err != nil {
+		return err
+	}
+
+	// biaya produk komposit yang memakai produk ini ikut berubah
+	return rollUpCompositesUsing(tx, productID)
+}
+
+// ListStockMovements menampilkan riwayat pergerakan stok, bisa difilter per produk.
+func ListStockMovements(w http.ResponseWriter, r *http.Request) {
+	type StockMovement struct {
+		ID            int64    `json:"id"`
+		StoreID       *int64   `json:"store_id"`
+		ProductID     int64    `json:"product_id"`
+		Type          string   `json:"type"`
+		Qty           float64  `json:"qty"`
+		UnitCost      *float64 `json:"unit_cost"`
+		ReferenceType *string  `json:"reference_type"`
+		ReferenceID   *int64   `json:"reference_id"`
+		Note          *string  `json:"note"`
+		CreatedAt     string   `json:"created_at"`
+	}
+
+	// Parse query parameters
+	productIDStr := r.URL.Query().Get("productId")
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+
+	if storeID != nil {
+		query += " AND store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	if productIDStr != "" {
+		productID, err := strconv.ParseInt(productIDStr, 10, 64)
+		if err != nil {
+			responses.FieldErrorResponse(w, "productId", "Invalid 'productId' parameter")
+			return
+		}
+		query += " AND product_id = ?"
+		args = append(args, productID)
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM stock_movements WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query("SELECT id, store_id, product_id, type, qty, unit_cost, reference_type, reference_id, note, created_at FROM stock_movements WHERE 1=1"+
+		query+keyset+" ORDER BY id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	movements := []StockMovement{}
+
+	for rows.Next() {
+		var movement StockMovement
+		err := rows.Scan(&movement.ID, &movement.StoreID, &movement.ProductID, &movement.Type, &movement.Qty, &movement.UnitCost,
+			&movement.ReferenceType, &movement.ReferenceID, &movement.Note, &movement.CreatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		movements = append(movements, movement)
+	}
+
+	var next *string
+	if page.hasMore(len(movements)) {
+		movements = movements[:page.Limit]
+		next = nextCursor("id", "", movements[len(movements)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":      page.meta(total, next),
+			"movements": movements,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// ListLowStock menampilkan produk yang stoknya sudah mencapai atau di bawah reorder point.
+func ListLowStock(w http.ResponseWriter, r *http.Request) {
+	type LowStockProduct struct {
+		StoreID      int64   `json:"store_id"`
+		ID           int64   `json:"id"`
+		SKU          string  `json:"sku"`
+		Name         string  `json:"name"`
+		Stock        float64 `json:"stock"`
+		ReorderPoint int     `json:"reorder_point"`
+		ReorderQty   int     `json:"reorder_qty"`
+		SuggestedQty float64 `json:"suggested_qty"`
+		CategoryID   *int64  `json:"category_id"`
+		CategoryName *string `json:"category_name"`
+	}
+
+	categoryIDStr := r.URL.Query().Get("categoryId")
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	// Reorder point berlaku untuk stok setiap store
+	query := `
+	    SELECT ps.store_id, p.id, p.sku, p.name, ps.stock, p.reorder_point, p.reorder_qty, c.id, c.name
+	    FROM product_stocks ps
+	    JOIN products p ON ps.product_id = p.id
+	    LEFT JOIN categories c ON p.category_id = c.id AND c.deleted_at IS NULL
+	    WHERE p.reorder_point > 0 AND ps.stock <= p.reorder_point AND p.deleted_at IS NULL
+	`
+	args := []interface{}{}
+
+	if storeID != nil {
+		query += " AND ps.store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	if categoryIDStr != "" {
+		categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
+		if err != nil {
+			responses.FieldErrorResponse(w, "categoryId", "Invalid 'categoryId' parameter")
+			return
+		}
+		query += " AND p.category_id = ?"
+		args = append(args, categoryID)
+	}
+
+	query += " ORDER BY ps.stock - p.reorder_point ASC"
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	products := []LowStockProduct{}
+
+	for rows.Next() {
+		var product LowStockProduct
+		err := rows.Scan(&product.StoreID, &product.ID, &product.SKU, &product.Name, &product.Stock, &product.ReorderPoint, &product.ReorderQty,
+			&product.CategoryID, &product.CategoryName)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		// Jumlah yang disarankan minimal cukup untuk kembali ke atas reorder point
+		product.SuggestedQty = float64(product.ReorderQty)
+		if shortage := float64(product.ReorderPoint) - product.Stock + 1; shortage > product.SuggestedQty {
+			product.SuggestedQty = shortage
+		}
+
+		products = append(products, product)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta": map[string]interface{}{
+				"total": len(products),
+			},
+			"products": products,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// UpdateReorderSettings mengatur reorder point dan reorder qty sebuah produk.
+func UpdateReorderSettings(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var settings struct {
+		ReorderPoint int `json:"reorder_point" validate:"min=0"`
+		ReorderQty   int `json:"reorder_qty" validate:"min=0"`
+	}
+
+	if !decodeRequest(w, r, &settings) {
+		return
+	}
+
+	var count int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND deleted_at IS NULL", productID).Scan(&count)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if count == 0 {
+		responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	_, err = config.DB.Exec("UPDATE products SET reorder_point = ?, reorder_qty = ?, updated_at = NOW() WHERE id = ?",
+		settings.ReorderPoint, settings.ReorderQty, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID           int64 `json:"id"`
+		ReorderPoint int   `json:"reorder_point"`
+		ReorderQty   int   `json:"reorder_qty"`
+	}{
+		ID:           productID,
+		ReorderPoint: settings.ReorderPoint,
+		ReorderQty:   settings.ReorderQty,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
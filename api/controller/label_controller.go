@@ -0,0 +1,396 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contentUnits memetakan satuan isi kemasan ke satuan harga satuan di label beserta faktor pengalinya.
+var contentUnits = map[string]struct {
+	PerUnit string
+	Factor  float64
+}{
+	"g":   {PerUnit: "kg", Factor: 1000},
+	"kg":  {PerUnit: "kg", Factor: 1},
+	"ml":  {PerUnit: "l", Factor: 1000},
+	"l":   {PerUnit: "l", Factor: 1},
+	"pcs": {PerUnit: "pcs", Factor: 1},
+}
+
+// labelTemplate adalah tata letak lembar stiker. Semua ukuran dalam milimeter.
+type labelTemplate struct {
+	Name        string  `json:"name"`
+	PageWidth   float64 `json:"page_width"`
+	PageHeight  float64 `json:"page_height"`
+	Columns     int     `json:"columns"`
+	Rows        int     `json:"rows"`
+	LabelWidth  float64 `json:"label_width"`
+	LabelHeight float64 `json:"label_height"`
+	MarginTop   float64 `json:"margin_top"`
+	MarginLeft  float64 `json:"margin_left"`
+	GapX        float64 `json:"gap_x"`
+	GapY        float64 `json:"gap_y"`
+	ShowBarcode bool    `json:"show_barcode"`
+	ShowBorder  bool    `json:"show_border"`
+}
+
+// labelTemplates berisi tata letak lembar stiker A4 yang umum dipakai.
+var labelTemplates = map[string]labelTemplate{
+	"a4-21": {Name: "a4-21", PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 7, LabelWidth: 63.5, LabelHeight: 38.1,
+		MarginTop: 15.15, MarginLeft: 7.2, GapX: 2.5, GapY: 0, ShowBarcode: true},
+	"a4-24": {Name: "a4-24", PageWidth: 210, PageHeight: 297, Columns: 3, Rows: 8, LabelWidth: 63.5, LabelHeight: 33.9,
+		MarginTop: 12.9, MarginLeft: 7.2, GapX: 2.5, GapY: 0, ShowBarcode: true},
+	"a4-14": {Name: "a4-14", PageWidth: 210, PageHeight: 297, Columns: 2, Rows: 7, LabelWidth: 99.1, LabelHeight: 38.1,
+		MarginTop: 15.15, MarginLeft: 4.65, GapX: 2.5, GapY: 0, ShowBarcode: true},
+	"a4-65": {Name: "a4-65", PageWidth: 210, PageHeight: 297, Columns: 5, Rows: 13, LabelWidth: 38.1, LabelHeight: 21.2,
+		MarginTop: 10.7, MarginLeft: 4.65, GapX: 2.5, GapY: 0, ShowBarcode: false},
+}
+
+// defaultLabelTemplate dipakai jika permintaan tidak menyebutkan template.
+const defaultLabelTemplate = "a4-21"
+
+// validate memeriksa apakah semua label muat di dalam halaman.
+func (t labelTemplate) validate() error {
+	if t.PageWidth <= 0 || t.PageHeight <= 0 || t.Columns <= 0 || t.Rows <= 0 || t.LabelWidth <= 0 || t.LabelHeight <= 0 {
+		return fmt.Errorf("Ukuran halaman, jumlah kolom/baris dan ukuran label harus lebih dari 0")
+	}
+	if t.MarginLeft+float64(t.Columns)*t.LabelWidth+float64(t.Columns-1)*t.GapX > t.PageWidth ||
+		t.MarginTop+float64(t.Rows)*t.LabelHeight+float64(t.Rows-1)*t.GapY > t.PageHeight {
+		return fmt.Errorf("Label tidak muat di dalam halaman")
+	}
+	return nil
+}
+
+// shelfLabel adalah data yang dicetak pada satu label rak.
+type shelfLabel struct {
+	ProductID   int64
+	Name        string
+	Price       int
+	ContentQty  *float64
+	ContentUnit *string
+	Barcode     *string
+	SKU         string
+}
+
+// unitPrice menghitung harga per kg, per l atau per pcs berdasarkan isi kemasan.
+func (l shelfLabel) unitPrice() string {
+	if l.ContentQty == nil || l.ContentUnit == nil || *l.ContentQty <= 0 {
+		return ""
+	}
+	unit, ok := contentUnits[*l.ContentUnit]
+	if !ok {
+		return ""
+	}
+	perUnit := float64(l.Price) / *l.ContentQty * unit.Factor
+	return formatRupiah(int(perUnit+0.5)) + " / " + unit.PerUnit
+}
+
+// formatRupiah memformat angka menjadi Rp dengan pemisah ribuan titik.
+func formatRupiah(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.Itoa(amount)
+	var parts []string
+	for len(digits) > 3 {
+		parts = append([]string{digits[len(digits)-3:]}, parts...)
+		digits = digits[:len(digits)-3]
+	}
+	parts = append([]string{digits}, parts...)
+	return sign + "Rp " + strings.Join(parts, ".")
+}
+
+// mmToPt mengubah milimeter ke point PDF.
+func mmToPt(mm float64) float64 {
+	return mm * 72 / 25.4
+}
+
+// pdfText meng-escape teks untuk string PDF dan mengganti karakter di luar Latin-1.
+func pdfText(text string) string {
+	var buffer strings.Builder
+	for _, c := range text {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			buffer.WriteRune('\\')
+			buffer.WriteRune(c)
+		case c < 32:
+			buffer.WriteRune(' ')
+		case c > 255:
+			buffer.WriteRune('?')
+		default:
+			buffer.WriteByte(byte(c))
+		}
+	}
+	return buffer.String()
+}
+
+// fitText memotong teks agar muat di lebar tertentu. Lebar huruf Helvetica diperkirakan
+// setengah ukuran font.
+func fitText(text string, width float64, fontSize float64) string {
+	maxChars := int(width / (fontSize * 0.5))
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	if maxChars <= 3 {
+		return string(runes[:maxChars])
+	}
+	return string(runes[:maxChars-3]) + "..."
+}
+
+// drawShelfLabel menulis perintah PDF untuk satu label dengan pojok kiri bawah di (x, y).
+func drawShelfLabel(content *bytes.Buffer, label shelfLabel, x float64, y float64, template labelTemplate) {
+	width := mmToPt(template.LabelWidth)
+	height := mmToPt(template.LabelHeight)
+	padding := mmToPt(2)
+
+	if template.ShowBorder {
+		fmt.Fprintf(content, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, y, width, height)
+	}
+
+	// ukuran font mengikuti tinggi label agar template kecil tetap terbaca
+	nameSize := height * 0.1
+	priceSize := height * 0.22
+	smallSize := height * 0.07
+	if nameSize > 10 {
+		nameSize = 10
+	}
+	if priceSize > 20 {
+		priceSize = 20
+	}
+	if smallSize > 7 {
+		smallSize = 7
+	}
+
+	innerWidth := width - 2*padding
+	cursor := y + height - padding - nameSize
+
+	fmt.Fprintf(content, "BT /F2 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", nameSize, x+padding, cursor, pdfText(fitText(label.Name, innerWidth, nameSize)))
+
+	cursor -= priceSize + 1
+	fmt.Fprintf(content, "BT /F2 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", priceSize, x+padding, cursor, pdfText(formatRupiah(label.Price)))
+
+	if unitPrice := label.unitPrice(); unitPrice != "" {
+		cursor -= smallSize + 2
+		fmt.Fprintf(content, "BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", smallSize, x+padding, cursor, pdfText(fitText(unitPrice, innerWidth, smallSize)))
+	}
+
+	code := label.SKU
+	if label.Barcode != nil {
+		code = *label.Barcode
+	}
+
+	// batang barcode hanya digambar jika berupa EAN-13 dan masih ada ruang di bawah teks
+	barTop := cursor - 3
+	barBottom := y + padding + smallSize + 1
+	if template.ShowBarcode && label.Barcode != nil && validEAN13(*label.Barcode) && barTop-barBottom >= mmToPt(5) {
+		moduleWidth := innerWidth / 95
+		if moduleWidth > mmToPt(0.33) {
+			moduleWidth = mmToPt(0.33)
+		}
+		for i, bar := range ean13Modules(*label.Barcode) {
+			if bar {
+				fmt.Fprintf(content, "%.3f %.2f %.3f %.2f re f\n", x+padding+float64(i)*moduleWidth, barBottom, moduleWidth, barTop-barBottom)
+			}
+		}
+	}
+
+	fmt.Fprintf(content, "BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", smallSize, x+padding, y+padding, pdfText(fitText(code, innerWidth, smallSize)))
+}
+
+// renderShelfLabelsPDF menyusun label ke lembar-lembar stiker dan mengembalikan dokumen PDF.
+func renderShelfLabelsPDF(labels []shelfLabel, template labelTemplate) []byte {
+	perPage := template.Columns * template.Rows
+	pageWidth := mmToPt(template.PageWidth)
+	pageHeight := mmToPt(template.PageHeight)
+
+	var pages [][]byte
+	for start := 0; start < len(labels); start += perPage {
+		var content bytes.Buffer
+		for i := start; i < start+perPage && i < len(labels); i++ {
+			position := i - start
+			column := position % template.Columns
+			row := position / template.Columns
+			x := mmToPt(template.MarginLeft + float64(column)*(template.LabelWidth+template.GapX))
+			// koordinat PDF dimulai dari kiri bawah halaman
+			y := pageHeight - mmToPt(template.MarginTop+float64(row)*(template.LabelHeight+template.GapY)+template.LabelHeight)
+			drawShelfLabel(&content, labels[i], x, y, template)
+		}
+		pages = append(pages, content.Bytes())
+	}
+
+	// objek 1: catalog, 2: pages, 3-4: font, lalu pasangan page dan content per halaman
+	var document bytes.Buffer
+	var offsets []int
+	writeObject := func(body string) {
+		offsets = append(offsets, document.Len())
+		fmt.Fprintf(&document, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	document.WriteString("%PDF-1.4\n")
+
+	var kids []string
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+i*2))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content)+1, content))
+	}
+
+	xrefOffset := document.Len()
+	fmt.Fprintf(&document, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&document, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&document, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return document.Bytes()
+}
+
+// ListLabelTemplates menampilkan template lembar stiker yang tersedia.
+func ListLabelTemplates(w http.ResponseWriter, r *http.Request) {
+	var templates []labelTemplate
+	for _, name := range []string{"a4-14", "a4-21", "a4-24", "a4-65"} {
+		templates = append(templates, labelTemplates[name])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", templates, http.StatusOK)
+}
+
+// PrintShelfLabels membuat PDF label rak untuk produk yang dipilih atau produk yang harganya
+// berubah sejak waktu tertentu. Harga mengikuti store yang aktif jika ada.
+func PrintShelfLabels(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ProductIDs     []int64        `json:"product_ids" validate:"dive,exists=products"`
+		ChangedSince   string         `json:"changed_since"` // format RFC3339
+		Template       string         `json:"template"`
+		CustomTemplate *labelTemplate `json:"custom_template"`
+		Copies         int            `json:"copies" validate:"max=100"`
+	}
+
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	if len(request.ProductIDs) == 0 && request.ChangedSince == "" {
+		responses.FieldErrorResponse(w, "product_ids", "product_ids atau changed_since harus diisi")
+		return
+	}
+
+	if request.Copies <= 0 {
+		request.Copies = 1
+	}
+
+	template := labelTemplates[defaultLabelTemplate]
+	if request.CustomTemplate != nil {
+		template = *request.CustomTemplate
+		template.Name = "custom"
+	} else if request.Template != "" {
+		var ok bool
+		template, ok = labelTemplates[request.Template]
+		if !ok {
+			responses.ErrorResponse(w, "Template label "+request.Template+" tidak ditemukan", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := template.validate(); err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := `
+	    SELECT p.id, p.name, CAST(p.price AS SIGNED), p.content_qty, p.content_unit, p.barcode, p.sku
+	    FROM products p
+	    WHERE p.deleted_at IS NULL
+	`
+	args := []interface{}{}
+
+	if storeID != nil {
+		query = `
+		    SELECT p.id, p.name, COALESCE(ps.price, CAST(p.price AS SIGNED)), p.content_qty, p.content_unit, p.barcode, p.sku
+		    FROM products p
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+		    WHERE p.deleted_at IS NULL
+		`
+		args = append(args, *storeID)
+	}
+
+	// produk induk yang memiliki varian tidak dijual langsung sehingga tidak perlu label
+	query += " AND NOT EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id AND v.deleted_at IS NULL)"
+
+	if len(request.ProductIDs) > 0 {
+		query += " AND p.id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(request.ProductIDs)), ",") + ")"
+		for _, id := range request.ProductIDs {
+			args = append(args, id)
+		}
+	}
+
+	if request.ChangedSince != "" {
+		changedSince, err := time.Parse(time.RFC3339, request.ChangedSince)
+		if err != nil {
+			responses.ErrorResponse(w, "Format changed_since harus RFC3339", http.StatusBadRequest)
+			return
+		}
+		// produk baru juga butuh label, sehingga created_at dipakai jika harga belum pernah berubah
+		if storeID != nil {
+			query += " AND (COALESCE(p.price_updated_at, p.created_at) >= ? OR ps.price_updated_at >= ?)"
+			args = append(args, changedSince, changedSince)
+		} else {
+			query += " AND COALESCE(p.price_updated_at, p.created_at) >= ?"
+			args = append(args, changedSince)
+		}
+	}
+
+	query += " ORDER BY p.name"
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	var labels []shelfLabel
+	for rows.Next() {
+		var label shelfLabel
+		if err := rows.Scan(&label.ProductID, &label.Name, &label.Price, &label.ContentQty, &label.ContentUnit, &label.Barcode, &label.SKU); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		for i := 0; i < request.Copies; i++ {
+			labels = append(labels, label)
+		}
+	}
+
+	if len(labels) == 0 {
+		responses.ErrorResponse(w, "Tidak ada produk yang perlu dicetak labelnya", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"shelf-labels-%s.pdf\"", time.Now().Format("20060102150405")))
+	w.Write(renderShelfLabelsPDF(labels, template))
+}
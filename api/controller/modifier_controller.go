@@ -0,0 +1,474 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// modifierOption adalah satu pilihan modifier, misalnya Extra shot dengan tambahan harga 5000.
+type modifierOption struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"price_delta"`
+}
+
+// modifierGroup adalah kelompok modifier dengan batas jumlah pilihan. MaxSelect 0 berarti tanpa batas.
+type modifierGroup struct {
+	ID        int64            `json:"id"`
+	Name      string           `json:"name"`
+	MinSelect int              `json:"min_select"`
+	MaxSelect int              `json:"max_select"`
+	Modifiers []modifierOption `json:"modifiers"`
+}
+
+// orderModifier adalah modifier yang dipilih pada baris order.
+type orderModifier struct {
+	ModifierID int64  `json:"modifier_id"`
+	Group      string `json:"group"`
+	Name       string `json:"name"`
+	PriceDelta int    `json:"price_delta"`
+}
+
+// loadModifierOptions mengisi pilihan modifier untuk setiap kelompok di groups.
+func loadModifierOptions(db sqlQueryer, groups []modifierGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	positions := map[int64]int{}
+	args := []interface{}{}
+	for i, group := range groups {
+		positions[group.ID] = i
+		args = append(args, group.ID)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(groups)), ",")
+	rows, err := db.Query("SELECT id, modifier_group_id, name, price_delta FROM modifiers WHERE modifier_group_id IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var option modifierOption
+		var groupID int64
+		if err := rows.Scan(&option.ID, &groupID, &option.Name, &option.PriceDelta); err != nil {
+			return err
+		}
+		i := positions[groupID]
+		groups[i].Modifiers = append(groups[i].Modifiers, option)
+	}
+	return rows.Err()
+}
+
+// loadProductModifierGroups mengambil kelompok modifier yang berlaku untuk produk: yang ditautkan
+// langsung ke produk, ke produk induk untuk varian, atau ke kategori produk.
+func loadProductModifierGroups(db sqlQueryer, productID int64) ([]modifierGroup, error) {
+	rows, err := db.Query(`
+	    SELECT DISTINCT g.id, g.name, g.min_select, g.max_select
+	    FROM modifier_groups g
+	    JOIN modifier_group_links l ON l.modifier_group_id = g.id
+	    JOIN products p ON p.id = ?
+	    LEFT JOIN categories c ON c.id = p.category_id AND c.deleted_at IS NULL
+	    WHERE l.product_id = p.id OR l.product_id = p.parent_id OR l.category_id = c.id
+	    ORDER BY g.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []modifierGroup{}
+	for rows.Next() {
+		group := modifierGroup{Modifiers: []modifierOption{}}
+		if err := rows.Scan(&group.ID, &group.Name, &group.MinSelect, &group.MaxSelect); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	rows.Close()
+
+	if err := loadModifierOptions(db, groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// selectModifiers mencocokkan modifier yang dipilih kasir dengan kelompok modifier produk
+// dan memeriksa batas min_select dan max_select setiap kelompok.
+func selectModifiers(groups []modifierGroup, modifierIDs []int64) ([]orderModifier, error) {
+	type choice struct {
+		Group  int
+		Option modifierOption
+	}
+
+	available := map[int64]choice{}
+	for i, group := range groups {
+		for _, option := range group.Modifiers {
+			available[option.ID] = choice{Group: i, Option: option}
+		}
+	}
+
+	selected := []orderModifier{}
+	counts := make([]int, len(groups))
+	seen := map[int64]bool{}
+	for _, modifierID := range modifierIDs {
+		if seen[modifierID] {
+			return nil, fmt.Errorf("Modifier dengan ID %d dipilih lebih dari sekali", modifierID)
+		}
+		seen[modifierID] = true
+
+		c, ok := available[modifierID]
+		if !ok {
+			return nil, fmt.Errorf("Modifier dengan ID %d tidak berlaku untuk produk ini", modifierID)
+		}
+		counts[c.Group]++
+		selected = append(selected, orderModifier{
+			ModifierID: c.Option.ID,
+			Group:      groups[c.Group].Name,
+			Name:       c.Option.Name,
+			PriceDelta: c.Option.PriceDelta,
+		})
+	}
+
+	for i, group := range groups {
+		if counts[i] < group.MinSelect {
+			return nil, fmt.Errorf("Pilih minimal %d modifier dari %s", group.MinSelect, group.Name)
+		}
+		if group.MaxSelect > 0 && counts[i] > group.MaxSelect {
+			return nil, fmt.Errorf("Pilih maksimal %d modifier dari %s", group.MaxSelect, group.Name)
+		}
+	}
+	return selected, nil
+}
+
+// loadOrderLineModifiers mengambil modifier yang tersimpan untuk baris order, dikelompokkan per baris.
+func loadOrderLineModifiers(orderProductIDs []int64) (map[int64][]orderModifier, error) {
+	modifiers := map[int64][]orderModifier{}
+	if len(orderProductIDs) == 0 {
+		return modifiers, nil
+	}
+
+	args := []interface{}{}
+	for _, id := range orderProductIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(orderProductIDs)), ",")
+
+	rows, err := config.DB.Query("SELECT order_product_id, modifier_id, group_name, name, price_delta FROM order_product_modifiers WHERE order_product_id IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderProductID int64
+		var modifier orderModifier
+		if err := rows.Scan(&orderProductID, &modifier.ModifierID, &modifier.Group, &modifier.Name, &modifier.PriceDelta); err != nil {
+			return nil, err
+		}
+		modifiers[orderProductID] = append(modifiers[orderProductID], modifier)
+	}
+	return modifiers, rows.Err()
+}
+
+// CreateModifierGroups membuat kelompok modifier beserta pilihannya.
+func CreateModifierGroups(w http.ResponseWriter, r *http.Request) {
+	type Modifier struct {
+		Name       string `json:"name" validate:"required,max=255"`
+		PriceDelta int    `json:"price_delta"`
+	}
+
+	var request struct {
+		Name      string     `json:"name" validate:"required,max=255"`
+		MinSelect int        `json:"min_select" validate:"min=0"`
+		MaxSelect *int       `json:"max_select" validate:"min=0"` // default 1, 0 berarti tanpa batas
+		Modifiers []Modifier `json:"modifiers" validate:"required"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	maxSelect := 1
+	if request.MaxSelect != nil {
+		maxSelect = *request.MaxSelect
+	}
+	request.Name = strings.TrimSpace(request.Name)
+	if maxSelect > 0 && request.MinSelect > maxSelect {
+		responses.FieldErrorResponse(w, "min_select", "min_select tidak boleh lebih besar dari max_select")
+		return
+	}
+	if request.MinSelect > len(request.Modifiers) {
+		responses.FieldErrorResponse(w, "min_select", "min_select melebihi jumlah modifier")
+		return
+	}
+
+	currentTime := time.Now()
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO modifier_groups (name, min_select, max_select, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		request.Name, request.MinSelect, maxSelect, currentTime, currentTime)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan kelompok modifier: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	groupID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID kelompok modifier yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	group := modifierGroup{ID: groupID, Name: request.Name, MinSelect: request.MinSelect, MaxSelect: maxSelect}
+	for _, modifier := range request.Modifiers {
+		modifier.Name = strings.TrimSpace(modifier.Name)
+
+		modifierResult, err := tx.Exec("INSERT INTO modifiers (modifier_group_id, name, price_delta, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			groupID, modifier.Name, modifier.PriceDelta, currentTime, currentTime)
+		if err != nil {
+			if isDuplicateEntry(err) {
+				responses.ErrorResponse(w, "Modifier "+modifier.Name+" duplikat", http.StatusConflict)
+				return
+			}
+			responses.InternalError(w, err)
+			return
+		}
+
+		modifierID, err := modifierResult.LastInsertId()
+		if err != nil {
+			responses.ErrorResponse(w, "Gagal mendapatkan ID modifier yang baru", http.StatusInternalServerError)
+			return
+		}
+		group.Modifiers = append(group.Modifiers, modifierOption{ID: modifierID, Name: modifier.Name, PriceDelta: modifier.PriceDelta})
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responses.SuccessResponse(w, "Success", group, http.StatusCreated)
+}
+
+// ListModifierGroups menampilkan kelompok modifier beserta pilihan dan tautannya.
+func ListModifierGroups(w http.ResponseWriter, r *http.Request) {
+	type ModifierGroup struct {
+		modifierGroup
+		ProductIDs  []int64 `json:"product_ids"`
+		CategoryIDs []int64 `json:"category_ids"`
+	}
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	var total int
+	if err := config.DB.QueryRow("SELECT COUNT(*) FROM modifier_groups").Scan(&total); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", false, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+
+	rows, err := config.DB.Query("SELECT id, name, min_select, max_select FROM modifier_groups WHERE 1=1"+keyset+" ORDER BY id"+limitSQL, append(keysetArgs, limitArgs...)...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	groups := []modifierGroup{}
+	for rows.Next() {
+		group := modifierGroup{Modifiers: []modifierOption{}}
+		if err := rows.Scan(&group.ID, &group.Name, &group.MinSelect, &group.MaxSelect); err != nil {
+			rows.Close()
+			responses.InternalError(w, err)
+			return
+		}
+		groups = append(groups, group)
+	}
+	rows.Close()
+
+	var next *string
+	if page.hasMore(len(groups)) {
+		groups = groups[:page.Limit]
+		next = nextCursor("id", "", groups[len(groups)-1].ID)
+	}
+
+	if err := loadModifierOptions(config.DB, groups); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	modifierGroups := []ModifierGroup{}
+	for _, group := range groups {
+		modifierGroup := ModifierGroup{modifierGroup: group, ProductIDs: []int64{}, CategoryIDs: []int64{}}
+
+		linkRows, err := config.DB.Query("SELECT product_id, category_id FROM modifier_group_links WHERE modifier_group_id = ? ORDER BY id", group.ID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		for linkRows.Next() {
+			var productID, categoryID sql.NullInt64
+			if err := linkRows.Scan(&productID, &categoryID); err != nil {
+				linkRows.Close()
+				responses.InternalError(w, err)
+				return
+			}
+			if productID.Valid {
+				modifierGroup.ProductIDs = append(modifierGroup.ProductIDs, productID.Int64)
+			}
+			if categoryID.Valid {
+				modifierGroup.CategoryIDs = append(modifierGroup.CategoryIDs, categoryID.Int64)
+			}
+		}
+		linkRows.Close()
+
+		modifierGroups = append(modifierGroups, modifierGroup)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":            page.meta(total, next),
+			"modifier_groups": modifierGroups,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// LinkModifierGroups mengganti daftar produk dan kategori yang memakai kelompok modifier.
+func LinkModifierGroups(w http.ResponseWriter, r *http.Request) {
+	groupID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID kelompok modifier tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		ProductIDs  []int64 `json:"product_ids" validate:"dive,exists=products"`
+		CategoryIDs []int64 `json:"category_ids" validate:"dive,exists=categories"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	var count int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM modifier_groups WHERE id = ?", groupID).Scan(&count)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if count == 0 {
+		responses.ErrorResponse(w, "Kelompok modifier tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM modifier_group_links WHERE modifier_group_id = ?", groupID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	currentTime := time.Now()
+	for _, productID := range request.ProductIDs {
+		_, err := tx.Exec("INSERT INTO modifier_group_links (modifier_group_id, product_id, created_at) VALUES (?, ?, ?)", groupID, productID, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Produk dengan ID %d muncul lebih dari sekali: %v", productID, err)
+			responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+			return
+		}
+	}
+	for _, categoryID := range request.CategoryIDs {
+		_, err := tx.Exec("INSERT INTO modifier_group_links (modifier_group_id, category_id, created_at) VALUES (?, ?, ?)", groupID, categoryID, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Category dengan ID %d muncul lebih dari sekali: %v", categoryID, err)
+			responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID          int64   `json:"id"`
+		ProductIDs  []int64 `json:"product_ids"`
+		CategoryIDs []int64 `json:"category_ids"`
+	}{
+		ID:          groupID,
+		ProductIDs:  request.ProductIDs,
+		CategoryIDs: request.CategoryIDs,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// ListProductModifierGroups menampilkan kelompok modifier yang bisa dipilih kasir untuk sebuah produk.
+func ListProductModifierGroups(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var count int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND deleted_at IS NULL", productID).Scan(&count)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if count == 0 {
+		responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	groups, err := loadProductModifierGroups(config.DB, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta": map[string]interface{}{
+				"total": len(groups),
+			},
+			"modifier_groups": groups,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// jenis notifikasi yang disimpan di tabel notifications
+const (
+	notificationLowStock = "low_stock"
+)
+
+// lowStockAlert menandai produk di sebuah store yang stoknya baru saja turun ke bawah reorder point.
+type lowStockAlert struct {
+	StoreID   int64
+	ProductID int64
+}
+
+// lowStockAlerts adalah antrean peringatan yang diproses oleh StartLowStockWorker.
+var lowStockAlerts = make(chan lowStockAlert, 256)
+
+// StartLowStockWorker menjalankan job latar belakang yang memproses peringatan stok menipis.
+func StartLowStockWorker() {
+	go func() {
+		for alert := range lowStockAlerts {
+			if err := emitLowStockAlert(alert); err != nil {
+				log.Printf("Gagal mengirim peringatan stok menipis untuk produk %d: %v\n", alert.ProductID, err)
+			}
+		}
+	}()
+}
+
+// queueLowStockAlert memasukkan produk ke antrean peringatan tanpa memblokir request.
+func queueLowStockAlert(storeID int64, productID int64) {
+	select {
+	case lowStockAlerts <- lowStockAlert{StoreID: storeID, ProductID: productID}:
+	default:
+		log.Printf("Antrean peringatan stok penuh, produk %d dilewati\n", productID)
+	}
+}
+
+// emitLowStockAlert menyimpan notifikasi stok menipis dan, jika LOW_STOCK_WEBHOOK_URL diisi,
+// mengirimkannya ke webhook.
+func emitLowStockAlert(alert lowStockAlert) error {
+	var name, sku, storeName string
+	var stock float64
+	var reorderPoint, reorderQty int
+	err := config.DB.QueryRow(`
+	    SELECT p.name, p.sku, s.name, ps.stock, p.reorder_point, p.reorder_qty
+	    FROM product_stocks ps
+	    JOIN products p ON ps.product_id = p.id
+	    JOIN stores s ON ps.store_id = s.id
+	    WHERE ps.store_id = ? AND ps.product_id = ?`, alert.StoreID, alert.ProductID).
+		Scan(&name, &sku, &storeName, &stock, &reorderPoint, &reorderQty)
+	if err != nil {
+		return err
+	}
+
+	// Stok bisa saja sudah diisi ulang sebelum job berjalan
+	if stock > float64(reorderPoint) {
+		return nil
+	}
+
+	message := fmt.Sprintf("Stok produk %s (%s) di %s tersisa %s, di bawah reorder point %d", name, sku, storeName, formatQty(stock), reorderPoint)
+	currentTime := time.Now()
+	_, err = config.DB.Exec("INSERT INTO notifications (store_id, type, product_id, message, created_at) VALUES (?, ?, ?, ?, ?)",
+		alert.StoreID, notificationLowStock, alert.ProductID, message, currentTime)
+	if err != nil {
+		return err
+	}
+
+	webhookURL := os.Getenv("LOW_STOCK_WEBHOOK_URL")
+	if webhookURL == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":          notificationLowStock,
+		"store_id":      alert.StoreID,
+		"product_id":    alert.ProductID,
+		"sku":           sku,
+		"name":          name,
+		"stock":         stock,
+		"reorder_point": reorderPoint,
+		"reorder_qty":   reorderQty,
+		"message":       message,
+		"created_at":    currentTime.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook mengembalikan status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func ListNotifications(w http.ResponseWriter, r *http.Request) {
+	type Notification struct {
+		ID        int64   `json:"id"`
+		StoreID   *int64  `json:"store_id"`
+		Type      string  `json:"type"`
+		ProductID *int64  `json:"product_id"`
+		Message   string  `json:"message"`
+		ReadAt    *string `json:"read_at"`
+		CreatedAt string  `json:"created_at"`
+	}
+
+	// Parse query parameters
+	unread := r.URL.Query().Get("unread")
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+
+	if storeID != nil {
+		query += " AND store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	if unread == "true" {
+		query += " AND read_at IS NULL"
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM notifications WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query("SELECT id, store_id, type, product_id, message, read_at, created_at FROM notifications WHERE 1=1"+
+		query+keyset+" ORDER BY id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+
+	for rows.Next() {
+		var notification Notification
+		err := rows.Scan(&notification.ID, &notification.StoreID, &notification.Type, &notification.ProductID, &notification.Message, &notification.ReadAt, &notification.CreatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		notifications = append(notifications, notification)
+	}
+
+	var next *string
+	if page.hasMore(len(notifications)) {
+		notifications = notifications[:page.Limit]
+		next = nextCursor("id", "", notifications[len(notifications)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":          page.meta(total, next),
+			"notifications": notifications,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// ReadNotifications menandai notifikasi sebagai sudah dibaca.
+func ReadNotifications(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID notifikasi tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var storeID *int64
+	err = config.DB.QueryRow("SELECT store_id FROM notifications WHERE id = ?", notificationID).Scan(&storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Notifikasi tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	// notifikasi store lain tidak boleh ditandai dibaca agar store tersebut tetap melihatnya
+	if !canAccessStoreOf(r, storeID) {
+		responses.ErrorResponse(w, "Tidak memiliki akses ke notifikasi store ini", http.StatusForbidden)
+		return
+	}
+
+	result, err := config.DB.Exec("UPDATE notifications SET read_at = NOW() WHERE id = ? AND read_at IS NULL", notificationID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		responses.ErrorResponse(w, "Notifikasi tidak ditemukan atau sudah dibaca", http.StatusNotFound)
+		return
+	}
+
+	responses.OtherResponses(w, "Success", http.StatusOK)
+}
@@ -2,21 +2,40 @@ package controller
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"golang-api/api/middleware"
 	"golang-api/api/responses"
 	"golang-api/config"
+	"log"
+	"math"
 	"math/rand"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 )
 
+// status order; order yang dibuat lewat CreateOrders langsung selesai. Order berstatus refunded
+// dihitung sebagai refund di laporan penjualan.
+const (
+	orderStatusCompleted = "completed"
+	orderStatusRefunded  = "refunded"
+)
+
+// orderLineSnapshot adalah data produk yang disalin ke baris order saat penjualan, sehingga struk
+// lama tidak ikut berubah ketika produk diubah atau dihapus. Tax dan discount disimpan 0 selama
+// belum ada aturan pajak dan diskon per baris.
+type orderLineSnapshot struct {
+	Name      string `json:"name"`
+	SKU       string `json:"sku"`
+	Unit      string `json:"unit"`
+	UnitPrice int    `json:"unit_price"`
+	Tax       int    `json:"tax"`
+	Discount  int    `json:"discount"`
+}
+
 func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	// declared struct
 	type Payments struct {
@@ -27,75 +46,46 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type OrderProduct struct {
-		Id          int64 `json:"id"`
-		Order_id    int64 `json:"order_id"`
-		ProductID   int   `json:"product_id"`
-		Qty         int   `json:"qty"`
-		Total_price int   `json:"total_price"`
+		Id          int64           `json:"id"`
+		Order_id    int64           `json:"order_id"`
+		ProductID   int             `json:"product_id"`
+		VariantID   *int            `json:"variant_id,omitempty"`
+		Barcode     string          `json:"barcode,omitempty"` // barcode timbangan, qty dan harga diambil dari barcode
+		ModifierIDs []int64         `json:"modifier_ids,omitempty"`
+		Modifiers   []orderModifier `json:"modifiers,omitempty"`
+		Qty         float64         `json:"qty" validate:"unless=barcode,positive"`
+		Total_price int             `json:"total_price"`
+		orderLineSnapshot
 	}
 
 	type CreateOrderRequest struct {
-		PaymentID int            `json:"payment_id"`
-		TotalPaid int            `json:"total_paid"`
-		Products  []OrderProduct `json:"products"`
+		PaymentID int            `json:"payment_id" validate:"required,exists=payments"`
+		TotalPaid int            `json:"total_paid" validate:"min=0"`
+		Customer  *string        `json:"customer" validate:"max=255"` // nama pelanggan, boleh dikosongkan
+		Products  []OrderProduct `json:"products" validate:"required"`
 	}
 
 	// create var to handle request from body
 	var request CreateOrderRequest
-	err := json.NewDecoder(r.Body).Decode(&request)
-	if err != nil {
-		errorMessage := fmt.Sprintf("Gagal membaca data produk dari permintaan: %v", err)
-		responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+	if !decodeRequest(w, r, &request) {
 		return
 	}
-	// decode token dan ambil data dari token
-	// declare struct untuk simpan data dari token
-	type DataUsers struct {
-		Username string `json:"username"`
-		UserId   int    `json:"user_id"`
-		jwt.StandardClaims
-	}
-	// Ambil secret key dari environment variable
-	secretKeyString := os.Getenv("SECRET_KEY")
-	secretKey := []byte(secretKeyString)
-
-	auth := r.Header.Get("Authorization")
-	if auth == "" {
-		responses.ErrorResponse(w, "Unauthorized: Missing token", http.StatusUnauthorized)
-		return
-	}
-	splitToken := strings.Split(auth, "Bearer ")
-	if len(splitToken) < 2 {
-		responses.ErrorResponse(w, "Unauthorized: Invalid token format", http.StatusUnauthorized)
-		return
-	}
-	auth = splitToken[1]
-
-	// Mendekode token dengan klaim DataUsers
-	token, err := jwt.ParseWithClaims(auth, &DataUsers{}, func(token *jwt.Token) (interface{}, error) {
-		// Periksa metode tanda tangan token (optional)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secretKey, nil
-	})
-
-	if err != nil || !token.Valid {
-		errorMessage := fmt.Sprintf("Token is invalid or expired: %v", err)
-		responses.ErrorResponse(w, errorMessage, http.StatusUnauthorized)
-		return
-	}
-
-	// Mengekstrak klaim dari token
-	claims, ok := token.Claims.(*DataUsers)
-	if !ok {
+	// ambil data pengguna dari token yang sudah diverifikasi middleware
+	claims := middleware.CurrentUser(r)
+	if claims == nil {
 		responses.ErrorResponse(w, "Unauthorized: Invalid token claims", http.StatusUnauthorized)
 		return
 	}
-	// Mengekstrak username dan user_id dari klaim dan mencetaknya
 	username := claims.Username
 	userID := claims.UserId
 
+	// order selalu dicatat di store milik kasir
+	storeID, err := requireStore(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
 	// Membuat slice untuk menampung hasil query
 
 	// ambil data payment dari db
@@ -105,39 +95,182 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	payment.Id = paymentID
 
 	// Lakukan query ke database
-	err = config.DB.QueryRow("SELECT name, type, logo FROM payments WHERE id=?", paymentID).Scan(&payment.Name, &payment.Type, &payment.Logo)
+	err = config.DB.QueryRow("SELECT name, type, logo FROM payments WHERE id=? AND deleted_at IS NULL", paymentID).Scan(&payment.Name, &payment.Type, &payment.Logo)
 	// Periksa apakah ada kesalahan dalam query
 	if err != nil {
 		if err == sql.ErrNoRows {
 			responses.ErrorResponse(w, "Payment dengan ID "+strconv.Itoa(paymentID)+" tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
+		return
+	}
+
+	// Transaksi dipakai agar pengecekan stok, penyimpanan order dan pengurangan stok terjadi bersamaan
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
 		return
 	}
+	defer tx.Rollback()
 
 	// Menghitung total harga untuk pesanan dan memeriksa stok produk
 	var total_price int
+	requestedQty := map[int]float64{} // total qty per produk, untuk produk yang muncul lebih dari sekali
+	lowStockProducts := []int64{}     // produk yang stoknya turun melewati reorder point karena penjualan ini
+	lineDeductions := [][]stockMovement{}
 
 	for i, orderProduct := range request.Products {
 		productID := orderProduct.ProductID
-		var stock, price int
-		err := config.DB.QueryRow("SELECT stock, price FROM products WHERE id=?", productID).Scan(&stock, &price)
+
+		// barcode timbangan menentukan produk lewat PLU
+		var scale *scaleBarcode
+		if orderProduct.Barcode != "" {
+			barcode, ok := parseScaleBarcode(orderProduct.Barcode)
+			if !ok {
+				responses.ErrorResponse(w, "Barcode "+orderProduct.Barcode+" bukan barcode timbangan", http.StatusBadRequest)
+				return
+			}
+			err := tx.QueryRow("SELECT id FROM products WHERE plu = ? AND deleted_at IS NULL", barcode.PLU).Scan(&productID)
+			if err != nil {
+				responses.ErrorResponse(w, "Produk dengan PLU "+barcode.PLU+" tidak ditemukan", http.StatusNotFound)
+				return
+			}
+			scale = &barcode
+			request.Products[i].ProductID = productID
+		}
+
+		// varian memiliki stok dan harga sendiri, sehingga yang dijual adalah baris varian
+		if orderProduct.VariantID != nil {
+			var parentID sql.NullInt64
+			err := tx.QueryRow("SELECT parent_id FROM products WHERE id = ? AND deleted_at IS NULL", *orderProduct.VariantID).Scan(&parentID)
+			if err != nil || !parentID.Valid || (productID != 0 && int64(productID) != parentID.Int64) {
+				responses.ErrorResponse(w, "Varian dengan ID "+strconv.Itoa(*orderProduct.VariantID)+" tidak ditemukan", http.StatusNotFound)
+				return
+			}
+			productID = *orderProduct.VariantID
+			request.Products[i].ProductID = productID
+		}
+
+		var stock float64
+		var price, reorderPoint, variantCount, qtyPrecision int
+		var unit, productType, name, sku string
+		// stok dan harga diambil dari store; harga khusus store menggantikan harga produk
+		err := tx.QueryRow(`
+		    SELECT p.name, p.sku, COALESCE(ps.stock, 0), COALESCE(ps.price, p.price), p.reorder_point, p.unit, p.qty_precision, p.type,
+		           (SELECT COUNT(*) FROM products v WHERE v.parent_id = p.id AND v.deleted_at IS NULL)
+		    FROM products p
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+		    WHERE p.id = ? AND p.deleted_at IS NULL FOR UPDATE`, storeID, productID).Scan(&name, &sku, &stock, &price, &reorderPoint, &unit, &qtyPrecision, &productType, &variantCount)
 		if err != nil {
-			responses.ErrorResponse(w, "Produk dengan ID "+strconv.Itoa(productID)+" tidak ditemukan", http.StatusNotFound)
+			if err == sql.ErrNoRows {
+				responses.ErrorResponse(w, "Produk dengan ID "+strconv.Itoa(productID)+" tidak ditemukan", http.StatusNotFound)
+				return
+			}
+			responses.InternalError(w, err)
 			return
 		}
 
-		if orderProduct.Qty > stock {
-			responses.ErrorResponse(w, "Stok produk dengan ID "+strconv.Itoa(productID)+" tidak mencukupi", http.StatusConflict)
+		if variantCount > 0 {
+			responses.ErrorResponse(w, "Produk dengan ID "+strconv.Itoa(productID)+" memiliki varian, sertakan variant_id", http.StatusBadRequest)
 			return
 		}
 
-		// Menghitung total harga produk berdasarkan kuantitas dan harga dari database
-		totalPrice := orderProduct.Qty * price
+		// modifier yang dipilih harus berasal dari kelompok modifier produk ini
+		groups, err := loadProductModifierGroups(tx, int64(productID))
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		modifiers, err := selectModifiers(groups, orderProduct.ModifierIDs)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+		request.Products[i].Modifiers = modifiers
 
-		// Menyimpan total harga ke dalam OrderProduct
+		var modifierPrice int
+		for _, modifier := range modifiers {
+			modifierPrice += modifier.PriceDelta
+		}
+
+		// Menghitung total harga produk berdasarkan kuantitas dan harga dari database,
+		// untuk produk timbangan harga satuan dikali berat. Harga modifier berlaku per qty.
+		totalPrice := int(math.Round(orderProduct.Qty * float64(price+modifierPrice)))
+		if scale != nil {
+			orderProduct.Qty, totalPrice = scaleQty(*scale, unit, qtyPrecision, price)
+			totalPrice += int(math.Round(orderProduct.Qty * float64(modifierPrice)))
+			request.Products[i].Qty = orderProduct.Qty
+		}
+
+		if orderProduct.Qty <= 0 {
+			responses.ErrorResponse(w, "Qty produk dengan ID "+strconv.Itoa(productID)+" harus lebih dari 0", http.StatusBadRequest)
+			return
+		}
+
+		if !validQtyPrecision(orderProduct.Qty, qtyPrecision) {
+			errorMessage := fmt.Sprintf("Qty produk dengan ID %d maksimal %d angka desimal (%s)", productID, qtyPrecision, unit)
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+
+		// produk komposit tidak memiliki stok sendiri, yang dikurangi adalah stok komponennya
+		deductions := []stockMovement{{ProductID: int64(productID), Qty: orderProduct.Qty}}
+		if productType == productTypeComposite {
+			components, err := loadComponents(tx, int64(productID))
+			if err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+			if len(components) == 0 {
+				responses.ErrorResponse(w, "Produk komposit dengan ID "+strconv.Itoa(productID)+" belum memiliki komponen", http.StatusBadRequest)
+				return
+			}
+
+			deductions = deductions[:0]
+			for _, component := range components {
+				deductions = append(deductions, stockMovement{ProductID: component.ComponentID, Qty: roundQty(component.Qty * orderProduct.Qty)})
+			}
+		}
+
+		for _, deduction := range deductions {
+			deductedID := int(deduction.ProductID)
+			deductedStock, deductedReorderPoint := stock, reorderPoint
+			if deductedID != productID {
+				err := tx.QueryRow(`
+				    SELECT COALESCE(ps.stock, 0), p.reorder_point
+				    FROM products p
+				    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+				    WHERE p.id = ? AND p.deleted_at IS NULL FOR UPDATE`, storeID, deductedID).Scan(&deductedStock, &deductedReorderPoint)
+				if err != nil {
+					// komponen yang sudah dihapus tidak boleh lagi dikurangi stoknya
+					if err == sql.ErrNoRows {
+						errorMessage := fmt.Sprintf("Komponen dengan ID %d pada produk dengan ID %d sudah dihapus", deductedID, productID)
+						responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+						return
+					}
+					responses.InternalError(w, err)
+					return
+				}
+			}
+
+			stockBefore := deductedStock - requestedQty[deductedID]
+			requestedQty[deductedID] = roundQty(requestedQty[deductedID] + deduction.Qty)
+			if requestedQty[deductedID] > deductedStock {
+				responses.ErrorResponse(w, "Stok produk dengan ID "+strconv.Itoa(deductedID)+" tidak mencukupi", http.StatusConflict)
+				return
+			}
+
+			stockAfter := deductedStock - requestedQty[deductedID]
+			if deductedReorderPoint > 0 && stockBefore > float64(deductedReorderPoint) && stockAfter <= float64(deductedReorderPoint) {
+				lowStockProducts = append(lowStockProducts, int64(deductedID))
+			}
+		}
+		lineDeductions = append(lineDeductions, deductions)
+
+		// Menyimpan total harga dan snapshot produk ke dalam OrderProduct
 		request.Products[i].Total_price = totalPrice
+		request.Products[i].orderLineSnapshot = orderLineSnapshot{Name: name, SKU: sku, Unit: unit, UnitPrice: price}
 
 		// Menambahkan total harga produk dalam pesanan
 		total_price += totalPrice
@@ -154,8 +287,11 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	receipt_code := fmt.Sprintf("%s%d", firstLetter, randomDigits) //generate random string for receipt code
 
 	currentTime := time.Now() //waktu saat ini
-	orderResult, err := config.DB.Exec("INSERT INTO orders (user_id, name, payment_id, total_price, total_paid, total_return, receipt_code, created_at,  updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-		userID, username, paymentID, total_price, TotalPaid, total_return, receipt_code, currentTime, currentTime)
+	if request.Customer != nil && strings.TrimSpace(*request.Customer) == "" {
+		request.Customer = nil
+	}
+	orderResult, err := tx.Exec("INSERT INTO orders (store_id, user_id, name, customer_name, status, payment_id, total_price, total_paid, total_return, receipt_code, created_at,  updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		storeID, userID, username, request.Customer, orderStatusCompleted, paymentID, total_price, TotalPaid, total_return, receipt_code, currentTime, currentTime)
 	if err != nil {
 		errorMessage := fmt.Sprintf("Gagal menyimpan orders ke database: %v", err)
 
@@ -169,14 +305,43 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	}
 	// insert data to OrderProducts
 	var productsInfo []OrderProduct
-	for _, orderProduct := range request.Products {
+	for i, orderProduct := range request.Products {
 		Qty := orderProduct.Qty
 		productID := orderProduct.ProductID
 		TotalPrice := orderProduct.Total_price
 
+		// Kurangi stok produk (atau stok komponennya) dan catat sebagai pergerakan stok penjualan.
+		// Harga pokok baris adalah jumlah harga pokok semua stok yang dikurangi.
+		var totalCost float64
+		for _, deduction := range lineDeductions[i] {
+			note := "Penjualan " + receipt_code
+			if deduction.ProductID != int64(productID) {
+				note += fmt.Sprintf(" (komponen produk %d)", productID)
+			}
+
+			cost, err := postStockMovementCost(tx, stockMovement{
+				StoreID:       storeID,
+				ProductID:     deduction.ProductID,
+				Type:          movementSale,
+				Qty:           -deduction.Qty,
+				ReferenceType: "order",
+				ReferenceID:   lastInsertID,
+				Note:          note,
+			})
+			if err != nil {
+				errorMessage := fmt.Sprintf("Gagal mengurangi stok produk: %v", err)
+				responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+				return
+			}
+			totalCost += cost
+		}
+		totalCost = roundMoney(totalCost)
+		unitCost := roundMoney(totalCost / Qty)
+
 		// Insert data ke dalam order_products
-		orderProductsResult, err := config.DB.Exec("INSERT INTO order_products (order_id, product_id, qty, total_price, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
-			lastInsertID, productID, Qty, TotalPrice, currentTime, currentTime)
+		snapshot := orderProduct.orderLineSnapshot
+		orderProductsResult, err := tx.Exec("INSERT INTO order_products (order_id, product_id, product_name, sku, unit, unit_price, tax, discount, unit_cost, total_cost, qty, total_price, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			lastInsertID, productID, snapshot.Name, snapshot.SKU, snapshot.Unit, snapshot.UnitPrice, snapshot.Tax, snapshot.Discount, unitCost, totalCost, Qty, TotalPrice, currentTime, currentTime)
 		if err != nil {
 			errorMessage := fmt.Sprintf("Gagal menyimpan order_products ke database: %v", err)
 			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
@@ -190,22 +355,52 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		for _, modifier := range orderProduct.Modifiers {
+			_, err := tx.Exec("INSERT INTO order_product_modifiers (order_product_id, modifier_id, group_name, name, price_delta, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+				lastOrderId, modifier.ModifierID, modifier.Group, modifier.Name, modifier.PriceDelta, currentTime)
+			if err != nil {
+				errorMessage := fmt.Sprintf("Gagal menyimpan modifier order: %v", err)
+				responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+				return
+			}
+		}
+
 		// Simpan data ke dalam slice productsInfo
 		var product OrderProduct
 		product.Id = lastOrderId
 		product.Order_id = lastInsertID
 		product.ProductID = productID
+		product.VariantID = orderProduct.VariantID
+		product.Barcode = orderProduct.Barcode
+		product.Qty = Qty
+		product.Total_price = TotalPrice
+		product.Modifiers = orderProduct.Modifiers
+		product.orderLineSnapshot = snapshot
 		productsInfo = append(productsInfo, product)
 	}
 
+	if err := tx.Commit(); err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan orders ke database: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	// Peringatan stok menipis dikirim oleh job latar belakang agar tidak memperlambat kasir
+	for _, productID := range lowStockProducts {
+		queueLowStockAlert(storeID, productID)
+	}
+
 	type Response struct {
 		ID            int64          `json:"id"`
+		StoreID       int64          `json:"store_id"`
 		UserID        int            `json:"user_id"`
 		PaymentTypeID int            `json:"payment_type_id"`
 		TotalPrice    int            `json:"total_price"`
 		TotalPaid     int            `json:"total_paid"`
 		TotalReturn   int            `json:"total_return"`
 		ReceiptID     string         `json:"receipt_id"`
+		Status        string         `json:"status"`
+		Customer      *string        `json:"customer"`
 		Products      []OrderProduct `json:"products"`
 		PaymentType   Payments       `json:"payment_type"`
 		UpdatedAt     string         `json:"updated_at"`
@@ -213,12 +408,15 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	}
 	responseData := Response{
 		ID:            lastInsertID,
+		StoreID:       storeID,
 		UserID:        userID,
 		PaymentTypeID: paymentID,
 		TotalPrice:    total_price,
 		TotalPaid:     request.TotalPaid,
 		TotalReturn:   total_return,
 		ReceiptID:     receipt_code,
+		Status:        orderStatusCompleted,
+		Customer:      request.Customer,
 		Products:      productsInfo,
 		PaymentType:   payment,
 		UpdatedAt:     currentTime.Format(time.RFC3339),
@@ -228,82 +426,291 @@ func CreateOrders(w http.ResponseWriter, r *http.Request) {
 	responses.SuccessResponse(w, "success", responseData, http.StatusCreated)
 }
 
-func ListOrders(w http.ResponseWriter, r *http.Request) {
-	type OrderProduct struct {
-		Id          *int64 `json:"id"`
-		Order_id    *int64 `json:"order_id"`
-		ProductID   *int64 `json:"product_id"`
-		Qty         *int64 `json:"qty"`
-		Total_price *int64 `json:"total_normal_price"`
-	}
-
-	type Products struct {
-		ID        *int64  `json:"id"`
-		SKU       *string `json:"sku"`
-		Name      *string `json:"name"`
-		Stock     *string `json:"stock"`
-		Price     *string `json:"price"`
-		Image     *string `json:"image"`
-		CreatedAt *string `json:"created_at"`
-		UpdatedAt *string `json:"updated_at"`
+// orderPayment adalah metode pembayaran yang dipakai sebuah order.
+type orderPayment struct {
+	Id   int    `json:"payment_id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Logo string `json:"logo"`
+}
+
+// orderItem adalah baris order yang ditampilkan dari snapshot beserta modifier yang dipilih.
+type orderItem struct {
+	ID         int64           `json:"id"`
+	ProductID  int64           `json:"product_id"`
+	Qty        float64         `json:"qty"`
+	TotalPrice int             `json:"total_price"`
+	Modifiers  []orderModifier `json:"modifiers"`
+	orderLineSnapshot
+}
+
+// int64Args mengubah daftar ID menjadi placeholder dan argumen untuk klausa IN.
+func int64Args(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
 	}
-	type Payments struct {
-		Id   int    `json:"payment_id"`
-		Name string `json:"name"`
-		Type string `json:"type"`
-		Logo string `json:"logo"`
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
+
+// loadOrderPayments mengambil metode pembayaran untuk beberapa payment sekaligus, termasuk
+// yang sudah dihapus agar order lama tetap menampilkan pembayarannya.
+func loadOrderPayments(paymentIDs []int64) (map[int64]orderPayment, error) {
+	payments := map[int64]orderPayment{}
+	if len(paymentIDs) == 0 {
+		return payments, nil
+	}
+
+	placeholders, args := int64Args(paymentIDs)
+	rows, err := config.DB.Query("SELECT id, name, type, COALESCE(logo, '') FROM payments WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payment orderPayment
+		if err := rows.Scan(&payment.Id, &payment.Name, &payment.Type, &payment.Logo); err != nil {
+			return nil, err
+		}
+		payments[int64(payment.Id)] = payment
 	}
-	type Orders struct {
-		ID              int    `json:"id"`
-		User_id         int    `json:"user_id"`
-		Payment_type_id int    `json:"payment_type_id"`
-		Total_price     int    `json:"total_price"`
-		Total_paid      int    `json:"total_paid"`
-		Total_return    int    `json:"total_return"`
-		Receipt_id      string `json:"receipt_id"`
+	return payments, rows.Err()
+}
+
+// loadOrderItems mengambil baris beberapa order sekaligus beserta modifiernya, dikelompokkan per order.
+func loadOrderItems(orderIDs []int64) (map[int64][]orderItem, error) {
+	items := map[int64][]orderItem{}
+	if len(orderIDs) == 0 {
+		return items, nil
+	}
+
+	placeholders, args := int64Args(orderIDs)
+	rows, err := config.DB.Query(`
+	    SELECT order_id, id, product_id, qty, total_price,
+	           COALESCE(product_name, ''), COALESCE(sku, ''), COALESCE(unit, ''), COALESCE(unit_price, 0), tax, discount
+	    FROM order_products WHERE order_id IN (`+placeholders+`) ORDER BY order_id, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var itemIDs []int64
+	for rows.Next() {
+		var orderID int64
+		var item orderItem
+		err := rows.Scan(&orderID, &item.ID, &item.ProductID, &item.Qty, &item.TotalPrice,
+			&item.Name, &item.SKU, &item.Unit, &item.UnitPrice, &item.Tax, &item.Discount)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		items[orderID] = append(items[orderID], item)
+		itemIDs = append(itemIDs, item.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	modifiers, err := loadOrderLineModifiers(itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, orderID := range orderIDs {
+		for i := range items[orderID] {
+			items[orderID][i].Modifiers = modifiers[items[orderID][i].ID]
+			if items[orderID][i].Modifiers == nil {
+				items[orderID][i].Modifiers = []orderModifier{}
+			}
+		}
+	}
+	return items, nil
+}
+
+// ListOrders menampilkan order beserta pembayaran dan barisnya. Berapa pun jumlah order dalam satu
+// halaman, data diambil dengan jumlah query yang tetap: order, payment, baris order dan modifier.
+func ListOrders(w http.ResponseWriter, r *http.Request) {
+	type Order struct {
+		ID            int64        `json:"id"`
+		StoreID       *int64       `json:"store_id"`
+		UserID        *int64       `json:"user_id"`
+		PaymentTypeID *int64       `json:"payment_type_id"`
+		TotalPrice    int          `json:"total_price"`
+		TotalPaid     int          `json:"total_paid"`
+		TotalReturn   int          `json:"total_return"`
+		ReceiptID     string       `json:"receipt_id"`
+		Status        string       `json:"status"`
+		Customer      *string      `json:"customer"`
+		PaymentType   orderPayment `json:"payment_type"`
+		Items         []orderItem  `json:"items"`
+		CreatedAt     string       `json:"created_at"`
+		UpdatedAt     string       `json:"updated_at"`
 	}
 
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	skipStr := r.URL.Query().Get("skip")
-	categoryIDStr := r.URL.Query().Get("categoryId")
-	searchQuery := r.URL.Query().Get("q")
+	query := r.URL.Query()
+	categoryIDStr := query.Get("categoryId")
+	searchQuery := query.Get("q")
 
-	// Convert limit and skip parameters to integers
-	limit, err := strconv.Atoi(limitStr)
+	page, err := parsePagination(r)
 	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+		badRequest(w, err)
 		return
 	}
-
-	skip, err := strconv.Atoi(skipStr)
+	format, err := exportFormat(r)
 	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'skip' parameter", http.StatusBadRequest)
+		badRequest(w, err)
 		return
 	}
-	// get data orders from db
-	query := "SELECT id, user_id, payment_id, total_price, total_paid, total_return, receipt_code FROM orders WHERE 1=1"
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	where := ""
 	args := []interface{}{}
 
+	if storeID != nil {
+		where += " AND o.store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	// from dan to dibaca di zona waktu store
+	loc, err := storeLocation(storeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	dateFilter, dateArgs, err := dateRangeFilter("o.created_at", query.Get("from"), query.Get("to"), loc)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	where += dateFilter
+	args = append(args, dateArgs...)
+
+	// filter berupa ID: kasir dan metode pembayaran
+	for _, filter := range []struct{ param, column string }{
+		{"userId", "o.user_id"},
+		{"paymentId", "o.payment_id"},
+	} {
+		value := query.Get(filter.param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			responses.ErrorResponse(w, "Invalid '"+filter.param+"' parameter", http.StatusBadRequest)
+			return
+		}
+		where += " AND " + filter.column + " = ?"
+		args = append(args, id)
+	}
+
+	// filter total order dalam rupiah
+	for _, filter := range []struct{ param, operator string }{
+		{"minTotal", ">="},
+		{"maxTotal", "<="},
+	} {
+		value := query.Get(filter.param)
+		if value == "" {
+			continue
+		}
+		total, err := strconv.Atoi(value)
+		if err != nil {
+			responses.ErrorResponse(w, "Invalid '"+filter.param+"' parameter", http.StatusBadRequest)
+			return
+		}
+		where += " AND o.total_price " + filter.operator + " ?"
+		args = append(args, total)
+	}
+
+	if status := query.Get("status"); status != "" {
+		where += " AND o.status = ?"
+		args = append(args, status)
+	}
+
+	if customer := query.Get("customer"); customer != "" {
+		where += " AND o.customer_name LIKE ?"
+		args = append(args, "%"+customer+"%")
+	}
+
+	if receipt := query.Get("receipt"); receipt != "" {
+		where += " AND o.receipt_code LIKE ?"
+		args = append(args, strings.NewReplacer("%", "\\%", "_", "\\_").Replace(receipt)+"%")
+	}
+
+	// categoryId menampilkan order yang memiliki minimal satu produk dari kategori tersebut
 	if categoryIDStr != "" {
 		categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
 		if err != nil {
-			responses.ErrorResponse(w, "Invalid 'categoryId' parameter", http.StatusBadRequest)
+			responses.FieldErrorResponse(w, "categoryId", "Invalid 'categoryId' parameter")
 			return
 		}
-		query += " AND (category_id = ? OR category_id IS NULL)"
+		where += " AND EXISTS (SELECT 1 FROM order_products op JOIN products p ON op.product_id = p.id WHERE op.order_id = o.id AND p.category_id = ?)"
 		args = append(args, categoryID)
 	}
 
 	if searchQuery != "" {
-		query += " AND name LIKE ?"
-		args = append(args, "%"+searchQuery+"%")
+		where += " AND (o.receipt_code LIKE ? OR o.name LIKE ?)"
+		args = append(args, "%"+searchQuery+"%", "%"+searchQuery+"%")
 	}
 
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, limit, skip)
+	// sortBy: created_at atau total, sortDir: asc atau desc
+	sortColumns := map[string]string{"created_at": "o.created_at", "total": "o.total_price"}
+	sortBy := query.Get("sortBy")
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	sortColumn, ok := sortColumns[sortBy]
+	if !ok {
+		responses.FieldErrorResponse(w, "sortBy", "Invalid 'sortBy' parameter, gunakan created_at atau total")
+		return
+	}
+	sortDir := strings.ToUpper(query.Get("sortDir"))
+	if sortDir == "" {
+		sortDir = "DESC"
+	}
+	if sortDir != "ASC" && sortDir != "DESC" {
+		responses.FieldErrorResponse(w, "sortDir", "Invalid 'sortDir' parameter, gunakan asc atau desc")
+		return
+	}
+
+	// ekspor CSV/XLSX memakai filter dan urutan yang sama tanpa paginasi
+	if format != "" {
+		exportOrders(w, r, format, where, args, sortColumn+" "+sortDir+", o.id "+sortDir, loc)
+		return
+	}
 
-	rows, err := config.DB.Query(query, args...)
+	// total seluruh order yang cocok dengan filter, bukan hanya halaman ini
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM orders o WHERE 1=1"+where, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	// cursor menyimpan nilai kolom urutan dan ID order terakhir di halaman sebelumnya
+	sortKey := sortBy + ":" + strings.ToLower(sortDir)
+	keyset, keysetArgs, err := page.keyset(sortKey, sortColumn, "o.id", sortDir == "DESC", func(value string) (interface{}, error) {
+		if sortBy == "total" {
+			return strconv.Atoi(value)
+		}
+		return time.Parse(time.RFC3339Nano, value)
+	})
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+
+	listSQL := "SELECT o.id, o.store_id, o.user_id, o.payment_id, o.total_price, o.total_paid, o.total_return, o.receipt_code, o.status, o.customer_name, o.created_at, o.updated_at FROM orders o WHERE 1=1" +
+		where + keyset + " ORDER BY " + sortColumn + " " + sortDir + ", o.id " + sortDir + limitSQL
+	args = append(args, keysetArgs...)
+	args = append(args, limitArgs...)
+
+	rows, err := config.DB.Query(listSQL, args...)
 	if err != nil {
 		errorMessage := fmt.Sprintf("Gagal ambil data  orders: %v", err)
 
@@ -311,317 +718,188 @@ func ListOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var orders []Orders
-	var products []Products
-	var payments Payments
-	var product Products
-
+	orders := []Order{}
+	var orderIDs, paymentIDs []int64
 	for rows.Next() {
-		var order Orders
-		err := rows.Scan(&order.ID, &order.User_id, &order.Payment_type_id, &order.Total_price, &order.Total_paid, &order.Total_return, &order.Receipt_id)
+		var order Order
+		err := rows.Scan(&order.ID, &order.StoreID, &order.UserID, &order.PaymentTypeID, &order.TotalPrice, &order.TotalPaid, &order.TotalReturn, &order.ReceiptID,
+			&order.Status, &order.Customer, &order.CreatedAt, &order.UpdatedAt)
 		if err != nil {
+			rows.Close()
 			errorMessage := fmt.Sprintf("Gagal masukkan data orders ke var: %v", err)
 			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
 			return
 		}
 		orders = append(orders, order)
-
-		// end orders
-		defer rows.Close()
-		// get data payment from id payment in orders
-		err = config.DB.QueryRow("SELECT id, name, type, logo FROM payments WHERE id=?", order.Payment_type_id).Scan(&payments.Id, &payments.Name, &payments.Type, &payments.Logo)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				errorMessage := fmt.Sprintf("Gagal ambil data payments: %v", err)
-
-				responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-				return
-			}
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-			return
+		orderIDs = append(orderIDs, order.ID)
+		if order.PaymentTypeID != nil {
+			paymentIDs = append(paymentIDs, *order.PaymentTypeID)
 		}
-		// end payments
-		var orderProducts OrderProduct
-
-		// get data products from id products in orders
-
-		for _, orderProduct := range orders {
-			err := config.DB.QueryRow("SELECT product_id FROM order_products WHERE order_id=?", orderProduct.ID).Scan(
-				&orderProducts.ProductID,
-			)
-
-			if err != nil {
-				if err == sql.ErrNoRows {
-					errorMessage := fmt.Sprintf("order_products dengan ID %d tidak ditemukan", orderProduct.ID)
-					responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-					return
-				}
-			}
-		}
-		// get data products from id products in orders
-		var productIDs []int
-
-		// Mengambil data dari tabel order_products berdasarkan order_id
-		rows, err := config.DB.Query("SELECT product_id FROM order_products WHERE order_id=?", order.ID)
-		if err != nil {
-			// Handle database errors
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
 
-		// Iterasi melalui hasil query order_products dan tambahkan product_id ke dalam slice productIDs
-		for rows.Next() {
-			var productID int
-			err := rows.Scan(&productID)
-			if err != nil {
-				// Handle database errors
-				responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			productIDs = append(productIDs, productID)
+	var next *string
+	if page.hasMore(len(orders)) {
+		orders, orderIDs = orders[:page.Limit], orderIDs[:page.Limit]
+		last := orders[len(orders)-1]
+		value := last.CreatedAt
+		if sortBy == "total" {
+			value = strconv.Itoa(last.TotalPrice)
 		}
-		// products
-		// Iterasi melalui setiap id dalam slice productIDs
-		for _, productID := range productIDs {
-			var product Products
+		next = nextCursor(sortKey, value, last.ID)
+	}
 
-			// Jalankan query SQL untuk mengambil produk berdasarkan id
-			err := config.DB.QueryRow("SELECT id, sku, name, stock, price, image, created_at, updated_at FROM products WHERE id=?", productID).Scan(
-				&product.ID, &product.SKU, &product.Name, &product.Stock, &product.Price, &product.Image, &product.CreatedAt, &product.UpdatedAt,
-			)
+	payments, err := loadOrderPayments(paymentIDs)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal ambil data payments: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
 
-			if err != nil {
-				if err == sql.ErrNoRows {
-					errorMessage := fmt.Sprintf("Produk dengan ID %d tidak ditemukan", productID)
-					responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-					return
-				}
-				// Handle other database errors
-				responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+	items, err := loadOrderItems(orderIDs)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
 
-			// Tambahkan produk ke dalam slice products
-			products = append(products, product)
+	for i := range orders {
+		if orders[i].PaymentTypeID != nil {
+			orders[i].PaymentType = payments[*orders[i].PaymentTypeID]
 		}
-		// end order_products
-
-		// products
-		err = config.DB.QueryRow("SELECT id, sku, name, stock, price, image, created_at, updated_at FROM products WHERE id=?", &orderProducts.ProductID).Scan(
-			&product.ID, &product.SKU, &product.Name, &product.Stock, &product.Price, &product.Image, &product.CreatedAt, &product.UpdatedAt,
-		)
-
-		if err != nil {
-			if err == sql.ErrNoRows {
-				errorMessage := fmt.Sprintf("Produk dengan ID %d tidak ditemukan", orderProducts.ProductID)
-				responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-				return
-			}
-			// Handle other database errors
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-			return
+		orders[i].Items = items[orders[i].ID]
+		if orders[i].Items == nil {
+			orders[i].Items = []orderItem{}
 		}
-
-		// Tambahkan produk ke slice products
-		products = append(products, product)
 	}
-	// end products
-	total := len(orders)
 
 	response := map[string]interface{}{
 		"data": map[string]interface{}{
-			"meta": map[string]interface{}{
-				"total": total,
-				"limit": limit,
-				"skip":  skip,
-			},
-			"orders":   orders,
-			"products": products,
-			"payments": payments,
+			"meta":   page.meta(total, next),
+			"orders": orders,
 		},
 	}
 	w.Header().Set("Content-Type", "application/json")
 	responses.SuccessResponse(w, "Success", response, http.StatusOK)
 }
 
-func DetailOrders(w http.ResponseWriter, r *http.Request) {
-	type OrderProduct struct {
-		Id          *int64 `json:"id"`
-		Order_id    *int64 `json:"order_id"`
-		ProductID   *int64 `json:"product_id"`
-		Qty         *int64 `json:"qty"`
-		Total_price *int64 `json:"total_normal_price"`
-	}
-
-	type Products struct {
-		ID        *int64  `json:"id"`
-		SKU       *string `json:"sku"`
-		Name      *string `json:"name"`
-		Stock     *string `json:"stock"`
-		Price     *string `json:"price"`
-		Image     *string `json:"image"`
-		CreatedAt *string `json:"created_at"`
-		UpdatedAt *string `json:"updated_at"`
-	}
-	type Payments struct {
-		Id   int    `json:"payment_id"`
-		Name string `json:"name"`
-		Type string `json:"type"`
-		Logo string `json:"logo"`
-	}
-	type Orders struct {
-		ID              int     `json:"id"`
-		User_id         int     `json:"user_id"`
-		Payment_type_id int     `json:"payment_type_id"`
-		Total_price     int     `json:"total_price"`
-		Total_paid      int     `json:"total_paid"`
-		Total_return    int     `json:"total_return"`
-		CreatedAt       *string `json:"created_at"`
-		UpdatedAt       *string `json:"updated_at"`
-		Receipt_id      string  `json:"receipt_id"`
-	}
-	// get id param
-	vars := mux.Vars(r)
-	OrderID := vars["id"]
-
-	if OrderID == "" {
-		// Tangani jika ID produk tidak ada
-		responses.ErrorResponse(w, "ID orders harus diisi", http.StatusBadRequest)
+// exportOrders menulis daftar order sebagai CSV atau XLSX, satu baris per order. Baris dibaca dan
+// ditulis satu per satu sehingga ekspor besar tidak ditampung di memori.
+func exportOrders(w http.ResponseWriter, r *http.Request, format string, where string, args []interface{}, orderBy string, loc *time.Location) {
+	rows, err := config.DB.Query(`
+	    SELECT o.receipt_code, o.created_at, s.name, u.name, pm.name, o.customer_name, o.status,
+	           (SELECT COALESCE(SUM(op.qty), 0) FROM order_products op WHERE op.order_id = o.id),
+	           o.total_price, o.total_paid, o.total_return
+	    FROM orders o
+	    LEFT JOIN stores s ON s.id = o.store_id
+	    LEFT JOIN users u ON u.id = o.user_id
+	    LEFT JOIN payments pm ON pm.id = o.payment_id
+	    WHERE 1=1`+where+" ORDER BY "+orderBy, args...)
+	if err != nil {
+		responses.InternalError(w, err)
 		return
 	}
-	// get data orders from db
-	query := "SELECT id, user_id, payment_id, total_price, total_paid, total_return, receipt_code, created_at, updated_at FROM orders WHERE id = ?"
-	rows, err := config.DB.Query(query, OrderID)
-	if err != nil {
-		errorMessage := fmt.Sprintf("Gagal ambil data  orders: %v", err)
+	defer rows.Close()
 
-		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+	writer, err := newExportWriter(w, r, format, "orders", []string{
+		"Receipt", "Tanggal", "Store", "Kasir", "Payment", "Customer", "Status", "Qty", "Total", "Dibayar", "Kembalian",
+	}, loc)
+	if err != nil {
+		badRequest(w, err)
 		return
 	}
-	var orders []Orders
-	var products []Products
-	var payments Payments
 
 	for rows.Next() {
-		var order Orders
-		err := rows.Scan(&order.ID, &order.User_id, &order.Payment_type_id, &order.Total_price, &order.Total_paid, &order.Total_return, &order.Receipt_id,
-			&order.CreatedAt, &order.UpdatedAt)
+		var receipt, status string
+		var createdAt time.Time
+		var store, cashier, payment, customer *string
+		var qty float64
+		var totalPrice, totalPaid, totalReturn int64
+		err := rows.Scan(&receipt, &createdAt, &store, &cashier, &payment, &customer, &status, &qty, &totalPrice, &totalPaid, &totalReturn)
+		if err == nil {
+			err = writer.WriteRow(receipt, createdAt, store, cashier, payment, customer, status, roundQty(qty), totalPrice, totalPaid, totalReturn)
+		}
 		if err != nil {
-			errorMessage := fmt.Sprintf("Gagal masukkan data orders ke var: %v", err)
-			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			log.Printf("Ekspor orders terhenti: %v\n", err)
 			return
 		}
-		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Ekspor orders terhenti: %v\n", err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Ekspor orders terhenti: %v\n", err)
+	}
+}
 
-		// end orders
-		defer rows.Close()
-		// get data payment from id payment in orders
-		err = config.DB.QueryRow("SELECT id, name, type, logo FROM payments WHERE id=?", order.Payment_type_id).Scan(&payments.Id, &payments.Name, &payments.Type, &payments.Logo)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				errorMessage := fmt.Sprintf("Gagal ambil data payments: %v", err)
+// DetailOrders menampilkan satu order dengan bentuk yang sama seperti setiap order di ListOrders.
+// Produk setiap baris order ada di items, diambil dari snapshot saat penjualan.
+func DetailOrders(w http.ResponseWriter, r *http.Request) {
+	type Order struct {
+		ID            int64        `json:"id"`
+		StoreID       *int64       `json:"store_id"`
+		UserID        *int64       `json:"user_id"`
+		PaymentTypeID *int64       `json:"payment_type_id"`
+		TotalPrice    int          `json:"total_price"`
+		TotalPaid     int          `json:"total_paid"`
+		TotalReturn   int          `json:"total_return"`
+		ReceiptID     string       `json:"receipt_id"`
+		Status        string       `json:"status"`
+		Customer      *string      `json:"customer"`
+		PaymentType   orderPayment `json:"payment_type"`
+		Items         []orderItem  `json:"items"`
+		CreatedAt     string       `json:"created_at"`
+		UpdatedAt     string       `json:"updated_at"`
+	}
+
+	orderID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID order tidak valid", http.StatusBadRequest)
+		return
+	}
 
-				responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-				return
-			}
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+	var order Order
+	err = config.DB.QueryRow("SELECT id, store_id, user_id, payment_id, total_price, total_paid, total_return, receipt_code, status, customer_name, created_at, updated_at FROM orders WHERE id = ?", orderID).
+		Scan(&order.ID, &order.StoreID, &order.UserID, &order.PaymentTypeID, &order.TotalPrice, &order.TotalPaid, &order.TotalReturn, &order.ReceiptID,
+			&order.Status, &order.Customer, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Order tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		// end payments
-		var orderProducts OrderProduct
-
-		// get data products from id products in orders
-
-		for _, orderProduct := range orders {
-			err := config.DB.QueryRow("SELECT product_id FROM order_products WHERE order_id=?", orderProduct.ID).Scan(
-				&orderProducts.ProductID,
-			)
+		responses.InternalError(w, err)
+		return
+	}
 
-			if err != nil {
-				if err == sql.ErrNoRows {
-					errorMessage := fmt.Sprintf("order_products dengan ID %d tidak ditemukan", orderProduct.ID)
-					responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-					return
-				}
-			}
-		}
-		// get data products from id products in orders
-		var productIDs []int
+	if !canAccessStoreOf(r, order.StoreID) {
+		responses.ErrorResponse(w, "Order ini milik store lain", http.StatusForbidden)
+		return
+	}
 
-		// Mengambil data dari tabel order_products berdasarkan order_id
-		rows, err := config.DB.Query("SELECT product_id FROM order_products WHERE order_id=?", order.ID)
+	// pembayaran dan baris order diambil dengan helper yang sama seperti ListOrders
+	if order.PaymentTypeID != nil {
+		payments, err := loadOrderPayments([]int64{*order.PaymentTypeID})
 		if err != nil {
-			// Handle database errors
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			responses.InternalError(w, err)
 			return
 		}
-		defer rows.Close()
-
-		// Iterasi melalui hasil query order_products dan tambahkan product_id ke dalam slice productIDs
-		for rows.Next() {
-			var productID int
-			err := rows.Scan(&productID)
-			if err != nil {
-				responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-			productIDs = append(productIDs, productID)
-		}
-		// products
-		// Iterasi melalui setiap id dalam slice productIDs
-		for _, productID := range productIDs {
-			var product Products
-
-			// Jalankan query SQL untuk mengambil produk berdasarkan id
-			err := config.DB.QueryRow("SELECT id, sku, name, stock, price, image, created_at, updated_at FROM products WHERE id=?", productID).Scan(
-				&product.ID, &product.SKU, &product.Name, &product.Stock, &product.Price, &product.Image, &product.CreatedAt, &product.UpdatedAt,
-			)
-
-			if err != nil {
-				if err == sql.ErrNoRows {
-					errorMessage := fmt.Sprintf("Produk dengan ID %d tidak ditemukan", productID)
-					responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
-					return
-				}
-				// Handle other database errors
-				responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+		order.PaymentType = payments[*order.PaymentTypeID]
+	}
 
-			// Tambahkan produk ke dalam slice products
-			products = append(products, product)
-		}
-
-		// buat respons
-		type Response struct {
-			ID            int64      `json:"id"`
-			UserID        int        `json:"user_id"`
-			PaymentTypeID int        `json:"payment_type_id"`
-			TotalPrice    int        `json:"total_price"`
-			TotalPaid     int        `json:"total_paid"`
-			TotalReturn   int        `json:"total_return"`
-			ReceiptID     string     `json:"receipt_id"`
-			Products      []Products `json:"products"`
-			PaymentType   Payments   `json:"payment_type"`
-			UpdatedAt     string     `json:"updated_at"`
-			CreatedAt     string     `json:"created_at"`
-		}
-		responseData := Response{
-			ID:            int64(order.ID),
-			UserID:        order.User_id,
-			PaymentTypeID: order.Payment_type_id,
-			TotalPrice:    order.Total_price,
-			TotalPaid:     order.Total_paid,
-			TotalReturn:   order.Total_return,
-			ReceiptID:     order.Receipt_id,
-			Products:      products,
-			PaymentType:   payments,
-			UpdatedAt:     *order.UpdatedAt,
-			CreatedAt:     *order.CreatedAt,
-		}
-		// Mengembalikan data produk sebagai JSON
-		w.Header().Set("Content-Type", "application/json")
-		responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+	items, err := loadOrderItems([]int64{order.ID})
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	order.Items = items[order.ID]
+	if order.Items == nil {
+		order.Items = []orderItem{}
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", order, http.StatusOK)
 }
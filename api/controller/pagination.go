@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultPageLimit dipakai jika parameter limit tidak diisi
+	defaultPageLimit = 20
+	// maxPageLimit adalah batas jumlah baris per halaman, limit yang lebih besar dipotong
+	maxPageLimit = 100
+)
+
+// pagination adalah parameter paginasi yang sama untuk semua endpoint daftar. Paginasi bisa
+// memakai skip (offset) atau cursor (keyset); cursor lebih stabil dan cepat untuk tabel besar.
+type pagination struct {
+	Limit  int
+	Skip   int
+	Cursor *pageCursor
+}
+
+// pageCursor menunjuk baris terakhir halaman sebelumnya. Sort adalah urutan yang dipakai saat
+// cursor dibuat dan Value adalah nilai kolom urutan pada baris tersebut.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+// parsePagination membaca limit, skip dan cursor dari query string.
+func parsePagination(r *http.Request) (pagination, error) {
+	query := r.URL.Query()
+	page := pagination{Limit: defaultPageLimit}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return page, invalidField("limit", "Invalid 'limit' parameter")
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		page.Limit = limit
+	}
+
+	if skipStr := query.Get("skip"); skipStr != "" {
+		skip, err := strconv.Atoi(skipStr)
+		if err != nil || skip < 0 {
+			return page, invalidField("skip", "Invalid 'skip' parameter")
+		}
+		page.Skip = skip
+	}
+
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		if page.Skip > 0 {
+			return page, invalidField("cursor", "Parameter 'cursor' dan 'skip' tidak dapat dipakai bersamaan")
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(cursorStr)
+		if err != nil {
+			return page, invalidField("cursor", "Invalid 'cursor' parameter")
+		}
+		var cursor pageCursor
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return page, invalidField("cursor", "Invalid 'cursor' parameter")
+		}
+		page.Cursor = &cursor
+	}
+
+	return page, nil
+}
+
+// keyset membuat filter SQL untuk baris setelah cursor pada urutan (column, idColumn). Jika
+// urutannya hanya berdasarkan ID, column sama dengan idColumn dan parse boleh nil. parse
+// mengubah nilai cursor menjadi argumen query yang sesuai tipe kolomnya.
+func (p pagination) keyset(sort string, column string, idColumn string, desc bool, parse func(string) (interface{}, error)) (string, []interface{}, error) {
+	if p.Cursor == nil {
+		return "", nil, nil
+	}
+	if p.Cursor.Sort != sort {
+		return "", nil, invalidField("cursor", "Cursor tidak cocok dengan urutan yang dipilih")
+	}
+
+	operator := ">"
+	if desc {
+		operator = "<"
+	}
+	if column == idColumn {
+		return " AND " + idColumn + " " + operator + " ?", []interface{}{p.Cursor.ID}, nil
+	}
+
+	value, err := parse(p.Cursor.Value)
+	if err != nil {
+		return "", nil, invalidField("cursor", "Invalid 'cursor' parameter")
+	}
+	filter := " AND (" + column + " " + operator + " ? OR (" + column + " = ? AND " + idColumn + " " + operator + " ?))"
+	return filter, []interface{}{value, value, p.Cursor.ID}, nil
+}
+
+// limitSQL mengembalikan klausa LIMIT/OFFSET. Satu baris tambahan diambil untuk mengetahui
+// apakah masih ada halaman berikutnya; baris tersebut dibuang dengan hasMore.
+func (p pagination) limitSQL() (string, []interface{}) {
+	return " LIMIT ? OFFSET ?", []interface{}{p.Limit + 1, p.Skip}
+}
+
+// hasMore memeriksa apakah jumlah baris yang diambil melebihi limit.
+func (p pagination) hasMore(count int) bool {
+	return count > p.Limit
+}
+
+// nextCursor membuat cursor untuk halaman berikutnya dari baris terakhir halaman ini.
+func nextCursor(sort string, value string, id int64) *string {
+	raw, _ := json.Marshal(pageCursor{Sort: sort, Value: value, ID: id})
+	cursor := base64.RawURLEncoding.EncodeToString(raw)
+	return &cursor
+}
+
+// meta membuat objek meta yang sama untuk semua endpoint daftar. total adalah jumlah seluruh
+// baris yang cocok dengan filter, next_cursor bernilai null di halaman terakhir.
+func (p pagination) meta(total int, next *string) map[string]interface{} {
+	return map[string]interface{}{
+		"total":       total,
+		"limit":       p.Limit,
+		"skip":        p.Skip,
+		"next_cursor": next,
+	}
+}
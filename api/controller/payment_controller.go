@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,8 +23,8 @@ import (
 
 func CreatePayment(w http.ResponseWriter, r *http.Request) {
 	var payment struct {
-		Name string `form:"name"`
-		Type string `form:"type"`
+		Name string `form:"name" validate:"required,max=255"`
+		Type string `form:"type" validate:"required,max=255"`
 	}
 
 	// Mengambil nilai form-data dan mengisinya ke struct product
@@ -34,8 +33,7 @@ func CreatePayment(w http.ResponseWriter, r *http.Request) {
 	payment.Type = r.FormValue("type")
 
 	// Validasi nilai form-data tidak boleh kosong
-	if payment.Name == "" || payment.Type == "" {
-		responses.ErrorResponse(w, "Semua kolom harus diisi", http.StatusBadRequest)
+	if !validateRequest(w, &payment) {
 		return
 	}
 
@@ -165,32 +163,30 @@ func ListPayments(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	skipStr := r.URL.Query().Get("skip")
 	categoryIDStr := r.URL.Query().Get("categoryId")
 	searchQuery := r.URL.Query().Get("q")
 
-	// Convert limit and skip parameters to integers
-	limit, err := strconv.Atoi(limitStr)
+	page, err := parsePagination(r)
 	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+		badRequest(w, err)
 		return
 	}
 
-	skip, err := strconv.Atoi(skipStr)
-	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'skip' parameter", http.StatusBadRequest)
-		return
-	}
-
-	query := "SELECT id, name, type, logo FROM payments WHERE 1=1"
+	query := ""
 	args := []interface{}{} // Slice to store query parameters
 
+	// deleted=true menampilkan payment yang sudah dihapus agar dapat dipulihkan
+	if r.URL.Query().Get("deleted") == "true" {
+		query += " AND deleted_at IS NOT NULL"
+	} else {
+		query += " AND deleted_at IS NULL"
+	}
+
 	if categoryIDStr != "" {
 		query += " AND (category_id = ? OR category_id IS NULL)"
 		categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
 		if err != nil {
-			responses.ErrorResponse(w, "Invalid 'categoryId' parameter", http.StatusBadRequest)
+			responses.FieldErrorResponse(w, "categoryId", "Invalid 'categoryId' parameter")
 			return
 		}
 		args = append(args, categoryID)
@@ -201,12 +197,24 @@ func ListPayments(w http.ResponseWriter, r *http.Request) {
 		args = append(args, "%"+searchQuery+"%")
 	}
 
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, limit, skip)
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM payments WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", false, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
 
-	rows, err := config.DB.Query(query, args...)
+	rows, err := config.DB.Query("SELECT id, name, type, logo FROM payments WHERE 1=1"+query+keyset+" ORDER BY id"+limitSQL, args...)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 	defer rows.Close()
@@ -217,22 +225,22 @@ func ListPayments(w http.ResponseWriter, r *http.Request) {
 		var payment Payment
 		err := rows.Scan(&payment.ID, &payment.Name, &payment.Type, &payment.Logo)
 		if err != nil {
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			responses.InternalError(w, err)
 			return
 		}
 		payments = append(payments, payment)
 	}
 
-	total := len(payments)
+	var next *string
+	if page.hasMore(len(payments)) {
+		payments = payments[:page.Limit]
+		next = nextCursor("id", "", payments[len(payments)-1].ID)
+	}
 
 	// Prepare response
 	response := map[string]interface{}{
 		"data": map[string]interface{}{
-			"meta": map[string]interface{}{
-				"total": total,
-				"limit": limit,
-				"skip":  skip,
-			},
+			"meta":     page.meta(total, next),
 			"payments": payments,
 		},
 	}
@@ -260,15 +268,13 @@ func DetailPayments(w http.ResponseWriter, r *http.Request) {
 	var payment Payment
 
 	// Menggunakan prepared statement untuk menghindari SQL Injection
-	err := config.DB.QueryRow("SELECT id, name, type, logo FROM payments WHERE id=?", PaymentId).Scan(&payment.ID, &payment.Name, &payment.Type, &payment.Logo)
+	err := config.DB.QueryRow("SELECT id, name, type, logo FROM payments WHERE id=? AND deleted_at IS NULL", PaymentId).Scan(&payment.ID, &payment.Name, &payment.Type, &payment.Logo)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			errorMessage := fmt.Sprintf("payment tidak ditemukan: %v", err)
-
-			responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
+			responses.ErrorResponse(w, "Payment tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -284,27 +290,25 @@ func UpdatePayments(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var updatedPayment struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
+		Name string `json:"name" validate:"required,max=255"`
+		Type string `json:"type" validate:"required,max=255"`
 		Logo string `json:"logo"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&updatedPayment); err != nil {
-		errorMessage := fmt.Sprintf("Gagal membaca data payment dari permintaan: %v", err)
-		responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+	if !decodeRequest(w, r, &updatedPayment) {
 		return
 	}
 
-	stmt, err := config.DB.Prepare("UPDATE payments SET name=?, type=?, logo=?, updated_at=NOW() WHERE id=?")
+	stmt, err := config.DB.Prepare("UPDATE payments SET name=?, type=?, logo=?, updated_at=NOW() WHERE id=? AND deleted_at IS NULL")
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 	defer stmt.Close()
 
 	_, err = stmt.Exec(updatedPayment.Name, updatedPayment.Type, updatedPayment.Logo, paymentID)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -328,13 +332,23 @@ func DeletePayments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Menghapus category dari database
-	_, err := config.DB.Exec("DELETE FROM payments WHERE id=?", PaymentId)
+	paymentID, err := strconv.ParseInt(PaymentId, 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID payment tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	// Payment hanya ditandai terhapus karena masih direferensikan oleh order
+	deleted, err := softDeleteRow(config.DB, "payments", paymentID)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 
 		return
 	}
+	if !deleted {
+		responses.ErrorResponse(w, "Payment tidak ditemukan", http.StatusNotFound)
+		return
+	}
 
 	responses.OtherResponses(w, "Success", http.StatusCreated)
 }
@@ -0,0 +1,852 @@
+package controller
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"golang-api/api/middleware"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sumber perubahan harga yang dicatat di price_histories
+const (
+	priceSourceInitial     = "initial"
+	priceSourceCreate      = "create"
+	priceSourceManual      = "manual"
+	priceSourceImport      = "import"
+	priceSourceStore       = "store"
+	priceSourcePriceChange = "price_change"
+)
+
+// status perubahan harga massal
+const (
+	priceChangeScheduled = "scheduled"
+	priceChangeApplied   = "applied"
+	priceChangeCancelled = "cancelled"
+)
+
+// jenis penyesuaian harga massal
+const (
+	adjustPercent = "percent"
+	adjustAmount  = "amount"
+	adjustSet     = "set"
+)
+
+const (
+	// priceChangeInterval adalah jeda pemeriksaan perubahan harga terjadwal yang sudah jatuh tempo
+	priceChangeInterval = time.Minute
+)
+
+// errPriceChangeNotScheduled dikembalikan jika perubahan harga sudah diterapkan atau dibatalkan.
+var errPriceChangeNotScheduled = errors.New("Perubahan harga sudah diterapkan atau dibatalkan")
+
+// priceHistoryEntry adalah satu perubahan harga produk. StoreID nil berarti harga dasar di
+// products, selain itu harga khusus store; Price nil berarti harga khusus store dihapus.
+type priceHistoryEntry struct {
+	ProductID     int64
+	StoreID       *int64
+	OldPrice      *int
+	Price         *int
+	Source        string
+	PriceChangeID *int64
+	UserID        *int
+}
+
+// recordPriceHistory mencatat perubahan harga di dalam transaksi. Harga yang tidak berubah
+// tidak dicatat.
+func recordPriceHistory(tx *sql.Tx, entry priceHistoryEntry) error {
+	if entry.OldPrice == nil && entry.Price == nil {
+		return nil
+	}
+	if entry.OldPrice != nil && entry.Price != nil && *entry.OldPrice == *entry.Price {
+		return nil
+	}
+
+	_, err := tx.Exec("INSERT INTO price_histories (product_id, store_id, old_price, price, source, price_change_id, user_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, NOW())",
+		entry.ProductID, entry.StoreID, entry.OldPrice, entry.Price, entry.Source, entry.PriceChangeID, entry.UserID)
+	return err
+}
+
+// lockProductPrice mengambil harga dasar produk dan mengunci barisnya sampai transaksi selesai.
+func lockProductPrice(tx *sql.Tx, productID int64) (int, error) {
+	var price int
+	err := tx.QueryRow("SELECT CAST(price AS SIGNED) FROM products WHERE id = ? FOR UPDATE", productID).Scan(&price)
+	return price, err
+}
+
+// currentUserID mengembalikan ID pengguna yang sedang login, atau nil.
+func currentUserID(r *http.Request) *int {
+	if claims := middleware.CurrentUser(r); claims != nil {
+		return &claims.UserId
+	}
+	return nil
+}
+
+// parseEffectiveAt membaca waktu berlaku perubahan harga. Tanggal saja berarti tengah malam
+// awal hari tersebut. Format tanpa zona waktu dibaca dengan zona waktu loc.
+func parseEffectiveAt(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalidField("effective_at", "Invalid 'effective_at', gunakan format 2006-01-02, 2006-01-02 15:04 atau RFC3339")
+}
+
+// adjustPrice menghitung harga baru dari penyesuaian massal. roundTo lebih dari 0 membulatkan
+// hasilnya ke kelipatan terdekat, misalnya 100 atau 500.
+func adjustPrice(price int, adjustment string, value float64, roundTo int) int {
+	newPrice := float64(price)
+	switch adjustment {
+	case adjustPercent:
+		newPrice = newPrice * (1 + value/100)
+	case adjustAmount:
+		newPrice = newPrice + value
+	case adjustSet:
+		newPrice = value
+	}
+	if roundTo > 0 {
+		return int(math.Round(newPrice/float64(roundTo))) * roundTo
+	}
+	return int(math.Round(newPrice))
+}
+
+// priceChangeItem adalah harga lama dan harga baru satu produk dalam perubahan harga massal.
+type priceChangeItem struct {
+	ProductID  int64  `json:"product_id"`
+	SKU        string `json:"sku"`
+	Name       string `json:"name"`
+	OldPrice   int    `json:"old_price"`
+	NewPrice   int    `json:"new_price"`
+	Difference int    `json:"difference"`
+}
+
+// BulkUpdatePrices mengubah harga dasar banyak produk sekaligus. Produk dipilih dengan filter
+// (category_id, supplier_id, tag, product_ids) lalu disesuaikan dengan adjustment: percent
+// (misalnya 5 untuk naik 5%), amount (selisih rupiah) atau set (harga tetap). Daftar harga juga
+// bisa dikirim langsung lewat items. Dengan preview=true hanya hasil perhitungannya yang
+// dikembalikan. effective_at di masa depan menjadwalkan perubahan yang diterapkan otomatis
+// saat waktunya tiba; tanpa effective_at harga langsung berubah.
+func BulkUpdatePrices(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name   string `json:"name" validate:"max=255"`
+		Filter *struct {
+			CategoryID *int64  `json:"category_id" validate:"exists=categories"`
+			SupplierID *int64  `json:"supplier_id" validate:"exists=suppliers"`
+			Tag        string  `json:"tag" validate:"max=50"`
+			ProductIDs []int64 `json:"product_ids"`
+		} `json:"filter"`
+		Adjustment *struct {
+			Type    string  `json:"type" validate:"required,oneof=percent amount set"`
+			Value   float64 `json:"value"`
+			RoundTo int     `json:"round_to" validate:"min=0"`
+		} `json:"adjustment"`
+		Items []struct {
+			ProductID int64 `json:"product_id" validate:"required,exists=products"`
+			Price     int   `json:"price" validate:"min=0"`
+		} `json:"items"`
+		EffectiveAt string `json:"effective_at"`
+		Preview     bool   `json:"preview"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+	preview := request.Preview || r.URL.Query().Get("preview") == "true"
+
+	loc, err := storeLocation(nil)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	effectiveAt := time.Now()
+	if request.EffectiveAt != "" {
+		effectiveAt, err = parseEffectiveAt(request.EffectiveAt, loc)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+	}
+
+	items := []priceChangeItem{}
+
+	switch {
+	case request.Filter != nil && len(request.Items) > 0:
+		responses.ErrorResponse(w, "Gunakan filter atau items, tidak keduanya", http.StatusBadRequest)
+		return
+
+	case request.Filter != nil:
+		if request.Adjustment == nil {
+			responses.FieldErrorResponse(w, "adjustment", "adjustment harus diisi saat memakai filter")
+			return
+		}
+		adjustment := request.Adjustment
+
+		query := ""
+		args := []interface{}{}
+		filter := request.Filter
+		if filter.CategoryID != nil {
+			// kategori induk ikut mengubah harga produk di semua subkategorinya
+			nodes, _, err := loadCategoryTree(config.DB)
+			if err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+			placeholders, categoryArgs := int64Args(categoryDescendants(nodes, *filter.CategoryID))
+			query += " AND p.category_id IN (" + placeholders + ")"
+			args = append(args, categoryArgs...)
+		}
+		if filter.SupplierID != nil {
+			query += " AND p.id IN (SELECT poi.product_id FROM purchase_order_items poi JOIN purchase_orders po ON poi.purchase_order_id = po.id WHERE po.supplier_id = ?)"
+			args = append(args, *filter.SupplierID)
+		}
+		if filter.Tag != "" {
+			query += " AND p.id IN (SELECT product_id FROM product_tags WHERE tag = ?)"
+			args = append(args, strings.ToLower(strings.TrimSpace(filter.Tag)))
+		}
+		if len(filter.ProductIDs) > 0 {
+			query += " AND p.id IN (?" + strings.Repeat(", ?", len(filter.ProductIDs)-1) + ")"
+			for _, id := range filter.ProductIDs {
+				args = append(args, id)
+			}
+		}
+		// tanpa filter semua produk akan berubah, sehingga minimal satu filter harus diisi
+		if query == "" {
+			responses.ErrorResponse(w, "Minimal satu filter harus diisi: category_id, supplier_id, tag atau product_ids", http.StatusBadRequest)
+			return
+		}
+
+		rows, err := config.DB.Query("SELECT p.id, p.sku, p.name, CAST(p.price AS SIGNED) FROM products p WHERE p.deleted_at IS NULL"+query+" ORDER BY p.id", args...)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var item priceChangeItem
+			if err := rows.Scan(&item.ProductID, &item.SKU, &item.Name, &item.OldPrice); err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+			item.NewPrice = adjustPrice(item.OldPrice, adjustment.Type, adjustment.Value, adjustment.RoundTo)
+			if item.NewPrice < 0 {
+				responses.ErrorResponse(w, "Harga baru produk "+item.SKU+" menjadi negatif", http.StatusBadRequest)
+				return
+			}
+			items = append(items, item)
+		}
+		if err := rows.Err(); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+	case len(request.Items) > 0:
+		seen := map[int64]bool{}
+		for _, requested := range request.Items {
+			if seen[requested.ProductID] {
+				responses.ErrorResponse(w, fmt.Sprintf("Produk %d muncul lebih dari satu kali", requested.ProductID), http.StatusBadRequest)
+				return
+			}
+			seen[requested.ProductID] = true
+
+			item := priceChangeItem{ProductID: requested.ProductID, NewPrice: requested.Price}
+			err := config.DB.QueryRow("SELECT sku, name, CAST(price AS SIGNED) FROM products WHERE id = ? AND deleted_at IS NULL", requested.ProductID).Scan(&item.SKU, &item.Name, &item.OldPrice)
+			if err != nil {
+				if err == sql.ErrNoRows {
+					responses.ErrorResponse(w, fmt.Sprintf("Produk %d tidak ditemukan", requested.ProductID), http.StatusNotFound)
+					return
+				}
+				responses.InternalError(w, err)
+				return
+			}
+			items = append(items, item)
+		}
+
+	default:
+		responses.FieldErrorResponse(w, "filter", "filter atau items harus diisi")
+		return
+	}
+
+	// produk yang harganya tidak berubah tidak ikut disimpan
+	changed := []priceChangeItem{}
+	for _, item := range items {
+		item.Difference = item.NewPrice - item.OldPrice
+		if item.Difference != 0 {
+			changed = append(changed, item)
+		}
+	}
+
+	responseData := map[string]interface{}{
+		"preview":      preview,
+		"effective_at": effectiveAt,
+		"matched":      len(items),
+		"changed":      len(changed),
+		"items":        changed,
+	}
+
+	if preview {
+		responses.SuccessResponse(w, "Pratinjau perubahan harga, tidak ada data yang disimpan", responseData, http.StatusOK)
+		return
+	}
+	if len(changed) == 0 {
+		responses.ErrorResponse(w, "Tidak ada harga produk yang berubah", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = "Perubahan harga " + effectiveAt.In(loc).Format("2006-01-02 15:04")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	currentTime := time.Now()
+	result, err := tx.Exec("INSERT INTO price_changes (name, status, effective_at, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		name, priceChangeScheduled, effectiveAt, currentUserID(r), currentTime, currentTime)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	priceChangeID, err := result.LastInsertId()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	for _, item := range changed {
+		_, err = tx.Exec("INSERT INTO price_change_items (price_change_id, product_id, old_price, new_price) VALUES (?, ?, ?, ?)",
+			priceChangeID, item.ProductID, item.OldPrice, item.NewPrice)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	status := priceChangeScheduled
+	if !effectiveAt.After(currentTime) {
+		if err := applyPriceChange(tx, priceChangeID, currentUserID(r)); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		status = priceChangeApplied
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData["id"] = priceChangeID
+	responseData["name"] = name
+	responseData["status"] = status
+	responses.SuccessResponse(w, "Success", responseData, http.StatusCreated)
+}
+
+// applyPriceChange menerapkan harga baru dari perubahan harga massal di dalam transaksi. Produk
+// yang sudah dihapus dilewati.
+func applyPriceChange(tx *sql.Tx, priceChangeID int64, userID *int) error {
+	var status string
+	err := tx.QueryRow("SELECT status FROM price_changes WHERE id = ? FOR UPDATE", priceChangeID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != priceChangeScheduled {
+		return errPriceChangeNotScheduled
+	}
+
+	type item struct {
+		ProductID int64
+		NewPrice  int
+	}
+	rows, err := tx.Query("SELECT product_id, new_price FROM price_change_items WHERE price_change_id = ? ORDER BY product_id", priceChangeID)
+	if err != nil {
+		return err
+	}
+	var items []item
+	for rows.Next() {
+		var it item
+		if err := rows.Scan(&it.ProductID, &it.NewPrice); err != nil {
+			rows.Close()
+			return err
+		}
+		items = append(items, it)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, it := range items {
+		var oldPrice int
+		err := tx.QueryRow("SELECT CAST(price AS SIGNED) FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", it.ProductID).Scan(&oldPrice)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// price_updated_at diisi sebelum price agar masih membandingkan dengan harga lama
+		_, err = tx.Exec("UPDATE products SET price_updated_at = IF(price = ?, price_updated_at, NOW()), price = ?, updated_at = NOW() WHERE id = ?",
+			strconv.Itoa(it.NewPrice), it.NewPrice, it.ProductID)
+		if err != nil {
+			return err
+		}
+
+		newPrice := it.NewPrice
+		err = recordPriceHistory(tx, priceHistoryEntry{
+			ProductID:     it.ProductID,
+			OldPrice:      &oldPrice,
+			Price:         &newPrice,
+			Source:        priceSourcePriceChange,
+			PriceChangeID: &priceChangeID,
+			UserID:        userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec("UPDATE price_changes SET status = ?, applied_at = NOW(), updated_at = NOW() WHERE id = ?", priceChangeApplied, priceChangeID)
+	return err
+}
+
+// applyDuePriceChanges menerapkan semua perubahan harga terjadwal yang waktunya sudah tiba,
+// masing-masing dalam transaksi sendiri.
+func applyDuePriceChanges() (int, error) {
+	rows, err := config.DB.Query("SELECT id FROM price_changes WHERE status = ? AND effective_at <= ? ORDER BY effective_at, id", priceChangeScheduled, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, id := range ids {
+		tx, err := config.DB.Begin()
+		if err != nil {
+			return applied, err
+		}
+		err = applyPriceChange(tx, id, nil)
+		if err == nil {
+			err = tx.Commit()
+		}
+		tx.Rollback()
+
+		// perubahan yang dibatalkan di antara query dan penerapan dilewati saja
+		if err == errPriceChangeNotScheduled {
+			continue
+		}
+		if err != nil {
+			log.Printf("Gagal menerapkan perubahan harga %d: %v\n", id, err)
+			continue
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// StartPriceChangeWorker menjalankan job latar belakang yang setiap menit menerapkan perubahan
+// harga terjadwal yang sudah jatuh tempo. Perubahan yang terlewat saat server mati diterapkan
+// saat server dijalankan.
+func StartPriceChangeWorker() {
+	go func() {
+		ticker := time.NewTicker(priceChangeInterval)
+		defer ticker.Stop()
+		for {
+			applied, err := applyDuePriceChanges()
+			if err != nil {
+				log.Printf("Gagal menjalankan perubahan harga terjadwal: %v\n", err)
+			} else if applied > 0 {
+				log.Printf("%d perubahan harga terjadwal diterapkan\n", applied)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ListPriceChanges menampilkan perubahan harga massal terbaru lebih dulu. Parameter status
+// menyaring scheduled, applied atau cancelled.
+func ListPriceChanges(w http.ResponseWriter, r *http.Request) {
+	type PriceChange struct {
+		ID          int64      `json:"id"`
+		Name        string     `json:"name"`
+		Status      string     `json:"status"`
+		EffectiveAt time.Time  `json:"effective_at"`
+		AppliedAt   *time.Time `json:"applied_at"`
+		ItemCount   int        `json:"item_count"`
+		CreatedAt   time.Time  `json:"created_at"`
+	}
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		if status != priceChangeScheduled && status != priceChangeApplied && status != priceChangeCancelled {
+			responses.FieldErrorResponse(w, "status", "Invalid 'status' parameter, gunakan scheduled, applied atau cancelled")
+			return
+		}
+		query += " AND pc.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM price_changes pc WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "pc.id", "pc.id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query(`
+	    SELECT pc.id, pc.name, pc.status, pc.effective_at, pc.applied_at, pc.created_at,
+	           (SELECT COUNT(*) FROM price_change_items pci WHERE pci.price_change_id = pc.id)
+	    FROM price_changes pc WHERE 1=1`+query+keyset+" ORDER BY pc.id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	priceChanges := []PriceChange{}
+	for rows.Next() {
+		var priceChange PriceChange
+		err := rows.Scan(&priceChange.ID, &priceChange.Name, &priceChange.Status, &priceChange.EffectiveAt, &priceChange.AppliedAt, &priceChange.CreatedAt, &priceChange.ItemCount)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		priceChanges = append(priceChanges, priceChange)
+	}
+
+	var next *string
+	if page.hasMore(len(priceChanges)) {
+		priceChanges = priceChanges[:page.Limit]
+		next = nextCursor("id", "", priceChanges[len(priceChanges)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"meta":          page.meta(total, next),
+		"price_changes": priceChanges,
+	}
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// DetailPriceChange menampilkan perubahan harga massal beserta harga lama dan baru tiap produk.
+func DetailPriceChange(w http.ResponseWriter, r *http.Request) {
+	priceChangeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID perubahan harga tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var priceChange struct {
+		ID          int64             `json:"id"`
+		Name        string            `json:"name"`
+		Status      string            `json:"status"`
+		EffectiveAt time.Time         `json:"effective_at"`
+		AppliedAt   *time.Time        `json:"applied_at"`
+		UserID      *int              `json:"user_id"`
+		CreatedAt   time.Time         `json:"created_at"`
+		Items       []priceChangeItem `json:"items"`
+	}
+	err = config.DB.QueryRow("SELECT id, name, status, effective_at, applied_at, user_id, created_at FROM price_changes WHERE id = ?", priceChangeID).
+		Scan(&priceChange.ID, &priceChange.Name, &priceChange.Status, &priceChange.EffectiveAt, &priceChange.AppliedAt, &priceChange.UserID, &priceChange.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Perubahan harga tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT pci.product_id, p.sku, p.name, pci.old_price, pci.new_price
+	    FROM price_change_items pci JOIN products p ON pci.product_id = p.id
+	    WHERE pci.price_change_id = ? ORDER BY pci.product_id`, priceChangeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	priceChange.Items = []priceChangeItem{}
+	for rows.Next() {
+		var item priceChangeItem
+		if err := rows.Scan(&item.ProductID, &item.SKU, &item.Name, &item.OldPrice, &item.NewPrice); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		item.Difference = item.NewPrice - item.OldPrice
+		priceChange.Items = append(priceChange.Items, item)
+	}
+
+	responses.SuccessResponse(w, "Success", priceChange, http.StatusOK)
+}
+
+// CancelPriceChange membatalkan perubahan harga terjadwal yang belum diterapkan.
+func CancelPriceChange(w http.ResponseWriter, r *http.Request) {
+	priceChangeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID perubahan harga tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	result, err := config.DB.Exec("UPDATE price_changes SET status = ?, updated_at = NOW() WHERE id = ? AND status = ?", priceChangeCancelled, priceChangeID, priceChangeScheduled)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		var count int
+		config.DB.QueryRow("SELECT COUNT(*) FROM price_changes WHERE id = ?", priceChangeID).Scan(&count)
+		if count == 0 {
+			responses.ErrorResponse(w, "Perubahan harga tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.ErrorResponse(w, errPriceChangeNotScheduled.Error(), http.StatusConflict)
+		return
+	}
+
+	responses.SuccessResponse(w, "Perubahan harga dibatalkan", map[string]interface{}{"id": priceChangeID, "status": priceChangeCancelled}, http.StatusOK)
+}
+
+// ProductPriceHistory menampilkan riwayat harga produk, terbaru lebih dulu. Parameter at
+// (tanggal atau RFC3339) mengembalikan harga yang berlaku pada waktu tersebut; tanggal saja
+// berarti harga di akhir hari itu. Dengan store, harga khusus store ikut diperhitungkan.
+func ProductPriceHistory(w http.ResponseWriter, r *http.Request) {
+	type PriceHistory struct {
+		ID            int64     `json:"id"`
+		StoreID       *int64    `json:"store_id"`
+		OldPrice      *int      `json:"old_price"`
+		Price         *int      `json:"price"`
+		Source        string    `json:"source"`
+		PriceChangeID *int64    `json:"price_change_id"`
+		UserID        *int      `json:"user_id"`
+		CreatedAt     time.Time `json:"created_at"`
+	}
+
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	loc, err := storeLocation(storeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	var count int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ?", productID).Scan(&count)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if count == 0 {
+		responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	at := time.Now()
+	if atStr := r.URL.Query().Get("at"); atStr != "" {
+		if day, err := time.ParseInLocation("2006-01-02", atStr, loc); err == nil {
+			at = day.AddDate(0, 0, 1).Add(-time.Second)
+		} else if at, err = time.Parse(time.RFC3339, atStr); err != nil {
+			responses.FieldErrorResponse(w, "at", "Invalid 'at' parameter, gunakan format 2006-01-02 atau RFC3339")
+			return
+		}
+	}
+
+	// harga dasar yang berlaku, lalu harga khusus store jika ada
+	var price *int
+	err = config.DB.QueryRow("SELECT price FROM price_histories WHERE product_id = ? AND store_id IS NULL AND created_at <= ? ORDER BY created_at DESC, id DESC LIMIT 1", productID, at).Scan(&price)
+	if err != nil && err != sql.ErrNoRows {
+		responses.InternalError(w, err)
+		return
+	}
+	if storeID != nil {
+		var storePrice *int
+		err = config.DB.QueryRow("SELECT price FROM price_histories WHERE product_id = ? AND store_id = ? AND created_at <= ? ORDER BY created_at DESC, id DESC LIMIT 1", productID, *storeID, at).Scan(&storePrice)
+		if err != nil && err != sql.ErrNoRows {
+			responses.InternalError(w, err)
+			return
+		}
+		if storePrice != nil {
+			price = storePrice
+		}
+	}
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := " AND store_id IS NULL"
+	args := []interface{}{productID}
+	if storeID != nil {
+		query = " AND (store_id IS NULL OR store_id = ?)"
+		args = append(args, *storeID)
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM price_histories WHERE product_id = ?"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query("SELECT id, store_id, old_price, price, source, price_change_id, user_id, created_at FROM price_histories WHERE product_id = ?"+query+keyset+" ORDER BY id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	history := []PriceHistory{}
+	for rows.Next() {
+		var entry PriceHistory
+		err := rows.Scan(&entry.ID, &entry.StoreID, &entry.OldPrice, &entry.Price, &entry.Source, &entry.PriceChangeID, &entry.UserID, &entry.CreatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		history = append(history, entry)
+	}
+
+	var next *string
+	if page.hasMore(len(history)) {
+		history = history[:page.Limit]
+		next = nextCursor("id", "", history[len(history)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"product_id": productID,
+		"store_id":   storeID,
+		"at":         at,
+		"price":      price,
+		"meta":       page.meta(total, next),
+		"history":    history,
+	}
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// SetProductTags mengganti seluruh tag produk. Tag disimpan dalam huruf kecil dan dipakai
+// sebagai filter perubahan harga massal.
+func SetProductTags(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Tags []string `json:"tags" validate:"dive,max=50"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, tag := range request.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("SELECT id FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE", productID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_tags WHERE product_id = ?", productID); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO product_tags (product_id, tag, created_at) VALUES (?, ?, NOW())", productID, tag); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responses.SuccessResponse(w, "Success", map[string]interface{}{"product_id": productID, "tags": tags}, http.StatusOK)
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -25,89 +24,164 @@ import (
 	"google.golang.org/api/option"
 )
 
+// productUnits adalah satuan jual yang didukung. Produk timbangan atau meteran memakai
+// kg, g, l, ml atau m dengan qty_precision lebih dari 0.
+var productUnits = map[string]bool{"pcs": true, "kg": true, "g": true, "l": true, "ml": true, "m": true}
+
 // list producs
 func ListProducts(w http.ResponseWriter, r *http.Request) {
 	type Product struct {
-		ID        int64  `json:"id"`
-		SKU       string `json:"sku"`
-		Name      string `json:"name"`
-		Stock     string `json:"stock"`
-		Price     string `json:"price"`
-		Image     string `json:"image"`
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
-		Category  *struct {
+		ID           int64   `json:"id"`
+		SKU          string  `json:"sku"`
+		Barcode      *string `json:"barcode"`
+		PLU          *string `json:"plu"`
+		Name         string  `json:"name"`
+		Type         string  `json:"type"`
+		Unit         string  `json:"unit"`
+		QtyPrecision int     `json:"qty_precision"`
+		Stock        string  `json:"stock"`
+		Price        string  `json:"price"`
+		Image        string  `json:"image"`
+		CreatedAt    string  `json:"created_at"`
+		UpdatedAt    string  `json:"updated_at"`
+		Category     *struct {
 			ID   int64  `json:"id"`
 			Name string `json:"name"`
 		} `json:"category"`
+		Variants []productVariant `json:"variants"`
 	}
 
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	skipStr := r.URL.Query().Get("skip")
 	categoryIDStr := r.URL.Query().Get("categoryId")
 	searchQuery := r.URL.Query().Get("q")
 
-	// Convert limit and skip parameters to integers
-	limit, err := strconv.Atoi(limitStr)
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	format, err := exportFormat(r)
 	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'limit' parameter", http.StatusBadRequest)
+		badRequest(w, err)
 		return
 	}
 
-	skip, err := strconv.Atoi(skipStr)
+	storeID, err := storeScope(r)
 	if err != nil {
-		responses.ErrorResponse(w, "Invalid 'skip' parameter", http.StatusBadRequest)
+		badRequest(w, err)
 		return
 	}
 
 	// SQL query with JOIN to fetch data from "products" and "categories" tables
 	query := `
 	    SELECT 
-		p.id, p.sku, p.name, p.stock, p.price, p.image, 
+		p.id, p.sku, p.barcode, p.plu, p.name, p.type, p.unit, p.qty_precision, p.stock, p.price, p.image, 
 		p.created_at, p.updated_at,
 		c.id AS category_id, c.name AS category_name
 	    FROM products p
-	    LEFT JOIN categories c ON p.category_id = c.id
-	    WHERE 1=1
+	    LEFT JOIN categories c ON p.category_id = c.id AND c.deleted_at IS NULL
+	    WHERE p.parent_id IS NULL
 	`
 
 	args := []interface{}{} // Slice to store query parameters
 
+	// Jika ada store, stok dan harga diambil dari store tersebut
+	if storeID != nil {
+		query = `
+		    SELECT 
+			p.id, p.sku, p.barcode, p.plu, p.name, p.type, p.unit, p.qty_precision, ` + qtyText("COALESCE(ps.stock, 0)") + `, COALESCE(ps.price, p.price), p.image, 
+			p.created_at, p.updated_at,
+			c.id AS category_id, c.name AS category_name
+		    FROM products p
+		    LEFT JOIN categories c ON p.category_id = c.id AND c.deleted_at IS NULL
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+		    WHERE p.parent_id IS NULL
+		`
+		args = append(args, *storeID)
+	}
+
+	// filter yang sama dipakai untuk query daftar dan query total
+	where := ""
+	whereArgs := []interface{}{}
+
+	// deleted=true menampilkan produk yang sudah dihapus agar dapat dipulihkan
+	if r.URL.Query().Get("deleted") == "true" {
+		where += " AND p.deleted_at IS NOT NULL"
+	} else {
+		where += " AND p.deleted_at IS NULL"
+	}
+
 	if categoryIDStr != "" {
-		query += " AND (p.category_id = ? OR p.category_id IS NULL)"
 		categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
 		if err != nil {
-			responses.ErrorResponse(w, "Invalid 'categoryId' parameter", http.StatusBadRequest)
+			responses.FieldErrorResponse(w, "categoryId", "Invalid 'categoryId' parameter")
 			return
 		}
-		args = append(args, categoryID)
+		categoryIDs := []int64{categoryID}
+
+		// includeDescendants=true ikut menampilkan produk dari semua subkategori
+		if r.URL.Query().Get("includeDescendants") == "true" {
+			nodes, _, err := loadCategoryTree(config.DB)
+			if err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+			categoryIDs = categoryDescendants(nodes, categoryID)
+		}
+
+		where += " AND (p.category_id IN (?" + strings.Repeat(", ?", len(categoryIDs)-1) + ") OR p.category_id IS NULL)"
+		for _, id := range categoryIDs {
+			whereArgs = append(whereArgs, id)
+		}
 	}
 
 	if searchQuery != "" {
-		query += " AND p.name LIKE ?"
-		args = append(args, "%"+searchQuery+"%")
+		where += " AND p.name LIKE ?"
+		whereArgs = append(whereArgs, "%"+searchQuery+"%")
 	}
 
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, limit, skip)
+	// ekspor CSV/XLSX memakai filter yang sama tanpa paginasi
+	if format != "" {
+		exportProducts(w, r, format, where, whereArgs, storeID)
+		return
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM products p WHERE p.parent_id IS NULL"+where, whereArgs...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "p.id", "p.id", false, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+
+	query += where + keyset + " ORDER BY p.id" + limitSQL
+	args = append(args, whereArgs...)
+	args = append(args, keysetArgs...)
+	args = append(args, limitArgs...)
 
 	rows, err := config.DB.Query(query, args...)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 	defer rows.Close()
 
 	var products []Product
+	var productIDs []int64
 
 	for rows.Next() {
 		var product Product
 		var categoryID sql.NullInt64
 		var categoryName sql.NullString
-		err := rows.Scan(&product.ID, &product.SKU, &product.Name, &product.Stock, &product.Price, &product.Image, &product.CreatedAt, &product.UpdatedAt, &categoryID, &categoryName)
+		err := rows.Scan(&product.ID, &product.SKU, &product.Barcode, &product.PLU, &product.Name, &product.Type, &product.Unit, &product.QtyPrecision, &product.Stock, &product.Price, &product.Image, &product.CreatedAt, &product.UpdatedAt, &categoryID, &categoryName)
 		if err != nil {
-			responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+			responses.InternalError(w, err)
 			return
 		}
 		if categoryID.Valid {
@@ -120,18 +194,29 @@ func ListProducts(w http.ResponseWriter, r *http.Request) {
 		}
 
 		products = append(products, product)
+		productIDs = append(productIDs, product.ID)
+	}
+
+	var next *string
+	if page.hasMore(len(products)) {
+		products, productIDs = products[:page.Limit], productIDs[:page.Limit]
+		next = nextCursor("id", "", products[len(products)-1].ID)
 	}
 
-	total := len(products)
+	// Varian ditampilkan di bawah produk induknya
+	variants, err := loadVariants(productIDs, storeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	for i := range products {
+		products[i].Variants = variants[products[i].ID]
+	}
 
 	// Prepare response
 	response := map[string]interface{}{
 		"data": map[string]interface{}{
-			"meta": map[string]interface{}{
-				"total": total,
-				"limit": limit,
-				"skip":  skip,
-			},
+			"meta":     page.meta(total, next),
 			"products": products,
 		},
 	}
@@ -141,13 +226,82 @@ func ListProducts(w http.ResponseWriter, r *http.Request) {
 	responses.SuccessResponse(w, "Success", response, http.StatusOK)
 }
 
+// exportProducts menulis daftar produk beserta variannya sebagai CSV atau XLSX. Stok dan harga
+// diambil dari store jika ada, dan nilai stok dihitung dengan cost_price.
+func exportProducts(w http.ResponseWriter, r *http.Request, format string, where string, whereArgs []interface{}, storeID *int64) {
+	stockPrice := "CAST(p.stock AS DECIMAL(15,3)), CAST(p.price AS DECIMAL(15,2))"
+	join := ""
+	args := []interface{}{}
+	if storeID != nil {
+		stockPrice = "COALESCE(ps.stock, 0), CAST(COALESCE(ps.price, p.price) AS DECIMAL(15,2))"
+		join = " LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?"
+		args = append(args, *storeID)
+	}
+	args = append(args, whereArgs...)
+
+	// varian ditulis tepat di bawah produk induknya
+	rows, err := config.DB.Query(`
+	    SELECT p.id, parent.sku, p.sku, p.barcode, p.name, c.name, p.unit, `+stockPrice+`, p.cost_price
+	    FROM products p
+	    LEFT JOIN products parent ON parent.id = p.parent_id
+	    LEFT JOIN categories c ON c.id = COALESCE(p.category_id, parent.category_id) AND c.deleted_at IS NULL`+join+`
+	    WHERE (p.parent_id IS NULL OR p.deleted_at IS NULL) AND COALESCE(p.parent_id, p.id) IN (SELECT p.id FROM products p WHERE p.parent_id IS NULL`+where+`)
+	    ORDER BY COALESCE(p.parent_id, p.id), p.parent_id IS NOT NULL, p.id`, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	loc, err := storeLocation(storeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	writer, err := newExportWriter(w, r, format, "products", []string{
+		"ID", "SKU Induk", "SKU", "Barcode", "Nama", "Kategori", "Satuan", "Stok", "Harga", "Harga Pokok", "Nilai Stok",
+	}, loc)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	for rows.Next() {
+		var id int64
+		var parentSKU, barcode, category *string
+		var sku, name, unit string
+		var stock, price, costPrice float64
+		err := rows.Scan(&id, &parentSKU, &sku, &barcode, &name, &category, &unit, &stock, &price, &costPrice)
+		if err == nil {
+			err = writer.WriteRow(id, parentSKU, sku, barcode, name, category, unit, roundQty(stock), price, costPrice, roundMoney(stock*costPrice))
+		}
+		if err != nil {
+			log.Printf("Ekspor products terhenti: %v\n", err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Ekspor products terhenti: %v\n", err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Ekspor products terhenti: %v\n", err)
+	}
+}
+
 // create products
 func CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product struct {
-		CategoryID *int64 `form:"categoryId"`
-		Name       string `form:"name"`
-		Price      string `form:"price"`
-		Stock      string `form:"stock"`
+		CategoryID    *int64  `form:"categoryId"`
+		Name          string  `form:"name" validate:"required,max=255"`
+		Price         int     `form:"price" validate:"min=0"`
+		Stock         string  `form:"stock" validate:"required"`
+		Barcode       *string `form:"barcode"`
+		PLU           *string `form:"plu"`
+		Unit          string  `form:"unit" validate:"oneof=pcs kg g l ml m"`
+		QtyPrecision  int     `form:"qty_precision" validate:"min=0,max=3"`
+		CostPrice     float64 `form:"cost_price" validate:"min=0"`
+		CostingMethod string  `form:"costing_method" validate:"oneof=average fifo"`
 	}
 
 	categoryID, err := strconv.ParseInt(r.FormValue("categoryId"), 10, 64)
@@ -159,21 +313,111 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 	product.CategoryID = &categoryID
 	product.Name = r.FormValue("name")
-	product.Price = r.FormValue("price")
+	product.Price, err = strconv.Atoi(r.FormValue("price"))
+	if err != nil {
+		responses.FieldErrorResponse(w, "price", "price harus diisi dengan bilangan bulat")
+		return
+	}
 	product.Stock = r.FormValue("stock")
+	if barcode := r.FormValue("barcode"); barcode != "" {
+		product.Barcode = &barcode
+	}
+	if plu := r.FormValue("plu"); plu != "" {
+		product.PLU = &plu
+	}
 
-	if product.Name == "" || product.Price == "" || product.Stock == "" {
-		responses.ErrorResponse(w, "Semua kolom harus diisi", http.StatusBadRequest)
+	// satuan jual default pcs tanpa desimal
+	product.Unit = r.FormValue("unit")
+	if product.Unit == "" {
+		product.Unit = "pcs"
+	}
+	if precision := r.FormValue("qty_precision"); precision != "" {
+		product.QtyPrecision, err = strconv.Atoi(precision)
+		if err != nil {
+			responses.FieldErrorResponse(w, "qty_precision", "qty_precision harus berupa angka")
+			return
+		}
+	}
+
+	// harga pokok opsional, metode default rata-rata tertimbang
+	if costPrice := r.FormValue("cost_price"); costPrice != "" {
+		product.CostPrice, err = strconv.ParseFloat(costPrice, 64)
+		if err != nil {
+			responses.FieldErrorResponse(w, "cost_price", "cost_price harus berupa angka")
+			return
+		}
+	}
+	product.CostingMethod = r.FormValue("costing_method")
+	if product.CostingMethod == "" {
+		product.CostingMethod = costingAverage
+	}
+
+	// semua kolom yang tidak valid dilaporkan sekaligus
+	if !validateRequest(w, &product) {
 		return
 	}
 
 	var count int
-	config.DB.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ?", categoryID).Scan(&count)
+	config.DB.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ? AND deleted_at IS NULL", categoryID).Scan(&count)
 
 	if count == 0 {
 		product.CategoryID = nil
 	}
 
+	initialStock, err := strconv.ParseFloat(product.Stock, 64)
+	if err != nil || initialStock < 0 {
+		responses.ErrorResponse(w, "Stok harus berupa angka dan tidak boleh negatif", http.StatusBadRequest)
+		return
+	}
+	if !validQtyPrecision(initialStock, product.QtyPrecision) {
+		responses.ErrorResponse(w, fmt.Sprintf("Stok maksimal %d angka desimal", product.QtyPrecision), http.StatusBadRequest)
+		return
+	}
+
+	// PLU dipakai barcode timbangan untuk menemukan produk
+	if product.PLU != nil {
+		if !validPLU(*product.PLU) {
+			responses.ErrorResponse(w, "PLU harus 5 digit angka", http.StatusBadRequest)
+			return
+		}
+		taken, err := pluTaken(*product.PLU, 0)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if taken {
+			responses.ErrorResponse(w, "PLU "+*product.PLU+" sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+	}
+
+	// barcode pabrik bersifat opsional dan harus unik
+	if product.Barcode != nil {
+		if !validBarcode(*product.Barcode) {
+			responses.ErrorResponse(w, "Barcode tidak valid", http.StatusBadRequest)
+			return
+		}
+		taken, err := barcodeTaken(*product.Barcode, 0)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if taken {
+			responses.ErrorResponse(w, "Barcode "+*product.Barcode+" sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+	}
+
+	// stok awal dicatat di store yang aktif
+	var storeID int64
+	if initialStock > 0 {
+		storeID, err = requireStore(r)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+	}
+
 	file, _, err := r.FormFile("image")
 	if err != nil {
 		errorMessage := fmt.Sprintf("No file uploaded: %v", err)
@@ -263,8 +507,16 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 	randomDigits := rand.Intn(900) + 100
 	SKU := fmt.Sprintf("%s%d", firstLetter, randomDigits)
 
-	result, err := config.DB.Exec("INSERT INTO products (category_id, name, sku, price, stock, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-		product.CategoryID, product.Name, SKU, product.Price, product.Stock, imageURL, currentTime, currentTime)
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// stok diisi lewat pergerakan stok agar tercatat di store
+	result, err := tx.Exec("INSERT INTO products (category_id, name, sku, barcode, plu, unit, qty_precision, price, cost_price, costing_method, stock, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)",
+		product.CategoryID, product.Name, SKU, product.Barcode, product.PLU, product.Unit, product.QtyPrecision, strconv.Itoa(product.Price), roundMoney(product.CostPrice), product.CostingMethod, imageURL, currentTime, currentTime)
 	if err != nil {
 		responses.ErrorResponse(w, "Gagal menyimpan produk ke database", http.StatusInternalServerError)
 		return
@@ -276,26 +528,68 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = recordPriceHistory(tx, priceHistoryEntry{
+		ProductID: lastInsertID,
+		Price:     &product.Price,
+		Source:    priceSourceCreate,
+		UserID:    currentUserID(r),
+	})
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal menyimpan riwayat harga produk", http.StatusInternalServerError)
+		return
+	}
+
+	if initialStock > 0 {
+		err = postStockMovement(tx, stockMovement{
+			StoreID:   storeID,
+			ProductID: lastInsertID,
+			Type:      movementInitialStock,
+			Qty:       initialStock,
+			Note:      "Stok awal",
+		})
+		if err != nil {
+			responses.ErrorResponse(w, "Gagal menyimpan stok awal produk", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.ErrorResponse(w, "Gagal menyimpan produk ke database", http.StatusInternalServerError)
+		return
+	}
+
 	productData := struct {
-		ID        int64       `json:"id"`
-		SKU       string      `json:"sku"`
-		Name      string      `json:"name"`
-		Stock     string      `json:"stock"`
-		Price     string      `json:"price"`
-		Image     string      `json:"image"`
-		Category  interface{} `json:"category"`
-		CreatedAt time.Time   `json:"created_at"`
-		UpdatedAt time.Time   `json:"updated_at"`
+		ID            int64       `json:"id"`
+		SKU           string      `json:"sku"`
+		Barcode       *string     `json:"barcode"`
+		PLU           *string     `json:"plu"`
+		Name          string      `json:"name"`
+		Unit          string      `json:"unit"`
+		QtyPrecision  int         `json:"qty_precision"`
+		Stock         string      `json:"stock"`
+		Price         string      `json:"price"`
+		CostPrice     float64     `json:"cost_price"`
+		CostingMethod string      `json:"costing_method"`
+		Image         string      `json:"image"`
+		Category      interface{} `json:"category"`
+		CreatedAt     time.Time   `json:"created_at"`
+		UpdatedAt     time.Time   `json:"updated_at"`
 	}{
-		ID:        lastInsertID,
-		SKU:       SKU,
-		Name:      product.Name,
-		Stock:     product.Stock,
-		Price:     product.Price,
-		Image:     imageURL,
-		Category:  product.CategoryID,
-		CreatedAt: currentTime,
-		UpdatedAt: currentTime,
+		ID:            lastInsertID,
+		SKU:           SKU,
+		Barcode:       product.Barcode,
+		PLU:           product.PLU,
+		Name:          product.Name,
+		Unit:          product.Unit,
+		QtyPrecision:  product.QtyPrecision,
+		Stock:         formatQty(initialStock),
+		Price:         strconv.Itoa(product.Price),
+		CostPrice:     roundMoney(product.CostPrice),
+		CostingMethod: product.CostingMethod,
+		Image:         imageURL,
+		Category:      product.CategoryID,
+		CreatedAt:     currentTime,
+		UpdatedAt:     currentTime,
 	}
 
 	responses.SuccessResponse(w, "Success", productData, http.StatusCreated)
@@ -304,18 +598,27 @@ func CreateProduct(w http.ResponseWriter, r *http.Request) {
 // DetailProducts
 func DetailProducts(w http.ResponseWriter, r *http.Request) {
 	type Product struct {
-		ID        int64  `json:"id"`
-		SKU       string `json:"sku"`
-		Name      string `json:"name"`
-		Stock     string `json:"stock"`
-		Price     string `json:"price"`
-		Image     string `json:"image"`
-		CreatedAt string `json:"created_at"`
-		UpdatedAt string `json:"updated_at"`
-		Category  *struct {
+		ID            int64   `json:"id"`
+		ParentID      *int64  `json:"parent_id"`
+		SKU           string  `json:"sku"`
+		Barcode       *string `json:"barcode"`
+		PLU           *string `json:"plu"`
+		Name          string  `json:"name"`
+		Type          string  `json:"type"`
+		Unit          string  `json:"unit"`
+		QtyPrecision  int     `json:"qty_precision"`
+		Stock         string  `json:"stock"`
+		Price         string  `json:"price"`
+		CostPrice     float64 `json:"cost_price"`
+		CostingMethod string  `json:"costing_method"`
+		Image         string  `json:"image"`
+		CreatedAt     string  `json:"created_at"`
+		UpdatedAt     string  `json:"updated_at"`
+		Category      *struct {
 			ID   *int64  `json:"id"`
 			Name *string `json:"name"`
 		} `json:"category"`
+		Variants []productVariant `json:"variants"`
 	}
 
 	// get id param
@@ -330,17 +633,40 @@ func DetailProducts(w http.ResponseWriter, r *http.Request) {
 
 	var product Product
 
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
 	// get data product from db using id that passed from param
-	err := config.DB.QueryRow("SELECT id, sku, name, stock, price, image, created_at, updated_at FROM products WHERE id=?", productID).Scan(&product.ID, &product.SKU, &product.Name, &product.Stock, &product.Price, &product.Image, &product.CreatedAt, &product.UpdatedAt)
+	if storeID != nil {
+		err = config.DB.QueryRow(`
+		    SELECT p.id, p.parent_id, p.sku, p.barcode, p.plu, p.name, p.type, p.unit, p.qty_precision, `+qtyText("COALESCE(ps.stock, 0)")+`, COALESCE(ps.price, p.price), p.cost_price, p.costing_method, p.image, p.created_at, p.updated_at
+		    FROM products p
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+		    WHERE p.id=? AND p.deleted_at IS NULL`, *storeID, productID).Scan(&product.ID, &product.ParentID, &product.SKU, &product.Barcode, &product.PLU, &product.Name, &product.Type, &product.Unit, &product.QtyPrecision, &product.Stock, &product.Price, &product.CostPrice, &product.CostingMethod, &product.Image, &product.CreatedAt, &product.UpdatedAt)
+	} else {
+		err = config.DB.QueryRow("SELECT id, parent_id, sku, barcode, plu, name, type, unit, qty_precision, stock, price, cost_price, costing_method, image, created_at, updated_at FROM products WHERE id=? AND deleted_at IS NULL", productID).Scan(&product.ID, &product.ParentID, &product.SKU, &product.Barcode, &product.PLU, &product.Name, &product.Type, &product.Unit, &product.QtyPrecision, &product.Stock, &product.Price, &product.CostPrice, &product.CostingMethod, &product.Image, &product.CreatedAt, &product.UpdatedAt)
+	}
 	if err != nil {
 		if err == sql.ErrNoRows {
 			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
+	if product.ParentID == nil {
+		variants, err := loadVariants([]int64{product.ID}, storeID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		product.Variants = variants[product.ID]
+	}
+
 	// Mengembalikan data produk sebagai JSON
 	w.Header().Set("Content-Type", "application/json")
 	responses.SuccessResponse(w, "Success", product, http.StatusOK)
@@ -355,25 +681,44 @@ func UpdateProducts(w http.ResponseWriter, r *http.Request) {
 		responses.ErrorResponse(w, "ID produk harus disertakan", http.StatusBadRequest)
 		return
 	}
+
+	// Produk yang sudah dihapus harus dipulihkan terlebih dahulu sebelum diubah
+	var productCount int
+	err := config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND deleted_at IS NULL", productID).Scan(&productCount)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if productCount == 0 {
+		responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
 	// Mendapatkan data produk dari body permintaan
 	var updatedProduct struct {
-		Name       string `json:"name"`
-		SKU        string `json:"sku"`
-		Stock      int    `json:"stock"`
-		Price      int    `json:"price"`
-		Image      string `json:"image"`
-		CategoryID *int64 `json:"category_id"`
+		Name          string   `json:"name" validate:"required,max=255"`
+		SKU           string   `json:"sku" validate:"required,max=255"`
+		Barcode       *string  `json:"barcode"`                // dikosongkan jika tidak diubah, string kosong untuk menghapus
+		Stock         *float64 `json:"stock" validate:"min=0"` // stok store yang aktif, dikosongkan jika tidak diubah
+		Price         int      `json:"price" validate:"min=0"`
+		Image         string   `json:"image" validate:"max=1000"`
+		CategoryID    *int64   `json:"category_id"`
+		ContentQty    *float64 `json:"content_qty" validate:"positive"`             // isi kemasan untuk harga satuan di label, misalnya 500
+		ContentUnit   *string  `json:"content_unit" validate:"oneof=g kg ml l pcs"` // satuan isi kemasan
+		Unit          *string  `json:"unit" validate:"oneof=pcs kg g l ml m"`       // satuan jual
+		QtyPrecision  *int     `json:"qty_precision" validate:"min=0,max=3"`        // jumlah angka desimal qty yang diizinkan
+		PLU           *string  `json:"plu"`                                         // kode 5 digit untuk barcode timbangan, string kosong untuk menghapus
+		CostPrice     *float64 `json:"cost_price" validate:"min=0"`                 // harga pokok per unit, dikosongkan jika tidak diubah
+		CostingMethod *string  `json:"costing_method" validate:"oneof=average fifo"`
 		// Anda dapat menambahkan lebih banyak field produk sesuai kebutuhan
 	}
-	if err := json.NewDecoder(r.Body).Decode(&updatedProduct); err != nil {
-		errorMessage := fmt.Sprintf("Gagal membaca data produk dari permintaan: %v", err)
-		responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+	if !decodeRequest(w, r, &updatedProduct) {
 		return
 	}
 
 	// Validasi categoryId
 	var count int
-	err := config.DB.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ?", updatedProduct.CategoryID).Scan(&count)
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ? AND deleted_at IS NULL", updatedProduct.CategoryID).Scan(&count)
 	if err != nil {
 		log.Printf("Error checking categoryID validity: %v\n", err)
 		errorMessage := fmt.Sprintf("Error checking categoryID validity: %v", err)
@@ -388,35 +733,200 @@ func UpdateProducts(w http.ResponseWriter, r *http.Request) {
 		updatedProduct.CategoryID = nil
 	}
 
-	// Menggunakan prepared statement untuk menghindari SQL Injection
-	stmt, err := config.DB.Prepare("UPDATE products SET name=?, sku=?, stock=?, price=?, image=?, category_id=?, updated_at=NOW() WHERE id=?")
+	if updatedProduct.Barcode != nil && *updatedProduct.Barcode != "" {
+		if !validBarcode(*updatedProduct.Barcode) {
+			responses.ErrorResponse(w, "Barcode tidak valid", http.StatusBadRequest)
+			return
+		}
+		id, _ := strconv.ParseInt(productID, 10, 64)
+		taken, err := barcodeTaken(*updatedProduct.Barcode, id)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if taken {
+			responses.ErrorResponse(w, "Barcode "+*updatedProduct.Barcode+" sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+	}
+
+	if updatedProduct.PLU != nil && *updatedProduct.PLU != "" {
+		if !validPLU(*updatedProduct.PLU) {
+			responses.ErrorResponse(w, "PLU harus 5 digit angka", http.StatusBadRequest)
+			return
+		}
+		id, _ := strconv.ParseInt(productID, 10, 64)
+		taken, err := pluTaken(*updatedProduct.PLU, id)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if taken {
+			responses.ErrorResponse(w, "PLU "+*updatedProduct.PLU+" sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+	}
+
+	var storeID int64
+	if updatedProduct.Stock != nil {
+		if !validQtyPrecision(*updatedProduct.Stock, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Stok maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+
+		var productType string
+		err = config.DB.QueryRow("SELECT type FROM products WHERE id = ?", productID).Scan(&productType)
+		if err == nil && productType == productTypeComposite {
+			responses.ErrorResponse(w, "Produk komposit tidak memiliki stok sendiri", http.StatusBadRequest)
+			return
+		}
+		storeID, err = requireStore(r)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// harga lama dibaca lebih dulu untuk riwayat harga
+	id, _ := strconv.ParseInt(productID, 10, 64)
+	oldPrice, err := lockProductPrice(tx, id)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
 		return
 	}
-	defer stmt.Close()
 
 	// Memperbarui produk di database, termasuk field image, category_id, dan updated_at
-	_, err = stmt.Exec(updatedProduct.Name, updatedProduct.SKU, updatedProduct.Stock, updatedProduct.Price, updatedProduct.Image, updatedProduct.CategoryID, productID)
+	// price_updated_at diisi sebelum price agar masih membandingkan dengan harga lama
+	_, err = tx.Exec("UPDATE products SET name=?, sku=?, price_updated_at=IF(price = ?, price_updated_at, NOW()), price=?, image=?, category_id=?, updated_at=NOW() WHERE id=?",
+		updatedProduct.Name, updatedProduct.SKU, strconv.Itoa(updatedProduct.Price), updatedProduct.Price, updatedProduct.Image, updatedProduct.CategoryID, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	err = recordPriceHistory(tx, priceHistoryEntry{
+		ProductID: id,
+		OldPrice:  &oldPrice,
+		Price:     &updatedProduct.Price,
+		Source:    priceSourceManual,
+		UserID:    currentUserID(r),
+	})
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
+		return
+	}
+
+	if updatedProduct.Barcode != nil {
+		_, err = tx.Exec("UPDATE products SET barcode = NULLIF(?, '') WHERE id = ?", *updatedProduct.Barcode, productID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	if updatedProduct.ContentQty != nil || updatedProduct.ContentUnit != nil {
+		_, err = tx.Exec("UPDATE products SET content_qty = COALESCE(?, content_qty), content_unit = COALESCE(NULLIF(?, ''), content_unit) WHERE id = ?",
+			updatedProduct.ContentQty, updatedProduct.ContentUnit, productID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	if updatedProduct.Unit != nil || updatedProduct.QtyPrecision != nil || updatedProduct.PLU != nil {
+		_, err = tx.Exec("UPDATE products SET unit = COALESCE(?, unit), qty_precision = COALESCE(?, qty_precision), plu = IF(? IS NULL, plu, NULLIF(?, '')) WHERE id = ?",
+			updatedProduct.Unit, updatedProduct.QtyPrecision, updatedProduct.PLU, updatedProduct.PLU, productID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	if updatedProduct.CostPrice != nil || updatedProduct.CostingMethod != nil {
+		var costPrice *float64
+		if updatedProduct.CostPrice != nil {
+			rounded := roundMoney(*updatedProduct.CostPrice)
+			costPrice = &rounded
+		}
+		_, err = tx.Exec("UPDATE products SET cost_price = COALESCE(?, cost_price), costing_method = COALESCE(?, costing_method) WHERE id = ?",
+			costPrice, updatedProduct.CostingMethod, productID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		// biaya produk komposit yang memakai produk ini ikut berubah
+		if costPrice != nil {
+			id, _ := strconv.ParseInt(productID, 10, 64)
+			if err := rollUpCompositesUsing(tx, id); err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+		}
+	}
+
+	// Perubahan stok dicatat sebagai penyesuaian sebesar selisih dengan stok store saat ini
+	if updatedProduct.Stock != nil {
+		id, err := strconv.ParseInt(productID, 10, 64)
+		if err != nil {
+			responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+			return
+		}
+
+		var currentStock float64
+		err = tx.QueryRow("SELECT COALESCE((SELECT stock FROM product_stocks WHERE store_id = ? AND product_id = ? FOR UPDATE), 0)", storeID, id).Scan(&currentStock)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		if delta := roundQty(*updatedProduct.Stock - currentStock); delta != 0 {
+			err = postStockMovement(tx, stockMovement{
+				StoreID:   storeID,
+				ProductID: id,
+				Type:      movementManualAdjustment,
+				Qty:       delta,
+				Note:      "Perubahan stok lewat update produk",
+			})
+			if err != nil {
+				responses.InternalError(w, err)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
 		return
 	}
 
 	// Membuat objek data produk untuk dikirim dalam respons
 	productData := struct {
-		ID         string `json:"id"`
-		Name       string `json:"name"`
-		SKU        string `json:"sku"`
-		Stock      int    `json:"stock"`
-		Price      int    `json:"price"`
-		Image      string `json:"image"`
-		CategoryID *int64 `json:"category_id"`
-		UpdatedAt  string `json:"updated_at"`
+		ID         string   `json:"id"`
+		Name       string   `json:"name"`
+		SKU        string   `json:"sku"`
+		Barcode    *string  `json:"barcode"`
+		Stock      *float64 `json:"stock"`
+		Price      int      `json:"price"`
+		Image      string   `json:"image"`
+		CategoryID *int64   `json:"category_id"`
+		UpdatedAt  string   `json:"updated_at"`
 	}{
 		ID:         productID,
 		Name:       updatedProduct.Name,
 		SKU:        updatedProduct.SKU,
+		Barcode:    updatedProduct.Barcode,
 		Stock:      updatedProduct.Stock,
 		Price:      updatedProduct.Price,
 		Image:      updatedProduct.Image,
@@ -437,11 +947,48 @@ func DeleteProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Menghapus pengguna dari database
-	_, err := config.DB.Exec("DELETE FROM products WHERE id=?", userID)
+	productID, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	// Produk induk tidak dapat dihapus selama masih memiliki varian
+	var variantCount int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE parent_id = ? AND deleted_at IS NULL", productID).Scan(&variantCount)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if variantCount > 0 {
+		responses.ErrorResponse(w, "Produk masih memiliki varian, hapus variannya terlebih dahulu", http.StatusConflict)
+		return
+	}
+
+	// Produk yang menjadi komponen produk komposit tidak dapat dihapus
+	var compositeCount int
+	err = config.DB.QueryRow(`
+	    SELECT COUNT(*) FROM product_components pc
+	    JOIN products p ON pc.product_id = p.id
+	    WHERE pc.component_id = ? AND p.deleted_at IS NULL`, productID).Scan(&compositeCount)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
+		return
+	}
+	if compositeCount > 0 {
+		responses.ErrorResponse(w, "Produk masih dipakai sebagai komponen produk komposit", http.StatusConflict)
+		return
+	}
 
+	// Produk hanya ditandai terhapus agar riwayat order tetap dapat menampilkan namanya.
+	// Opsi, komponen dan stoknya dihapus permanen oleh job purge.
+	deleted, err := softDeleteRow(config.DB, "products", productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if !deleted {
+		responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
 		return
 	}
 
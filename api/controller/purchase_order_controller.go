@@ -0,0 +1,548 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/middleware"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// status purchase order: draft -> sent -> partially_received -> received
+const (
+	purchaseOrderDraft             = "draft"
+	purchaseOrderSent              = "sent"
+	purchaseOrderPartiallyReceived = "partially_received"
+	purchaseOrderReceived          = "received"
+)
+
+func CreatePurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	type PurchaseOrderItem struct {
+		ID           int64   `json:"id"`
+		ProductID    int64   `json:"product_id" validate:"required"`
+		Qty          float64 `json:"qty" validate:"positive"`
+		QtyReceived  float64 `json:"qty_received"`
+		ExpectedCost float64 `json:"expected_cost" validate:"min=0"`
+	}
+
+	var request struct {
+		SupplierID int64               `json:"supplier_id" validate:"required,exists=suppliers"`
+		Notes      *string             `json:"notes" validate:"max=1000"`
+		Items      []PurchaseOrderItem `json:"items" validate:"required"`
+	}
+
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	// Validasi item dan hitung total biaya yang diharapkan
+	var expectedTotal float64
+	var count int
+	for _, item := range request.Items {
+		// produk komposit tidak memiliki stok sendiri sehingga tidak bisa dibeli atau dipindahkan
+		err := config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND type <> ? AND deleted_at IS NULL", item.ProductID, productTypeComposite).Scan(&count)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if count == 0 {
+			responses.ErrorResponse(w, "Produk dengan ID "+strconv.FormatInt(item.ProductID, 10)+" tidak ditemukan atau merupakan produk komposit", http.StatusNotFound)
+			return
+		}
+
+		if !validQtyPrecision(item.Qty, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Qty maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+		expectedTotal += item.Qty * item.ExpectedCost
+	}
+
+	// barang dari purchase order akan diterima di store pembuatnya
+	storeID, err := requireStore(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	var userID *int
+	if claims := middleware.CurrentUser(r); claims != nil {
+		userID = &claims.UserId
+	}
+
+	currentTime := time.Now()
+	rand.Seed(time.Now().UnixNano())
+	code := fmt.Sprintf("PO%s%d", currentTime.Format("060102"), rand.Intn(9000)+1000)
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO purchase_orders (store_id, supplier_id, user_id, code, status, notes, expected_total, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		storeID, request.SupplierID, userID, code, purchaseOrderDraft, request.Notes, expectedTotal, currentTime, currentTime)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan purchase order ke database: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	purchaseOrderID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID purchase order yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	for i, item := range request.Items {
+		itemResult, err := tx.Exec("INSERT INTO purchase_order_items (purchase_order_id, product_id, qty, qty_received, expected_cost, created_at, updated_at) VALUES (?, ?, ?, 0, ?, ?, ?)",
+			purchaseOrderID, item.ProductID, item.Qty, item.ExpectedCost, currentTime, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Gagal menyimpan purchase_order_items ke database: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		request.Items[i].ID, err = itemResult.LastInsertId()
+		if err != nil {
+			responses.ErrorResponse(w, "Gagal mendapatkan ID purchase_order_items yang baru", http.StatusInternalServerError)
+			return
+		}
+		request.Items[i].QtyReceived = 0
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID            int64               `json:"id"`
+		StoreID       int64               `json:"store_id"`
+		SupplierID    int64               `json:"supplier_id"`
+		UserID        *int                `json:"user_id"`
+		Code          string              `json:"code"`
+		Status        string              `json:"status"`
+		Notes         *string             `json:"notes"`
+		ExpectedTotal float64             `json:"expected_total"`
+		Items         []PurchaseOrderItem `json:"items"`
+		CreatedAt     string              `json:"created_at"`
+		UpdatedAt     string              `json:"updated_at"`
+	}{
+		ID:            purchaseOrderID,
+		StoreID:       storeID,
+		SupplierID:    request.SupplierID,
+		UserID:        userID,
+		Code:          code,
+		Status:        purchaseOrderDraft,
+		Notes:         request.Notes,
+		ExpectedTotal: expectedTotal,
+		Items:         request.Items,
+		CreatedAt:     currentTime.Format(time.RFC3339),
+		UpdatedAt:     currentTime.Format(time.RFC3339),
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusCreated)
+}
+
+func ListPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	type PurchaseOrder struct {
+		ID            int64   `json:"id"`
+		StoreID       *int64  `json:"store_id"`
+		SupplierID    int64   `json:"supplier_id"`
+		SupplierName  string  `json:"supplier_name"`
+		Code          string  `json:"code"`
+		Status        string  `json:"status"`
+		ExpectedTotal float64 `json:"expected_total"`
+		CreatedAt     string  `json:"created_at"`
+		UpdatedAt     string  `json:"updated_at"`
+	}
+
+	// Parse query parameters
+	supplierIDStr := r.URL.Query().Get("supplierId")
+	status := r.URL.Query().Get("status")
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	if storeID != nil {
+		query += " AND po.store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	if supplierIDStr != "" {
+		supplierID, err := strconv.ParseInt(supplierIDStr, 10, 64)
+		if err != nil {
+			responses.FieldErrorResponse(w, "supplierId", "Invalid 'supplierId' parameter")
+			return
+		}
+		query += " AND po.supplier_id = ?"
+		args = append(args, supplierID)
+	}
+
+	if status != "" {
+		query += " AND po.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM purchase_orders po WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "po.id", "po.id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query(`
+	    SELECT po.id, po.store_id, po.supplier_id, s.name, po.code, po.status, po.expected_total, po.created_at, po.updated_at
+	    FROM purchase_orders po
+	    JOIN suppliers s ON po.supplier_id = s.id
+	    WHERE 1=1`+query+keyset+" ORDER BY po.id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	purchaseOrders := []PurchaseOrder{}
+
+	for rows.Next() {
+		var purchaseOrder PurchaseOrder
+		err := rows.Scan(&purchaseOrder.ID, &purchaseOrder.StoreID, &purchaseOrder.SupplierID, &purchaseOrder.SupplierName, &purchaseOrder.Code,
+			&purchaseOrder.Status, &purchaseOrder.ExpectedTotal, &purchaseOrder.CreatedAt, &purchaseOrder.UpdatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		purchaseOrders = append(purchaseOrders, purchaseOrder)
+	}
+
+	var next *string
+	if page.hasMore(len(purchaseOrders)) {
+		purchaseOrders = purchaseOrders[:page.Limit]
+		next = nextCursor("id", "", purchaseOrders[len(purchaseOrders)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":            page.meta(total, next),
+			"purchase_orders": purchaseOrders,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+func DetailPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	type PurchaseOrderItem struct {
+		ID           int64   `json:"id"`
+		ProductID    int64   `json:"product_id"`
+		ProductName  string  `json:"product_name"`
+		Qty          float64 `json:"qty"`
+		QtyReceived  float64 `json:"qty_received"`
+		ExpectedCost float64 `json:"expected_cost"`
+	}
+
+	type PurchaseOrder struct {
+		ID            int64               `json:"id"`
+		StoreID       *int64              `json:"store_id"`
+		SupplierID    int64               `json:"supplier_id"`
+		SupplierName  string              `json:"supplier_name"`
+		UserID        *int64              `json:"user_id"`
+		Code          string              `json:"code"`
+		Status        string              `json:"status"`
+		Notes         *string             `json:"notes"`
+		ExpectedTotal float64             `json:"expected_total"`
+		Items         []PurchaseOrderItem `json:"items"`
+		CreatedAt     string              `json:"created_at"`
+		UpdatedAt     string              `json:"updated_at"`
+	}
+
+	vars := mux.Vars(r)
+	purchaseOrderID := vars["id"]
+
+	if purchaseOrderID == "" {
+		responses.ErrorResponse(w, "ID purchase order harus diisi", http.StatusBadRequest)
+		return
+	}
+
+	var purchaseOrder PurchaseOrder
+
+	err := config.DB.QueryRow(`
+	    SELECT po.id, po.store_id, po.supplier_id, s.name, po.user_id, po.code, po.status, po.notes, po.expected_total, po.created_at, po.updated_at
+	    FROM purchase_orders po
+	    JOIN suppliers s ON po.supplier_id = s.id
+	    WHERE po.id = ?`, purchaseOrderID).
+		Scan(&purchaseOrder.ID, &purchaseOrder.StoreID, &purchaseOrder.SupplierID, &purchaseOrder.SupplierName, &purchaseOrder.UserID, &purchaseOrder.Code,
+			&purchaseOrder.Status, &purchaseOrder.Notes, &purchaseOrder.ExpectedTotal, &purchaseOrder.CreatedAt, &purchaseOrder.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Purchase order tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStoreOf(r, purchaseOrder.StoreID) {
+		responses.ErrorResponse(w, "Purchase order ini milik store lain", http.StatusForbidden)
+		return
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT poi.id, poi.product_id, p.name, poi.qty, poi.qty_received, poi.expected_cost
+	    FROM purchase_order_items poi
+	    JOIN products p ON poi.product_id = p.id
+	    WHERE poi.purchase_order_id = ?
+	    ORDER BY poi.id`, purchaseOrder.ID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item PurchaseOrderItem
+		err := rows.Scan(&item.ID, &item.ProductID, &item.ProductName, &item.Qty, &item.QtyReceived, &item.ExpectedCost)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		purchaseOrder.Items = append(purchaseOrder.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", purchaseOrder, http.StatusOK)
+}
+
+// SendPurchaseOrders mengubah status purchase order dari draft menjadi sent.
+func SendPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	purchaseOrderID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID purchase order tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var status string
+	var storeID *int64
+	err = config.DB.QueryRow("SELECT status, store_id FROM purchase_orders WHERE id = ?", purchaseOrderID).Scan(&status, &storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Purchase order tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStoreOf(r, storeID) {
+		responses.ErrorResponse(w, "Hanya store pembuat yang dapat mengirim purchase order ini", http.StatusForbidden)
+		return
+	}
+
+	if status != purchaseOrderDraft {
+		errorMessage := fmt.Sprintf("Purchase order dengan status %s tidak dapat dikirim", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	_, err = config.DB.Exec("UPDATE purchase_orders SET status = ?, updated_at = NOW() WHERE id = ? AND status = ?",
+		purchaseOrderSent, purchaseOrderID, purchaseOrderDraft)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}{
+		ID:     purchaseOrderID,
+		Status: purchaseOrderSent,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// ReceivePurchaseOrders mencatat penerimaan barang (sebagian atau seluruhnya) dari sebuah
+// purchase order. Setiap item yang diterima menambah stok produk lewat stock_movements
+// dan memperbarui cost_price produk.
+func ReceivePurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	type ReceiveItem struct {
+		ItemID   int64    `json:"item_id" validate:"required"`
+		Qty      float64  `json:"qty" validate:"positive"`
+		UnitCost *float64 `json:"unit_cost" validate:"min=0"`
+	}
+
+	purchaseOrderID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID purchase order tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Items []ReceiveItem `json:"items" validate:"required"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Kunci purchase order agar penerimaan tidak diproses bersamaan
+	var status, code string
+	var storeID int64
+	err = tx.QueryRow("SELECT status, code, store_id FROM purchase_orders WHERE id = ? FOR UPDATE", purchaseOrderID).Scan(&status, &code, &storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Purchase order tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStore(r, storeID) {
+		responses.ErrorResponse(w, "Hanya store pembuat yang dapat menerima purchase order ini", http.StatusForbidden)
+		return
+	}
+
+	if status != purchaseOrderSent && status != purchaseOrderPartiallyReceived {
+		errorMessage := fmt.Sprintf("Purchase order dengan status %s tidak dapat diterima", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	for i, receiveItem := range request.Items {
+		if !validQtyPrecision(receiveItem.Qty, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Qty yang diterima maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+
+		// Harga beli negatif akan merusak lapisan biaya dan cost_price rata-rata
+		if receiveItem.UnitCost != nil && *receiveItem.UnitCost < 0 {
+			field := fmt.Sprintf("items[%d].unit_cost", i)
+			responses.FieldErrorResponse(w, field, field+" tidak boleh negatif")
+			return
+		}
+
+		var productID int64
+		var qty, qtyReceived float64
+		var expectedCost float64
+		err := tx.QueryRow("SELECT product_id, qty, qty_received, expected_cost FROM purchase_order_items WHERE id = ? AND purchase_order_id = ? FOR UPDATE",
+			receiveItem.ItemID, purchaseOrderID).Scan(&productID, &qty, &qtyReceived, &expectedCost)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				errorMessage := fmt.Sprintf("Item dengan ID %d tidak ditemukan pada purchase order ini", receiveItem.ItemID)
+				responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
+				return
+			}
+			responses.InternalError(w, err)
+			return
+		}
+
+		if roundQty(qtyReceived+receiveItem.Qty) > qty {
+			errorMessage := fmt.Sprintf("Qty item dengan ID %d melebihi sisa yang dipesan (%s)", receiveItem.ItemID, formatQty(qty-qtyReceived))
+			responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+			return
+		}
+
+		unitCost := expectedCost
+		if receiveItem.UnitCost != nil {
+			unitCost = *receiveItem.UnitCost
+		}
+
+		if err := applyReceiptCost(tx, productID, receiveItem.Qty, unitCost); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		err = postStockMovement(tx, stockMovement{
+			StoreID:       storeID,
+			ProductID:     productID,
+			Type:          movementPurchaseReceipt,
+			Qty:           receiveItem.Qty,
+			UnitCost:      &unitCost,
+			ReferenceType: "purchase_order",
+			ReferenceID:   purchaseOrderID,
+			Note:          "Penerimaan " + code,
+		})
+		if err != nil {
+			errorMessage := fmt.Sprintf("Gagal mencatat pergerakan stok: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.Exec("UPDATE purchase_order_items SET qty_received = qty_received + ?, updated_at = NOW() WHERE id = ?", receiveItem.Qty, receiveItem.ItemID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	// Tentukan status baru berdasarkan sisa item yang belum diterima
+	var outstanding int
+	err = tx.QueryRow("SELECT COUNT(*) FROM purchase_order_items WHERE purchase_order_id = ? AND qty_received < qty", purchaseOrderID).Scan(&outstanding)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	newStatus := purchaseOrderReceived
+	if outstanding > 0 {
+		newStatus = purchaseOrderPartiallyReceived
+	}
+
+	_, err = tx.Exec("UPDATE purchase_orders SET status = ?, updated_at = NOW() WHERE id = ?", newStatus, purchaseOrderID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID     int64         `json:"id"`
+		Status string        `json:"status"`
+		Items  []ReceiveItem `json:"received_items"`
+	}{
+		ID:     purchaseOrderID,
+		Status: newStatus,
+		Items:  request.Items,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
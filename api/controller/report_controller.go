@@ -0,0 +1,578 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// salesMetricsSQL menghitung ringkasan penjualan dari baris order_products yang di-join dengan
+// orders. total_price baris adalah nilai yang dibayar pelanggan setelah diskon, sehingga
+// penjualan kotor adalah total_price ditambah diskon. Pajak dan harga pokok order yang di-refund
+// tidak dihitung karena penjualannya dibatalkan.
+const salesMetricsSQL = `
+	COUNT(DISTINCT o.id),
+	COUNT(DISTINCT CASE WHEN o.status = '` + orderStatusRefunded + `' THEN o.id END),
+	COALESCE(SUM(op.qty), 0),
+	CAST(COALESCE(SUM(op.total_price + op.discount), 0) AS SIGNED),
+	CAST(COALESCE(SUM(op.discount), 0) AS SIGNED),
+	CAST(COALESCE(SUM(CASE WHEN o.status = '` + orderStatusRefunded + `' THEN 0 ELSE op.tax END), 0) AS SIGNED),
+	CAST(COALESCE(SUM(CASE WHEN o.status = '` + orderStatusRefunded + `' THEN op.total_price ELSE 0 END), 0) AS SIGNED),
+	COALESCE(SUM(CASE WHEN o.status = '` + orderStatusRefunded + `' THEN 0 ELSE op.total_cost END), 0)`
+
+// salesBucketSQL membulatkan waktu order ke 15 menit. Laporan per waktu dikelompokkan per
+// potongan ini lalu dipindahkan ke zona waktu store di Go, sehingga zona waktu dengan selisih
+// setengah jam tetap masuk ke hari dan jam yang benar.
+const salesBucketSQL = "CONCAT(DATE_FORMAT(o.created_at, '%Y-%m-%d %H:'), LPAD(MINUTE(o.created_at) DIV 15 * 15, 2, '0'))"
+
+// salesSummary adalah angka penjualan untuk satu kelompok laporan.
+type salesSummary struct {
+	Orders         int     `json:"orders"`
+	RefundedOrders int     `json:"refunded_orders"`
+	Qty            float64 `json:"qty"`
+	GrossSales     int64   `json:"gross_sales"`
+	Discounts      int64   `json:"discounts"`
+	Tax            int64   `json:"tax"`
+	Refunds        int64   `json:"refunds"`
+	NetSales       int64   `json:"net_sales"`
+	AverageBasket  int64   `json:"average_basket"`
+	Cogs           float64 `json:"cogs"`
+	GrossProfit    float64 `json:"gross_profit"`
+	MarginPercent  float64 `json:"margin_percent"`
+}
+
+// scanTargets mengembalikan tujuan Scan sesuai urutan kolom salesMetricsSQL.
+func (s *salesSummary) scanTargets() []interface{} {
+	return []interface{}{&s.Orders, &s.RefundedOrders, &s.Qty, &s.GrossSales, &s.Discounts, &s.Tax, &s.Refunds, &s.Cogs}
+}
+
+// add menjumlahkan angka dari kelompok lain. Jumlah order boleh dijumlahkan karena setiap
+// order hanya masuk ke satu potongan waktu.
+func (s *salesSummary) add(other salesSummary) {
+	s.Orders += other.Orders
+	s.RefundedOrders += other.RefundedOrders
+	s.Qty += other.Qty
+	s.GrossSales += other.GrossSales
+	s.Discounts += other.Discounts
+	s.Tax += other.Tax
+	s.Refunds += other.Refunds
+	s.Cogs += other.Cogs
+}
+
+// finish menghitung penjualan bersih, rata-rata nilai transaksi dan margin. Penjualan bersih
+// adalah penjualan kotor dikurangi diskon dan refund; rata-rata hanya dihitung dari order yang
+// tidak di-refund. Laba kotor adalah penjualan bersih tanpa pajak dikurangi harga pokok.
+func (s *salesSummary) finish() {
+	s.Qty = roundQty(s.Qty)
+	s.NetSales = s.GrossSales - s.Discounts - s.Refunds
+	if sold := s.Orders - s.RefundedOrders; sold > 0 {
+		s.AverageBasket = int64(math.Round(float64(s.NetSales) / float64(sold)))
+	}
+
+	s.Cogs = roundMoney(s.Cogs)
+	revenue := float64(s.NetSales - s.Tax)
+	s.GrossProfit = roundMoney(revenue - s.Cogs)
+	if revenue != 0 {
+		s.MarginPercent = roundMoney(s.GrossProfit / revenue * 100)
+	}
+}
+
+// salesReportRow adalah satu baris laporan penjualan. Key adalah tanggal awal periode untuk
+// laporan per waktu, atau ID kasir, payment, kategori maupun produk.
+type salesReportRow struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	salesSummary
+}
+
+// salesSummaryColumns adalah judul kolom ekspor untuk angka salesSummary.
+var salesSummaryColumns = []string{
+	"Order", "Order Refund", "Qty", "Penjualan Kotor", "Diskon", "Pajak", "Refund", "Penjualan Bersih", "Rata-rata Transaksi", "HPP", "Laba Kotor", "Margin (%)",
+}
+
+// exportValues mengembalikan angka salesSummary sesuai urutan salesSummaryColumns.
+func (s salesSummary) exportValues() []interface{} {
+	return []interface{}{s.Orders, s.RefundedOrders, s.Qty, s.GrossSales, s.Discounts, s.Tax, s.Refunds, s.NetSales, s.AverageBasket, s.Cogs, s.GrossProfit, s.MarginPercent}
+}
+
+// exportReport menulis baris laporan yang sudah dihitung sebagai CSV atau XLSX.
+func exportReport(w http.ResponseWriter, r *http.Request, format string, name string, columns []string, loc *time.Location, rows [][]interface{}) {
+	writer, err := newExportWriter(w, r, format, name, columns, loc)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row...); err != nil {
+			log.Printf("Ekspor %s terhenti: %v\n", name, err)
+			return
+		}
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Ekspor %s terhenti: %v\n", name, err)
+	}
+}
+
+// salesHeatmapCell adalah penjualan pada satu jam di satu hari dalam seminggu. DayOfWeek
+// mengikuti ISO: 1 untuk Senin sampai 7 untuk Minggu.
+type salesHeatmapCell struct {
+	DayOfWeek int `json:"day_of_week"`
+	Hour      int `json:"hour"`
+	salesSummary
+}
+
+// salesGroupings adalah pengelompokan laporan per kasir, payment, kategori dan produk. Kolom
+// pertama adalah key dan kolom kedua label.
+var salesGroupings = map[string]struct {
+	columns string
+	join    string
+	groupBy string
+}{
+	"cashier": {
+		columns: "o.user_id, MAX(u.name)",
+		join:    " LEFT JOIN users u ON u.id = o.user_id",
+		groupBy: "o.user_id",
+	},
+	"payment": {
+		columns: "o.payment_id, MAX(pm.name)",
+		join:    " LEFT JOIN payments pm ON pm.id = o.payment_id",
+		groupBy: "o.payment_id",
+	},
+	"category": {
+		columns: "c.id, MAX(c.name)",
+		join:    " LEFT JOIN products p ON p.id = op.product_id LEFT JOIN categories c ON c.id = p.category_id",
+		groupBy: "c.id",
+	},
+	"product": {
+		columns: "op.product_id, MAX(op.product_name)",
+		groupBy: "op.product_id",
+	},
+}
+
+// salesReportFilter membaca store dan rentang tanggal laporan. Order yang dihitung adalah order
+// yang selesai maupun yang di-refund; refund dikurangkan dari penjualan bersih.
+func salesReportFilter(r *http.Request) (string, []interface{}, *int64, *time.Location, error) {
+	storeID, err := storeScope(r)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	loc, err := storeLocation(storeID)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	where := " WHERE o.status IN ('" + orderStatusCompleted + "', '" + orderStatusRefunded + "')"
+	args := []interface{}{}
+
+	if storeID != nil {
+		where += " AND o.store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	query := r.URL.Query()
+	rangeFilter, rangeArgs, err := dateRangeFilter("o.created_at", query.Get("from"), query.Get("to"), loc)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+	where += rangeFilter
+	args = append(args, rangeArgs...)
+
+	return where, args, storeID, loc, nil
+}
+
+// loadStoreLocations mengambil zona waktu semua store, dipakai agar setiap order dikelompokkan
+// menurut jam lokal store tempat order tersebut dibuat.
+func loadStoreLocations() (map[int64]*time.Location, error) {
+	rows, err := config.DB.Query("SELECT id, timezone FROM stores")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := map[int64]*time.Location{}
+	for rows.Next() {
+		var id int64
+		var timezone string
+		if err := rows.Scan(&id, &timezone); err != nil {
+			return nil, err
+		}
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, err
+		}
+		locations[id] = loc
+	}
+	return locations, rows.Err()
+}
+
+// eachSalesBucket memanggil fn untuk setiap potongan 15 menit yang memiliki penjualan, dengan
+// waktu yang sudah dipindahkan ke zona waktu store order tersebut. Order tanpa store memakai
+// zona waktu bawaan.
+func eachSalesBucket(where string, args []interface{}, fn func(at time.Time, summary salesSummary)) error {
+	locations, err := loadStoreLocations()
+	if err != nil {
+		return err
+	}
+	defaultLoc, err := storeLocation(nil)
+	if err != nil {
+		return err
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT o.store_id, `+salesBucketSQL+`, `+salesMetricsSQL+`
+	    FROM orders o
+	    JOIN order_products op ON op.order_id = o.id`+where+`
+	    GROUP BY o.store_id, `+salesBucketSQL, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var storeID sql.NullInt64
+		var bucket string
+		var summary salesSummary
+		if err := rows.Scan(append([]interface{}{&storeID, &bucket}, summary.scanTargets()...)...); err != nil {
+			return err
+		}
+
+		// created_at disimpan dalam zona waktu server (koneksi memakai loc=Local)
+		at, err := time.ParseInLocation("2006-01-02 15:04", bucket, time.Local)
+		if err != nil {
+			return err
+		}
+		loc := defaultLoc
+		if storeLoc, ok := locations[storeID.Int64]; storeID.Valid && ok {
+			loc = storeLoc
+		}
+
+		fn(at.In(loc), summary)
+	}
+	return rows.Err()
+}
+
+// salesPeriodKey mengembalikan awal periode day, week (mulai Senin) atau month dari waktu t.
+func salesPeriodKey(t time.Time, period string) string {
+	switch period {
+	case "week":
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset).Format("2006-01-02")
+	case "month":
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// salesReportSorts adalah urutan yang dapat dipilih untuk laporan per kasir, payment, kategori
+// dan produk.
+var salesReportSorts = map[string]func(a, b salesSummary) bool{
+	"net_sales":      func(a, b salesSummary) bool { return a.NetSales > b.NetSales },
+	"qty":            func(a, b salesSummary) bool { return a.Qty > b.Qty },
+	"gross_profit":   func(a, b salesSummary) bool { return a.GrossProfit > b.GrossProfit },
+	"margin_percent": func(a, b salesSummary) bool { return a.MarginPercent > b.MarginPercent },
+}
+
+// salesReport adalah hasil laporan penjualan yang sudah dikelompokkan.
+type salesReport struct {
+	GroupBy  string
+	StoreID  *int64
+	Location *time.Location
+	Totals   salesSummary
+	Rows     []salesReportRow
+}
+
+// buildSalesReport membaca parameter groupBy, sortBy, limit, from, to dan storeId lalu menyusun
+// laporan penjualan. Laporan per waktu diurutkan menurut periode, sedangkan laporan per kasir,
+// payment, kategori dan produk diurutkan menurut sortBy (default defaultSort). Mengembalikan
+// status HTTP yang sesuai jika terjadi kesalahan.
+func buildSalesReport(r *http.Request, defaultSort string) (salesReport, int, error) {
+	var report salesReport
+	query := r.URL.Query()
+
+	groupBy := query.Get("groupBy")
+	if groupBy == "" {
+		groupBy = "day"
+	}
+	isPeriod := groupBy == "day" || groupBy == "week" || groupBy == "month"
+	grouping, isGrouping := salesGroupings[groupBy]
+	if !isPeriod && !isGrouping {
+		return report, http.StatusBadRequest, invalidField("groupBy", "Invalid 'groupBy' parameter, gunakan day, week, month, cashier, payment, category atau product")
+	}
+
+	sortBy := query.Get("sortBy")
+	if sortBy == "" {
+		sortBy = defaultSort
+	}
+	less, ok := salesReportSorts[sortBy]
+	if !ok {
+		return report, http.StatusBadRequest, invalidField("sortBy", "Invalid 'sortBy' parameter, gunakan net_sales, qty, gross_profit atau margin_percent")
+	}
+
+	limit := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return report, http.StatusBadRequest, invalidField("limit", "Invalid 'limit' parameter")
+		}
+	}
+
+	where, args, storeID, loc, err := salesReportFilter(r)
+	if err != nil {
+		return report, http.StatusBadRequest, err
+	}
+	report.GroupBy = groupBy
+	report.StoreID = storeID
+	report.Location = loc
+
+	err = config.DB.QueryRow("SELECT "+salesMetricsSQL+" FROM orders o JOIN order_products op ON op.order_id = o.id"+where, args...).Scan(report.Totals.scanTargets()...)
+	if err != nil {
+		return report, http.StatusInternalServerError, err
+	}
+	report.Totals.finish()
+
+	rows := []salesReportRow{}
+	if isPeriod {
+		periods := map[string]*salesReportRow{}
+		err = eachSalesBucket(where, args, func(at time.Time, summary salesSummary) {
+			key := salesPeriodKey(at, groupBy)
+			row, ok := periods[key]
+			if !ok {
+				row = &salesReportRow{Key: key, Label: key}
+				periods[key] = row
+			}
+			row.add(summary)
+		})
+		if err != nil {
+			return report, http.StatusInternalServerError, err
+		}
+
+		for _, row := range periods {
+			row.finish()
+			rows = append(rows, *row)
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Key < rows[j].Key })
+	} else {
+		result, err := config.DB.Query(`
+		    SELECT `+grouping.columns+`, `+salesMetricsSQL+`
+		    FROM orders o
+		    JOIN order_products op ON op.order_id = o.id`+grouping.join+where+`
+		    GROUP BY `+grouping.groupBy, args...)
+		if err != nil {
+			return report, http.StatusInternalServerError, err
+		}
+		defer result.Close()
+
+		for result.Next() {
+			var row salesReportRow
+			var key sql.NullInt64
+			var label sql.NullString
+			if err := result.Scan(append([]interface{}{&key, &label}, row.scanTargets()...)...); err != nil {
+				return report, http.StatusInternalServerError, err
+			}
+			if key.Valid {
+				row.Key = strconv.FormatInt(key.Int64, 10)
+			}
+			row.Label = label.String
+			if !key.Valid && groupBy == "category" {
+				row.Label = "Tanpa kategori"
+			}
+			row.finish()
+			rows = append(rows, row)
+		}
+		if err := result.Err(); err != nil {
+			return report, http.StatusInternalServerError, err
+		}
+
+		sort.SliceStable(rows, func(i, j int) bool { return less(rows[i].salesSummary, rows[j].salesSummary) })
+		if limit > 0 && len(rows) > limit {
+			rows = rows[:limit]
+		}
+	}
+	report.Rows = rows
+
+	return report, http.StatusOK, nil
+}
+
+// SalesReport menampilkan ringkasan penjualan: penjualan kotor dan bersih, pajak, diskon,
+// refund, jumlah order dan rata-rata nilai transaksi. Parameter groupBy memilih pengelompokan
+// day, week, month, cashier, payment, category atau product. Laporan per kasir, payment, kategori
+// dan produk diurutkan dari penjualan bersih terbesar, atau dari qty terbesar dengan sortBy=qty
+// untuk melihat produk terlaris.
+func SalesReport(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	report, status, err := buildSalesReport(r, "net_sales")
+	if err != nil {
+		if status == http.StatusBadRequest {
+			badRequest(w, err)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	// ekspor berisi satu baris per kelompok dan baris total di akhir
+	if format != "" {
+		rows := [][]interface{}{}
+		for _, row := range report.Rows {
+			rows = append(rows, append([]interface{}{row.Key, row.Label}, row.exportValues()...))
+		}
+		rows = append(rows, append([]interface{}{"", "Total"}, report.Totals.exportValues()...))
+		exportReport(w, r, format, "sales-"+report.GroupBy, append([]string{"Key", "Label"}, salesSummaryColumns...), report.Location, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"group_by": report.GroupBy,
+			"store_id": report.StoreID,
+			"timezone": report.Location.String(),
+			"totals":   report.Totals,
+			"rows":     report.Rows,
+		},
+	}
+
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// marginSummary adalah bagian laporan penjualan yang ditampilkan di laporan margin. Revenue
+// adalah penjualan bersih tanpa pajak.
+type marginSummary struct {
+	Key           string  `json:"key,omitempty"`
+	Label         string  `json:"label,omitempty"`
+	Qty           float64 `json:"qty"`
+	Revenue       int64   `json:"revenue"`
+	Cogs          float64 `json:"cogs"`
+	GrossProfit   float64 `json:"gross_profit"`
+	MarginPercent float64 `json:"margin_percent"`
+}
+
+// newMarginSummary mengambil angka margin dari ringkasan penjualan.
+func newMarginSummary(key string, label string, summary salesSummary) marginSummary {
+	return marginSummary{
+		Key:           key,
+		Label:         label,
+		Qty:           summary.Qty,
+		Revenue:       summary.NetSales - summary.Tax,
+		Cogs:          summary.Cogs,
+		GrossProfit:   summary.GrossProfit,
+		MarginPercent: summary.MarginPercent,
+	}
+}
+
+// MarginReport menampilkan harga pokok penjualan (COGS), laba kotor dan persentase margin per
+// periode, kasir, payment, kategori atau produk. Harga pokok diambil dari snapshot saat penjualan,
+// sehingga perubahan cost_price tidak mengubah margin penjualan lama. Parameter sama seperti
+// SalesReport; laporan per kategori dan produk diurutkan dari laba kotor terbesar.
+func MarginReport(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	report, status, err := buildSalesReport(r, "gross_profit")
+	if err != nil {
+		if status == http.StatusBadRequest {
+			badRequest(w, err)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	rows := make([]marginSummary, 0, len(report.Rows))
+	for _, row := range report.Rows {
+		rows = append(rows, newMarginSummary(row.Key, row.Label, row.salesSummary))
+	}
+
+	if format != "" {
+		totals := newMarginSummary("", "Total", report.Totals)
+		exportRows := [][]interface{}{}
+		for _, row := range append(rows, totals) {
+			exportRows = append(exportRows, []interface{}{row.Key, row.Label, row.Qty, row.Revenue, row.Cogs, row.GrossProfit, row.MarginPercent})
+		}
+		exportReport(w, r, format, "margin-"+report.GroupBy, []string{"Key", "Label", "Qty", "Pendapatan", "HPP", "Laba Kotor", "Margin (%)"}, report.Location, exportRows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"group_by": report.GroupBy,
+			"store_id": report.StoreID,
+			"timezone": report.Location.String(),
+			"totals":   newMarginSummary("", "", report.Totals),
+			"rows":     rows,
+		},
+	}
+
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// SalesHeatmap menampilkan penjualan per jam untuk setiap hari dalam seminggu (7 x 24 sel)
+// menurut jam lokal store, untuk melihat jam-jam paling ramai.
+func SalesHeatmap(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	where, args, storeID, loc, err := salesReportFilter(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	cells := make([]salesHeatmapCell, 7*24)
+	for i := range cells {
+		cells[i].DayOfWeek = i/24 + 1
+		cells[i].Hour = i % 24
+	}
+
+	err = eachSalesBucket(where, args, func(at time.Time, summary salesSummary) {
+		day := (int(at.Weekday()) + 6) % 7
+		cells[day*24+at.Hour()].add(summary)
+	})
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal membuat heatmap penjualan: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+	for i := range cells {
+		cells[i].finish()
+	}
+
+	if format != "" {
+		rows := [][]interface{}{}
+		for _, cell := range cells {
+			rows = append(rows, append([]interface{}{cell.DayOfWeek, cell.Hour}, cell.exportValues()...))
+		}
+		exportReport(w, r, format, "sales-heatmap", append([]string{"Hari", "Jam"}, salesSummaryColumns...), loc, rows)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"store_id": storeID,
+			"timezone": loc.String(),
+			"cells":    cells,
+		},
+	}
+
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
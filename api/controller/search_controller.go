@@ -0,0 +1,227 @@
+package controller
+
+import (
+	"database/sql"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minSearchLength adalah panjang minimal kata kunci, sesuai ukuran potongan parser ngram.
+const minSearchLength = 2
+
+// searchHit adalah satu produk hasil pencarian beserta skor dan potongan teks yang cocok.
+type searchHit struct {
+	ID         int64             `json:"id"`
+	ParentID   *int64            `json:"parent_id"`
+	SKU        string            `json:"sku"`
+	Barcode    *string           `json:"barcode"`
+	Name       string            `json:"name"`
+	Unit       string            `json:"unit"`
+	Stock      string            `json:"stock"`
+	Price      string            `json:"price"`
+	Image      string            `json:"image"`
+	Category   *productCategory  `json:"category"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// productCategory adalah kategori ringkas yang ditampilkan bersama produk.
+type productCategory struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// compactText mengubah teks menjadi huruf kecil tanpa spasi dan tanda baca, beserta posisi byte
+// setiap huruf pada teks asli agar potongan yang cocok dapat ditandai di teks aslinya.
+func compactText(text string) ([]rune, []int) {
+	var runes []rune
+	var offsets []int
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, unicode.ToLower(r))
+			offsets = append(offsets, i)
+		}
+	}
+	return runes, offsets
+}
+
+// longestCommonRun mencari potongan terpanjang yang sama antara query dan teks, dipakai untuk
+// menandai hasil yang ditemukan walaupun kata kuncinya salah ketik.
+func longestCommonRun(query []rune, text []rune) (start int, length int) {
+	previous := make([]int, len(query)+1)
+	current := make([]int, len(query)+1)
+	for i := 1; i <= len(text); i++ {
+		for j := 1; j <= len(query); j++ {
+			if text[i-1] == query[j-1] {
+				current[j] = previous[j-1] + 1
+				if current[j] > length {
+					length = current[j]
+					start = i - current[j]
+				}
+			} else {
+				current[j] = 0
+			}
+		}
+		previous, current = current, previous
+	}
+	return start, length
+}
+
+// highlightMatch menandai bagian teks yang cocok dengan query menggunakan <em>. Spasi dan tanda
+// baca diabaikan saat mencocokkan, sehingga "kopisusu" menandai "Kopi Susu". Jika tidak ada yang
+// cocok minimal tiga huruf, highlightMatch mengembalikan false.
+func highlightMatch(text string, query string) (string, bool) {
+	textRunes, offsets := compactText(text)
+	queryRunes, _ := compactText(query)
+	if len(textRunes) == 0 || len(queryRunes) == 0 {
+		return "", false
+	}
+
+	start, length := longestCommonRun(queryRunes, textRunes)
+	minLength := 3
+	if len(queryRunes) < minLength {
+		minLength = len(queryRunes)
+	}
+	if length < minLength {
+		return "", false
+	}
+
+	from := offsets[start]
+	last := offsets[start+length-1]
+	_, size := utf8.DecodeRuneInString(text[last:])
+	to := last + size
+
+	return html.EscapeString(text[:from]) + "<em>" + html.EscapeString(text[from:to]) + "</em>" + html.EscapeString(text[to:]), true
+}
+
+// SearchProducts mencari produk berdasarkan nama, SKU, barcode dan nama kategori. Hasil diurutkan
+// dari yang paling relevan: SKU atau barcode yang sama persis, nama yang diawali kata kunci, lalu
+// skor FULLTEXT. Varian ikut dicari dan ditandai dengan parent_id.
+func SearchProducts(w http.ResponseWriter, r *http.Request) {
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(searchQuery) < minSearchLength {
+		responses.ErrorResponse(w, "Kata kunci pencarian minimal 2 karakter", http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	if page.Cursor != nil {
+		responses.ErrorResponse(w, "Pencarian diurutkan berdasarkan relevansi, gunakan 'skip' untuk halaman berikutnya", http.StatusBadRequest)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	prefix := strings.NewReplacer("%", "\\%", "_", "\\_").Replace(searchQuery) + "%"
+	match := "MATCH(p.name, p.sku, p.barcode) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	categoryMatch := "MATCH(c.name) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+	from := `
+	    FROM products p
+	    LEFT JOIN categories c ON c.id = p.category_id AND c.deleted_at IS NULL
+	`
+	where := `
+	    WHERE p.deleted_at IS NULL
+	      AND (` + match + ` OR ` + categoryMatch + ` OR p.sku = ? OR p.barcode = ? OR p.name LIKE ?)
+	`
+	whereArgs := []interface{}{searchQuery, searchQuery, searchQuery, searchQuery, prefix}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*)"+from+where, whereArgs...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	stockPrice := "p.stock, p.price"
+	args := []interface{}{}
+	if storeID != nil {
+		stockPrice = qtyText("COALESCE(ps.stock, 0)") + ", COALESCE(ps.price, p.price)"
+		from += " LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	// skor: kecocokan persis SKU/barcode paling tinggi, lalu nama berawalan kata kunci,
+	// lalu relevansi FULLTEXT nama/SKU/barcode dan setengah relevansi nama kategori
+	score := `(IF(p.sku = ? OR p.barcode = ?, 100, 0) + IF(p.name LIKE ?, 10, 0) + ` + match + ` + 0.5 * COALESCE(` + categoryMatch + `, 0))`
+	scoreArgs := []interface{}{searchQuery, searchQuery, prefix, searchQuery, searchQuery}
+
+	limitSQL, limitArgs := page.limitSQL()
+	query := `
+	    SELECT p.id, p.parent_id, p.sku, p.barcode, p.name, p.unit, ` + stockPrice + `, p.image, c.id, c.name, ` + score + ` AS score` +
+		from + where + ` ORDER BY score DESC, p.id` + limitSQL
+
+	// urutan argumen mengikuti urutan placeholder: skor di SELECT, join store, filter, lalu limit
+	args = append(scoreArgs, args...)
+	args = append(args, whereArgs...)
+	args = append(args, limitArgs...)
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	hits := []searchHit{}
+	for rows.Next() {
+		var hit searchHit
+		var categoryID sql.NullInt64
+		var categoryName sql.NullString
+		err := rows.Scan(&hit.ID, &hit.ParentID, &hit.SKU, &hit.Barcode, &hit.Name, &hit.Unit, &hit.Stock, &hit.Price, &hit.Image, &categoryID, &categoryName, &hit.Score)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if categoryID.Valid {
+			hit.Category = &productCategory{ID: categoryID.Int64, Name: categoryName.String}
+		}
+
+		hit.Highlights = map[string]string{}
+		fields := map[string]string{"name": hit.Name, "sku": hit.SKU}
+		if hit.Barcode != nil {
+			fields["barcode"] = *hit.Barcode
+		}
+		if hit.Category != nil {
+			fields["category"] = hit.Category.Name
+		}
+		for field, text := range fields {
+			if fragment, ok := highlightMatch(text, searchQuery); ok {
+				hit.Highlights[field] = fragment
+			}
+		}
+
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if page.hasMore(len(hits)) {
+		hits = hits[:page.Limit]
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":     page.meta(total, nil),
+			"products": hits,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
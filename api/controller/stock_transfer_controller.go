@@ -0,0 +1,779 @@
+package controller
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"golang-api/api/middleware"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// status transfer stok: draft -> dispatched -> in_transit -> received
+const (
+	stockTransferDraft      = "draft"
+	stockTransferDispatched = "dispatched"
+	stockTransferInTransit  = "in_transit"
+	stockTransferReceived   = "received"
+)
+
+// CreateStockTransfers membuat draft transfer stok dari store yang aktif ke store tujuan.
+func CreateStockTransfers(w http.ResponseWriter, r *http.Request) {
+	type StockTransferItem struct {
+		ID        int64   `json:"id"`
+		ProductID int64   `json:"product_id" validate:"required"`
+		Qty       float64 `json:"qty" validate:"positive"`
+	}
+
+	var request struct {
+		ToStoreID int64               `json:"to_store_id" validate:"required,exists=stores"`
+		Notes     *string             `json:"notes" validate:"max=1000"`
+		Items     []StockTransferItem `json:"items" validate:"required"`
+	}
+
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	// barang selalu dikirim dari store yang aktif
+	fromStoreID, err := requireStore(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	if request.ToStoreID == fromStoreID {
+		responses.ErrorResponse(w, "Store tujuan tidak boleh sama dengan store asal", http.StatusBadRequest)
+		return
+	}
+
+	var count int
+	seen := map[int64]bool{}
+	for _, item := range request.Items {
+		if !validQtyPrecision(item.Qty, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Qty maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+		if seen[item.ProductID] {
+			errorMessage := fmt.Sprintf("Produk dengan ID %d muncul lebih dari sekali", item.ProductID)
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+		seen[item.ProductID] = true
+
+		// produk komposit tidak memiliki stok sendiri sehingga tidak bisa dibeli atau dipindahkan
+		err := config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND type <> ? AND deleted_at IS NULL", item.ProductID, productTypeComposite).Scan(&count)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if count == 0 {
+			responses.ErrorResponse(w, "Produk dengan ID "+strconv.FormatInt(item.ProductID, 10)+" tidak ditemukan atau merupakan produk komposit", http.StatusNotFound)
+			return
+		}
+	}
+
+	var userID *int
+	if claims := middleware.CurrentUser(r); claims != nil {
+		userID = &claims.UserId
+	}
+
+	currentTime := time.Now()
+	rand.Seed(time.Now().UnixNano())
+	code := fmt.Sprintf("TR%s%d", currentTime.Format("060102"), rand.Intn(9000)+1000)
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO stock_transfers (code, from_store_id, to_store_id, status, notes, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		code, fromStoreID, request.ToStoreID, stockTransferDraft, request.Notes, userID, currentTime, currentTime)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan transfer stok ke database: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	transferID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID transfer stok yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	for i, item := range request.Items {
+		itemResult, err := tx.Exec("INSERT INTO stock_transfer_items (stock_transfer_id, product_id, qty, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+			transferID, item.ProductID, item.Qty, currentTime, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Gagal menyimpan stock_transfer_items ke database: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		request.Items[i].ID, err = itemResult.LastInsertId()
+		if err != nil {
+			responses.ErrorResponse(w, "Gagal mendapatkan ID stock_transfer_items yang baru", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID          int64               `json:"id"`
+		Code        string              `json:"code"`
+		FromStoreID int64               `json:"from_store_id"`
+		ToStoreID   int64               `json:"to_store_id"`
+		Status      string              `json:"status"`
+		Notes       *string             `json:"notes"`
+		UserID      *int                `json:"user_id"`
+		Items       []StockTransferItem `json:"items"`
+		CreatedAt   string              `json:"created_at"`
+		UpdatedAt   string              `json:"updated_at"`
+	}{
+		ID:          transferID,
+		Code:        code,
+		FromStoreID: fromStoreID,
+		ToStoreID:   request.ToStoreID,
+		Status:      stockTransferDraft,
+		Notes:       request.Notes,
+		UserID:      userID,
+		Items:       request.Items,
+		CreatedAt:   currentTime.Format(time.RFC3339),
+		UpdatedAt:   currentTime.Format(time.RFC3339),
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusCreated)
+}
+
+// ListStockTransfers menampilkan transfer yang dikirim atau diterima oleh store yang aktif.
+func ListStockTransfers(w http.ResponseWriter, r *http.Request) {
+	type StockTransfer struct {
+		ID           int64   `json:"id"`
+		Code         string  `json:"code"`
+		FromStoreID  int64   `json:"from_store_id"`
+		ToStoreID    int64   `json:"to_store_id"`
+		Status       string  `json:"status"`
+		DispatchedAt *string `json:"dispatched_at"`
+		ReceivedAt   *string `json:"received_at"`
+		CreatedAt    string  `json:"created_at"`
+		UpdatedAt    string  `json:"updated_at"`
+	}
+
+	// Parse query parameters
+	status := r.URL.Query().Get("status")
+	direction := r.URL.Query().Get("direction")
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+
+	// direction: out untuk transfer keluar, in untuk transfer masuk, kosong untuk keduanya
+	if storeID != nil {
+		switch direction {
+		case "out":
+			query += " AND from_store_id = ?"
+			args = append(args, *storeID)
+		case "in":
+			query += " AND to_store_id = ?"
+			args = append(args, *storeID)
+		case "":
+			query += " AND (from_store_id = ? OR to_store_id = ?)"
+			args = append(args, *storeID, *storeID)
+		default:
+			responses.FieldErrorResponse(w, "direction", "Invalid 'direction' parameter")
+			return
+		}
+	}
+
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM stock_transfers WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query("SELECT id, code, from_store_id, to_store_id, status, dispatched_at, received_at, created_at, updated_at FROM stock_transfers WHERE 1=1"+
+		query+keyset+" ORDER BY id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	transfers := []StockTransfer{}
+
+	for rows.Next() {
+		var transfer StockTransfer
+		err := rows.Scan(&transfer.ID, &transfer.Code, &transfer.FromStoreID, &transfer.ToStoreID, &transfer.Status,
+			&transfer.DispatchedAt, &transfer.ReceivedAt, &transfer.CreatedAt, &transfer.UpdatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		transfers = append(transfers, transfer)
+	}
+
+	var next *string
+	if page.hasMore(len(transfers)) {
+		transfers = transfers[:page.Limit]
+		next = nextCursor("id", "", transfers[len(transfers)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":            page.meta(total, next),
+			"stock_transfers": transfers,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+func DetailStockTransfers(w http.ResponseWriter, r *http.Request) {
+	type StockTransferItem struct {
+		ID          int64    `json:"id"`
+		ProductID   int64    `json:"product_id"`
+		ProductName string   `json:"product_name"`
+		Qty         float64  `json:"qty"`
+		QtyReceived *float64 `json:"qty_received"`
+		UnitCost    float64  `json:"unit_cost"`
+	}
+
+	type Discrepancy struct {
+		ID          int64   `json:"id"`
+		ItemID      int64   `json:"item_id"`
+		ProductID   int64   `json:"product_id"`
+		QtySent     float64 `json:"qty_sent"`
+		QtyReceived float64 `json:"qty_received"`
+		Difference  float64 `json:"difference"`
+		UnitCost    float64 `json:"unit_cost"`
+		Note        *string `json:"note"`
+		CreatedAt   string  `json:"created_at"`
+	}
+
+	type StockTransfer struct {
+		ID            int64               `json:"id"`
+		Code          string              `json:"code"`
+		FromStoreID   int64               `json:"from_store_id"`
+		FromStoreName string              `json:"from_store_name"`
+		ToStoreID     int64               `json:"to_store_id"`
+		ToStoreName   string              `json:"to_store_name"`
+		Status        string              `json:"status"`
+		Notes         *string             `json:"notes"`
+		UserID        *int64              `json:"user_id"`
+		DispatchedAt  *string             `json:"dispatched_at"`
+		ReceivedBy    *int64              `json:"received_by"`
+		ReceivedAt    *string             `json:"received_at"`
+		Items         []StockTransferItem `json:"items"`
+		Discrepancies []Discrepancy       `json:"discrepancies"`
+		CreatedAt     string              `json:"created_at"`
+		UpdatedAt     string              `json:"updated_at"`
+	}
+
+	vars := mux.Vars(r)
+	transferID := vars["id"]
+
+	if transferID == "" {
+		responses.ErrorResponse(w, "ID transfer stok harus diisi", http.StatusBadRequest)
+		return
+	}
+
+	var transfer StockTransfer
+
+	err := config.DB.QueryRow(`
+	    SELECT t.id, t.code, t.from_store_id, fs.name, t.to_store_id, ts.name, t.status, t.notes, t.user_id,
+	           t.dispatched_at, t.received_by, t.received_at, t.created_at, t.updated_at
+	    FROM stock_transfers t
+	    JOIN stores fs ON t.from_store_id = fs.id
+	    JOIN stores ts ON t.to_store_id = ts.id
+	    WHERE t.id = ?`, transferID).
+		Scan(&transfer.ID, &transfer.Code, &transfer.FromStoreID, &transfer.FromStoreName, &transfer.ToStoreID, &transfer.ToStoreName,
+			&transfer.Status, &transfer.Notes, &transfer.UserID, &transfer.DispatchedAt, &transfer.ReceivedBy, &transfer.ReceivedAt,
+			&transfer.CreatedAt, &transfer.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Transfer stok tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStore(r, transfer.FromStoreID) && !canAccessStore(r, transfer.ToStoreID) {
+		responses.ErrorResponse(w, "Transfer stok tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT ti.id, ti.product_id, p.name, ti.qty, ti.qty_received, ti.unit_cost
+	    FROM stock_transfer_items ti
+	    JOIN products p ON ti.product_id = p.id
+	    WHERE ti.stock_transfer_id = ?
+	    ORDER BY ti.id`, transfer.ID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item StockTransferItem
+		err := rows.Scan(&item.ID, &item.ProductID, &item.ProductName, &item.Qty, &item.QtyReceived, &item.UnitCost)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		transfer.Items = append(transfer.Items, item)
+	}
+
+	discrepancyRows, err := config.DB.Query(`
+	    SELECT id, stock_transfer_item_id, product_id, qty_sent, qty_received, difference, unit_cost, note, created_at
+	    FROM stock_transfer_discrepancies
+	    WHERE stock_transfer_id = ?
+	    ORDER BY id`, transfer.ID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer discrepancyRows.Close()
+
+	for discrepancyRows.Next() {
+		var discrepancy Discrepancy
+		err := discrepancyRows.Scan(&discrepancy.ID, &discrepancy.ItemID, &discrepancy.ProductID, &discrepancy.QtySent, &discrepancy.QtyReceived,
+			&discrepancy.Difference, &discrepancy.UnitCost, &discrepancy.Note, &discrepancy.CreatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		transfer.Discrepancies = append(transfer.Discrepancies, discrepancy)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", transfer, http.StatusOK)
+}
+
+// DispatchStockTransfers mengirim transfer dari store asal. Stok setiap item dikurangi dari
+// store asal dan cost_price produk disalin ke item transfer.
+func DispatchStockTransfers(w http.ResponseWriter, r *http.Request) {
+	transferID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID transfer stok tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Kunci transfer agar tidak dikirim dua kali
+	var status, code string
+	var fromStoreID int64
+	err = tx.QueryRow("SELECT status, code, from_store_id FROM stock_transfers WHERE id = ? FOR UPDATE", transferID).Scan(&status, &code, &fromStoreID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Transfer stok tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStore(r, fromStoreID) {
+		responses.ErrorResponse(w, "Hanya store asal yang dapat mengirim transfer ini", http.StatusForbidden)
+		return
+	}
+
+	if status != stockTransferDraft {
+		errorMessage := fmt.Sprintf("Transfer stok dengan status %s tidak dapat dikirim", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	type dispatchItem struct {
+		ID        int64
+		ProductID int64
+		Qty       float64
+	}
+
+	rows, err := tx.Query("SELECT id, product_id, qty FROM stock_transfer_items WHERE stock_transfer_id = ? ORDER BY id", transferID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	var items []dispatchItem
+	for rows.Next() {
+		var item dispatchItem
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.Qty); err != nil {
+			rows.Close()
+			responses.InternalError(w, err)
+			return
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+
+	for _, item := range items {
+		// Kunci stok store asal agar tidak terjual bersamaan dengan pengiriman
+		var stock float64
+		var costPrice float64
+		err := tx.QueryRow(`
+		    SELECT COALESCE(ps.stock, 0), p.cost_price
+		    FROM products p
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+		    WHERE p.id = ? FOR UPDATE`, fromStoreID, item.ProductID).Scan(&stock, &costPrice)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		if stock < item.Qty {
+			errorMessage := fmt.Sprintf("Stok produk dengan ID %d tidak mencukupi (tersisa %s)", item.ProductID, formatQty(stock))
+			responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+			return
+		}
+
+		err = postStockMovement(tx, stockMovement{
+			StoreID:       fromStoreID,
+			ProductID:     item.ProductID,
+			Type:          movementTransferOut,
+			Qty:           -item.Qty,
+			UnitCost:      &costPrice,
+			ReferenceType: "stock_transfer",
+			ReferenceID:   transferID,
+			Note:          "Pengiriman " + code,
+		})
+		if err != nil {
+			errorMessage := fmt.Sprintf("Gagal mencatat pergerakan stok: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.Exec("UPDATE stock_transfer_items SET unit_cost = ?, updated_at = NOW() WHERE id = ?", costPrice, item.ID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	_, err = tx.Exec("UPDATE stock_transfers SET status = ?, dispatched_at = NOW(), updated_at = NOW() WHERE id = ?", stockTransferDispatched, transferID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}{
+		ID:     transferID,
+		Status: stockTransferDispatched,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// InTransitStockTransfers menandai transfer yang sudah dikirim sedang dalam perjalanan.
+func InTransitStockTransfers(w http.ResponseWriter, r *http.Request) {
+	transferID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID transfer stok tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var status string
+	var fromStoreID int64
+	err = config.DB.QueryRow("SELECT status, from_store_id FROM stock_transfers WHERE id = ?", transferID).Scan(&status, &fromStoreID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Transfer stok tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStore(r, fromStoreID) {
+		responses.ErrorResponse(w, "Hanya store asal yang dapat mengubah status transfer ini", http.StatusForbidden)
+		return
+	}
+
+	if status != stockTransferDispatched {
+		errorMessage := fmt.Sprintf("Transfer stok dengan status %s tidak dapat ditandai dalam perjalanan", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	_, err = config.DB.Exec("UPDATE stock_transfers SET status = ?, updated_at = NOW() WHERE id = ? AND status = ?",
+		stockTransferInTransit, transferID, stockTransferDispatched)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID     int64  `json:"id"`
+		Status string `json:"status"`
+	}{
+		ID:     transferID,
+		Status: stockTransferInTransit,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// ReceiveStockTransfers mencatat penerimaan transfer di store tujuan. Item yang tidak
+// disebutkan dianggap diterima penuh. Qty yang berbeda dari yang dikirim dicatat di
+// stock_transfer_discrepancies.
+func ReceiveStockTransfers(w http.ResponseWriter, r *http.Request) {
+	type ReceiveItem struct {
+		ItemID      int64   `json:"item_id" validate:"required"`
+		QtyReceived float64 `json:"qty_received" validate:"min=0"`
+		Note        *string `json:"note" validate:"max=1000"`
+	}
+
+	type Discrepancy struct {
+		ItemID      int64   `json:"item_id"`
+		ProductID   int64   `json:"product_id"`
+		QtySent     float64 `json:"qty_sent"`
+		QtyReceived float64 `json:"qty_received"`
+		Difference  float64 `json:"difference"`
+	}
+
+	transferID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID transfer stok tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Items []ReceiveItem `json:"items"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			errorMessage := fmt.Sprintf("Gagal membaca data penerimaan dari permintaan: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+		if !validateRequest(w, &request) {
+			return
+		}
+	}
+
+	received := map[int64]ReceiveItem{}
+	for _, item := range request.Items {
+		if !validQtyPrecision(item.QtyReceived, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Qty yang diterima maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+		received[item.ItemID] = item
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var status, code string
+	var toStoreID int64
+	err = tx.QueryRow("SELECT status, code, to_store_id FROM stock_transfers WHERE id = ? FOR UPDATE", transferID).Scan(&status, &code, &toStoreID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Transfer stok tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStore(r, toStoreID) {
+		responses.ErrorResponse(w, "Hanya store tujuan yang dapat menerima transfer ini", http.StatusForbidden)
+		return
+	}
+
+	if status != stockTransferDispatched && status != stockTransferInTransit {
+		errorMessage := fmt.Sprintf("Transfer stok dengan status %s tidak dapat diterima", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	type transferItem struct {
+		ID        int64
+		ProductID int64
+		Qty       float64
+		UnitCost  float64
+	}
+
+	rows, err := tx.Query("SELECT id, product_id, qty, unit_cost FROM stock_transfer_items WHERE stock_transfer_id = ? ORDER BY id FOR UPDATE", transferID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	var items []transferItem
+	for rows.Next() {
+		var item transferItem
+		if err := rows.Scan(&item.ID, &item.ProductID, &item.Qty, &item.UnitCost); err != nil {
+			rows.Close()
+			responses.InternalError(w, err)
+			return
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+
+	// Pastikan semua item yang dikirim di request memang milik transfer ini
+	for itemID := range received {
+		found := false
+		for _, item := range items {
+			if item.ID == itemID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errorMessage := fmt.Sprintf("Item dengan ID %d tidak ditemukan pada transfer ini", itemID)
+			responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
+			return
+		}
+	}
+
+	currentTime := time.Now()
+	var discrepancies []Discrepancy
+
+	for _, item := range items {
+		qtyReceived := item.Qty
+		var note *string
+		if receiveItem, ok := received[item.ID]; ok {
+			qtyReceived = receiveItem.QtyReceived
+			note = receiveItem.Note
+		}
+
+		// penerimaan melebihi yang dikirim akan menambah stok yang tidak pernah keluar dari store asal
+		if qtyReceived > item.Qty {
+			errorMessage := fmt.Sprintf("Qty yang diterima untuk item dengan ID %d melebihi qty yang dikirim (%s)", item.ID, formatQty(item.Qty))
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+
+		if qtyReceived > 0 {
+			unitCost := item.UnitCost
+			err := postStockMovement(tx, stockMovement{
+				StoreID:       toStoreID,
+				ProductID:     item.ProductID,
+				Type:          movementTransferIn,
+				Qty:           qtyReceived,
+				UnitCost:      &unitCost,
+				ReferenceType: "stock_transfer",
+				ReferenceID:   transferID,
+				Note:          "Penerimaan " + code,
+			})
+			if err != nil {
+				errorMessage := fmt.Sprintf("Gagal mencatat pergerakan stok: %v", err)
+				responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		_, err := tx.Exec("UPDATE stock_transfer_items SET qty_received = ?, updated_at = NOW() WHERE id = ?", qtyReceived, item.ID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		if qtyReceived != item.Qty {
+			difference := roundQty(qtyReceived - item.Qty)
+			_, err := tx.Exec("INSERT INTO stock_transfer_discrepancies (stock_transfer_id, stock_transfer_item_id, product_id, qty_sent, qty_received, difference, unit_cost, note, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+				transferID, item.ID, item.ProductID, item.Qty, qtyReceived, difference, item.UnitCost, note, currentTime)
+			if err != nil {
+				errorMessage := fmt.Sprintf("Gagal menyimpan selisih transfer: %v", err)
+				responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+				return
+			}
+
+			discrepancies = append(discrepancies, Discrepancy{
+				ItemID:      item.ID,
+				ProductID:   item.ProductID,
+				QtySent:     item.Qty,
+				QtyReceived: qtyReceived,
+				Difference:  difference,
+			})
+		}
+	}
+
+	var receivedBy *int
+	if claims := middleware.CurrentUser(r); claims != nil {
+		receivedBy = &claims.UserId
+	}
+
+	_, err = tx.Exec("UPDATE stock_transfers SET status = ?, received_by = ?, received_at = ?, updated_at = NOW() WHERE id = ?",
+		stockTransferReceived, receivedBy, currentTime, transferID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID            int64         `json:"id"`
+		Status        string        `json:"status"`
+		Discrepancies []Discrepancy `json:"discrepancies"`
+	}{
+		ID:            transferID,
+		Status:        stockTransferReceived,
+		Discrepancies: discrepancies,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
@@ -0,0 +1,648 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/middleware"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// status sesi stocktake: open -> approved atau cancelled
+const (
+	stocktakeOpen      = "open"
+	stocktakeApproved  = "approved"
+	stocktakeCancelled = "cancelled"
+)
+
+// CreateStocktakes membuka sesi stocktake untuk satu kategori atau seluruh toko.
+// Stok sistem dan cost_price setiap produk disalin saat sesi dibuka.
+func CreateStocktakes(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		CategoryID *int64  `json:"category_id" validate:"exists=categories"`
+		Notes      *string `json:"notes" validate:"max=1000"`
+	}
+
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	// stocktake selalu dilakukan per store
+	storeID, err := requireStore(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	var userID *int
+	if claims := middleware.CurrentUser(r); claims != nil {
+		userID = &claims.UserId
+	}
+
+	currentTime := time.Now()
+	rand.Seed(time.Now().UnixNano())
+	code := fmt.Sprintf("ST%s%d", currentTime.Format("060102"), rand.Intn(9000)+1000)
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO stocktakes (store_id, code, category_id, status, notes, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		storeID, code, request.CategoryID, stocktakeOpen, request.Notes, userID, currentTime, currentTime)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan stocktake ke database: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	stocktakeID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID stocktake yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	// Salin stok sistem store ini untuk semua produk yang termasuk dalam sesi. Produk komposit dan
+	// induk varian tidak memiliki stok sendiri sehingga tidak ikut dihitung.
+	snapshotSQL := `
+	    INSERT INTO stocktake_items (stocktake_id, product_id, system_qty, unit_cost, created_at, updated_at)
+	    SELECT ?, p.id, COALESCE(ps.stock, 0), p.cost_price, ?, ?
+	    FROM products p
+	    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+	    WHERE p.type <> ? AND p.deleted_at IS NULL
+	      AND NOT EXISTS (SELECT 1 FROM products v WHERE v.parent_id = p.id AND v.deleted_at IS NULL)
+	`
+	args := []interface{}{stocktakeID, currentTime, currentTime, storeID, productTypeComposite}
+	if request.CategoryID != nil {
+		// kategori mencakup semua subkategorinya
+		nodes, _, err := loadCategoryTree(tx)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		placeholders, categoryArgs := int64Args(categoryDescendants(nodes, *request.CategoryID))
+		snapshotSQL += " AND p.category_id IN (" + placeholders + ")"
+		args = append(args, categoryArgs...)
+	}
+
+	snapshotResult, err := tx.Exec(snapshotSQL, args...)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyalin stok produk: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+	itemCount, _ := snapshotResult.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID         int64   `json:"id"`
+		StoreID    int64   `json:"store_id"`
+		Code       string  `json:"code"`
+		CategoryID *int64  `json:"category_id"`
+		Status     string  `json:"status"`
+		Notes      *string `json:"notes"`
+		UserID     *int    `json:"user_id"`
+		ItemCount  int64   `json:"item_count"`
+		CreatedAt  string  `json:"created_at"`
+	}{
+		ID:         stocktakeID,
+		StoreID:    storeID,
+		Code:       code,
+		CategoryID: request.CategoryID,
+		Status:     stocktakeOpen,
+		Notes:      request.Notes,
+		UserID:     userID,
+		ItemCount:  itemCount,
+		CreatedAt:  currentTime.Format(time.RFC3339),
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusCreated)
+}
+
+func ListStocktakes(w http.ResponseWriter, r *http.Request) {
+	type Stocktake struct {
+		ID           int64   `json:"id"`
+		StoreID      *int64  `json:"store_id"`
+		Code         string  `json:"code"`
+		CategoryID   *int64  `json:"category_id"`
+		Status       string  `json:"status"`
+		ItemCount    int     `json:"item_count"`
+		CountedItems int     `json:"counted_items"`
+		ApprovedAt   *string `json:"approved_at"`
+		CreatedAt    string  `json:"created_at"`
+	}
+
+	// Parse query parameters
+	status := r.URL.Query().Get("status")
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	if storeID != nil {
+		query += " AND s.store_id = ?"
+		args = append(args, *storeID)
+	}
+
+	if status != "" {
+		query += " AND s.status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM stocktakes s WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "s.id", "s.id", true, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query(`
+	    SELECT s.id, s.store_id, s.code, s.category_id, s.status, COUNT(si.id), COUNT(si.counted_qty), s.approved_at, s.created_at
+	    FROM stocktakes s
+	    LEFT JOIN stocktake_items si ON si.stocktake_id = s.id
+	    WHERE 1=1`+query+keyset+" GROUP BY s.id ORDER BY s.id DESC"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	stocktakes := []Stocktake{}
+
+	for rows.Next() {
+		var stocktake Stocktake
+		err := rows.Scan(&stocktake.ID, &stocktake.StoreID, &stocktake.Code, &stocktake.CategoryID, &stocktake.Status, &stocktake.ItemCount,
+			&stocktake.CountedItems, &stocktake.ApprovedAt, &stocktake.CreatedAt)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		stocktakes = append(stocktakes, stocktake)
+	}
+
+	var next *string
+	if page.hasMore(len(stocktakes)) {
+		stocktakes = stocktakes[:page.Limit]
+		next = nextCursor("id", "", stocktakes[len(stocktakes)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":       page.meta(total, next),
+			"stocktakes": stocktakes,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+// DetailStocktakes menampilkan sesi stocktake beserta selisih setiap produk terhadap stok sistem.
+func DetailStocktakes(w http.ResponseWriter, r *http.Request) {
+	type StocktakeItem struct {
+		ProductID     int64    `json:"product_id"`
+		SKU           string   `json:"sku"`
+		Name          string   `json:"name"`
+		SystemQty     float64  `json:"system_qty"`
+		CountedQty    *float64 `json:"counted_qty"`
+		VarianceQty   *float64 `json:"variance_qty"`
+		UnitCost      float64  `json:"unit_cost"`
+		VarianceValue *float64 `json:"variance_value"`
+	}
+
+	type Stocktake struct {
+		ID         int64           `json:"id"`
+		StoreID    *int64          `json:"store_id"`
+		Code       string          `json:"code"`
+		CategoryID *int64          `json:"category_id"`
+		Status     string          `json:"status"`
+		Notes      *string         `json:"notes"`
+		UserID     *int64          `json:"user_id"`
+		ApprovedBy *int64          `json:"approved_by"`
+		ApprovedAt *string         `json:"approved_at"`
+		Items      []StocktakeItem `json:"items"`
+		CreatedAt  string          `json:"created_at"`
+		UpdatedAt  string          `json:"updated_at"`
+	}
+
+	vars := mux.Vars(r)
+	stocktakeID := vars["id"]
+
+	if stocktakeID == "" {
+		responses.ErrorResponse(w, "ID stocktake harus diisi", http.StatusBadRequest)
+		return
+	}
+
+	var stocktake Stocktake
+
+	err := config.DB.QueryRow("SELECT id, store_id, code, category_id, status, notes, user_id, approved_by, approved_at, created_at, updated_at FROM stocktakes WHERE id = ?", stocktakeID).
+		Scan(&stocktake.ID, &stocktake.StoreID, &stocktake.Code, &stocktake.CategoryID, &stocktake.Status, &stocktake.Notes, &stocktake.UserID,
+			&stocktake.ApprovedBy, &stocktake.ApprovedAt, &stocktake.CreatedAt, &stocktake.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Stocktake tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStoreOf(r, stocktake.StoreID) {
+		responses.ErrorResponse(w, "Stocktake ini milik store lain", http.StatusForbidden)
+		return
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT si.product_id, p.sku, p.name, si.system_qty, si.counted_qty, si.unit_cost
+	    FROM stocktake_items si
+	    JOIN products p ON si.product_id = p.id
+	    WHERE si.stocktake_id = ?
+	    ORDER BY p.name`, stocktake.ID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item StocktakeItem
+		err := rows.Scan(&item.ProductID, &item.SKU, &item.Name, &item.SystemQty, &item.CountedQty, &item.UnitCost)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		if item.CountedQty != nil {
+			varianceQty := roundQty(*item.CountedQty - item.SystemQty)
+			varianceValue := varianceQty * item.UnitCost
+			item.VarianceQty = &varianceQty
+			item.VarianceValue = &varianceValue
+		}
+
+		stocktake.Items = append(stocktake.Items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", stocktake, http.StatusOK)
+}
+
+// SubmitStocktakeCounts menyimpan hasil hitung fisik. Dengan mode "add" hasil hitung
+// ditambahkan ke hitungan sebelumnya sehingga beberapa perangkat bisa menghitung
+// produk yang sama di rak yang berbeda; mode default "set" menimpa hitungan.
+func SubmitStocktakeCounts(w http.ResponseWriter, r *http.Request) {
+	type Count struct {
+		ProductID int64   `json:"product_id" validate:"required"`
+		Qty       float64 `json:"qty" validate:"min=0"`
+	}
+
+	stocktakeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID stocktake tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Mode   string  `json:"mode" validate:"oneof=set add"`
+		Counts []Count `json:"counts" validate:"required"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	if request.Mode == "" {
+		request.Mode = "set"
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Kunci stocktake agar hasil hitung tidak masuk setelah stocktake disetujui atau dibatalkan
+	var status string
+	var storeID *int64
+	err = tx.QueryRow("SELECT status, store_id FROM stocktakes WHERE id = ? FOR UPDATE", stocktakeID).Scan(&status, &storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Stocktake tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStoreOf(r, storeID) {
+		responses.ErrorResponse(w, "Hanya store yang melakukan stocktake yang dapat mengirim hasil hitung", http.StatusForbidden)
+		return
+	}
+
+	if status != stocktakeOpen {
+		errorMessage := fmt.Sprintf("Stocktake dengan status %s tidak dapat diubah", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	var userID *int
+	if claims := middleware.CurrentUser(r); claims != nil {
+		userID = &claims.UserId
+	}
+
+	updateSQL := "UPDATE stocktake_items SET counted_qty = ?, counted_by = ?, updated_at = NOW() WHERE stocktake_id = ? AND product_id = ?"
+	if request.Mode == "add" {
+		// penambahan dilakukan di database agar aman jika beberapa perangkat mengirim bersamaan
+		updateSQL = "UPDATE stocktake_items SET counted_qty = COALESCE(counted_qty, 0) + ?, counted_by = ?, updated_at = NOW() WHERE stocktake_id = ? AND product_id = ?"
+	}
+
+	for _, count := range request.Counts {
+		if !validQtyPrecision(count.Qty, maxQtyPrecision) {
+			responses.ErrorResponse(w, "Qty hasil hitung maksimal 3 angka desimal", http.StatusBadRequest)
+			return
+		}
+
+		result, err := tx.Exec(updateSQL, count.Qty, userID, stocktakeID, count.ProductID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			errorMessage := fmt.Sprintf("Produk dengan ID %d tidak termasuk dalam stocktake ini", count.ProductID)
+			responses.ErrorResponse(w, errorMessage, http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responses.OtherResponses(w, "Success", http.StatusOK)
+}
+
+// ApproveStocktakes menyetujui sesi stocktake dan memposting selisih hitung sebagai
+// pergerakan stok. Produk yang belum dihitung tidak disesuaikan.
+func ApproveStocktakes(w http.ResponseWriter, r *http.Request) {
+	stocktakeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID stocktake tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var userID *int
+	if claims := middleware.CurrentUser(r); claims != nil {
+		userID = &claims.UserId
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	var status, code string
+	var storeID int64
+	err = tx.QueryRow("SELECT status, code, store_id FROM stocktakes WHERE id = ? FOR UPDATE", stocktakeID).Scan(&status, &code, &storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Stocktake tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStore(r, storeID) {
+		responses.ErrorResponse(w, "Hanya store yang melakukan stocktake yang dapat menyetujuinya", http.StatusForbidden)
+		return
+	}
+
+	if status != stocktakeOpen {
+		errorMessage := fmt.Sprintf("Stocktake dengan status %s tidak dapat disetujui", status)
+		responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+		return
+	}
+
+	rows, err := tx.Query("SELECT product_id, counted_qty - system_qty, unit_cost FROM stocktake_items WHERE stocktake_id = ? AND counted_qty IS NOT NULL AND counted_qty <> system_qty", stocktakeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	var adjustments []stockMovement
+	for rows.Next() {
+		var adjustment stockMovement
+		var unitCost float64
+		if err := rows.Scan(&adjustment.ProductID, &adjustment.Qty, &unitCost); err != nil {
+			rows.Close()
+			responses.InternalError(w, err)
+			return
+		}
+		adjustment.StoreID = storeID
+		adjustment.Type = movementStocktakeAdjustment
+		adjustment.UnitCost = &unitCost
+		adjustment.ReferenceType = "stocktake"
+		adjustment.ReferenceID = stocktakeID
+		adjustment.Note = "Penyesuaian " + code
+		adjustments = append(adjustments, adjustment)
+	}
+	rows.Close()
+
+	// Selisih diposting sebagai delta sehingga penjualan selama sesi berlangsung tetap terhitung
+	for _, adjustment := range adjustments {
+		if err := postStockMovement(tx, adjustment); err != nil {
+			errorMessage := fmt.Sprintf("Gagal mencatat pergerakan stok: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	_, err = tx.Exec("UPDATE stocktakes SET status = ?, approved_by = ?, approved_at = NOW(), updated_at = NOW() WHERE id = ?",
+		stocktakeApproved, userID, stocktakeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID          int64  `json:"id"`
+		Status      string `json:"status"`
+		Adjustments int    `json:"adjustments"`
+	}{
+		ID:          stocktakeID,
+		Status:      stocktakeApproved,
+		Adjustments: len(adjustments),
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// CancelStocktakes membatalkan sesi stocktake yang masih terbuka tanpa menyesuaikan stok.
+func CancelStocktakes(w http.ResponseWriter, r *http.Request) {
+	stocktakeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID stocktake tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var storeID *int64
+	err = config.DB.QueryRow("SELECT store_id FROM stocktakes WHERE id = ?", stocktakeID).Scan(&storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Stocktake tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStoreOf(r, storeID) {
+		responses.ErrorResponse(w, "Hanya store yang melakukan stocktake yang dapat membatalkannya", http.StatusForbidden)
+		return
+	}
+
+	result, err := config.DB.Exec("UPDATE stocktakes SET status = ?, updated_at = NOW() WHERE id = ? AND status = ?",
+		stocktakeCancelled, stocktakeID, stocktakeOpen)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		responses.ErrorResponse(w, "Stocktake tidak ditemukan atau sudah ditutup", http.StatusConflict)
+		return
+	}
+
+	responses.OtherResponses(w, "Success", http.StatusOK)
+}
+
+// StocktakeVarianceReport merangkum selisih stocktake per produk yang dinilai dengan cost_price
+// saat sesi dibuka.
+func StocktakeVarianceReport(w http.ResponseWriter, r *http.Request) {
+	type VarianceLine struct {
+		ProductID     int64   `json:"product_id"`
+		SKU           string  `json:"sku"`
+		Name          string  `json:"name"`
+		SystemQty     float64 `json:"system_qty"`
+		CountedQty    float64 `json:"counted_qty"`
+		VarianceQty   float64 `json:"variance_qty"`
+		UnitCost      float64 `json:"unit_cost"`
+		VarianceValue float64 `json:"variance_value"`
+	}
+
+	stocktakeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID stocktake tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var code, status string
+	var storeID *int64
+	err = config.DB.QueryRow("SELECT code, status, store_id FROM stocktakes WHERE id = ?", stocktakeID).Scan(&code, &status, &storeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Stocktake tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	if !canAccessStoreOf(r, storeID) {
+		responses.ErrorResponse(w, "Stocktake ini milik store lain", http.StatusForbidden)
+		return
+	}
+
+	var uncounted int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM stocktake_items WHERE stocktake_id = ? AND counted_qty IS NULL", stocktakeID).Scan(&uncounted)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT si.product_id, p.sku, p.name, si.system_qty, si.counted_qty, si.unit_cost
+	    FROM stocktake_items si
+	    JOIN products p ON si.product_id = p.id
+	    WHERE si.stocktake_id = ? AND si.counted_qty IS NOT NULL AND si.counted_qty <> si.system_qty
+	    ORDER BY ABS((si.counted_qty - si.system_qty) * si.unit_cost) DESC`, stocktakeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	lines := []VarianceLine{}
+	var gainValue, lossValue float64
+
+	for rows.Next() {
+		var line VarianceLine
+		err := rows.Scan(&line.ProductID, &line.SKU, &line.Name, &line.SystemQty, &line.CountedQty, &line.UnitCost)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		line.VarianceQty = roundQty(line.CountedQty - line.SystemQty)
+		line.VarianceValue = line.VarianceQty * line.UnitCost
+		if line.VarianceValue > 0 {
+			gainValue += line.VarianceValue
+		} else {
+			lossValue += line.VarianceValue
+		}
+
+		lines = append(lines, line)
+	}
+
+	response := map[string]interface{}{
+		"stocktake_id":    stocktakeID,
+		"code":            code,
+		"status":          status,
+		"uncounted_items": uncounted,
+		"gain_value":      gainValue,
+		"loss_value":      lossValue,
+		"net_value":       gainValue + lossValue,
+		"lines":           lines,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
@@ -0,0 +1,462 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/middleware"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultStoreTimezone adalah zona waktu store yang tidak menentukan zona waktunya sendiri.
+const defaultStoreTimezone = "Asia/Jakarta"
+
+// isHeadOffice memeriksa apakah pengguna yang login adalah pengguna kantor pusat. Request
+// yang tidak melewati AuthMiddleware diperlakukan sebagai proses internal.
+func isHeadOffice(r *http.Request) bool {
+	claims := middleware.CurrentUser(r)
+	return claims == nil || claims.HeadOffice
+}
+
+// storeScope mengembalikan store milik pengguna yang login. Pengguna kantor pusat boleh
+// memilih store lewat query parameter storeId; nil berarti semua store.
+func storeScope(r *http.Request) (*int64, error) {
+	if !isHeadOffice(r) {
+		return middleware.CurrentUser(r).StoreId, nil
+	}
+
+	storeIDStr := r.URL.Query().Get("storeId")
+	if storeIDStr == "" {
+		return nil, nil
+	}
+
+	storeID, err := strconv.ParseInt(storeIDStr, 10, 64)
+	if err != nil {
+		return nil, invalidField("storeId", "Invalid 'storeId' parameter")
+	}
+	return &storeID, nil
+}
+
+// requireStore sama seperti storeScope tetapi mewajibkan adanya store, dipakai oleh
+// operasi yang mengubah stok atau mencatat transaksi.
+func requireStore(r *http.Request) (int64, error) {
+	storeID, err := storeScope(r)
+	if err != nil {
+		return 0, err
+	}
+	if storeID == nil {
+		return 0, invalidField("storeId", "Store harus dipilih, gunakan parameter 'storeId'")
+	}
+	return *storeID, nil
+}
+
+// canAccessStore memeriksa apakah pengguna yang login boleh bertindak atas nama store tertentu.
+// Pengguna kantor pusat boleh mengakses semua store.
+func canAccessStore(r *http.Request, storeID int64) bool {
+	if isHeadOffice(r) {
+		return true
+	}
+	claims := middleware.CurrentUser(r)
+	return claims.StoreId != nil && *claims.StoreId == storeID
+}
+
+// canAccessStoreOf sama seperti canAccessStore untuk kolom store_id yang boleh kosong. Data
+// tanpa store hanya dapat diakses pengguna kantor pusat.
+func canAccessStoreOf(r *http.Request, storeID *int64) bool {
+	if storeID == nil {
+		return isHeadOffice(r)
+	}
+	return canAccessStore(r, *storeID)
+}
+
+// storeLocation mengembalikan zona waktu store, dipakai untuk membaca filter tanggal. Tanpa store
+// dipakai zona waktu bawaan.
+func storeLocation(storeID *int64) (*time.Location, error) {
+	timezone := defaultStoreTimezone
+	if storeID != nil {
+		err := config.DB.QueryRow("SELECT timezone FROM stores WHERE id = ?", *storeID).Scan(&timezone)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return time.LoadLocation(timezone)
+}
+
+// dateRangeFilter membuat filter SQL untuk kolom waktu dari parameter from dan to. Nilai berupa
+// tanggal (2006-01-02) dibaca di zona waktu loc dan to mencakup seluruh hari tersebut; nilai
+// RFC3339 dipakai apa adanya.
+func dateRangeFilter(column string, fromStr string, toStr string, loc *time.Location) (string, []interface{}, error) {
+	filter := ""
+	args := []interface{}{}
+
+	if fromStr != "" {
+		from, err := time.ParseInLocation("2006-01-02", fromStr, loc)
+		if err != nil {
+			from, err = time.Parse(time.RFC3339, fromStr)
+			if err != nil {
+				return "", nil, invalidField("from", "Invalid 'from' parameter, gunakan format 2006-01-02 atau RFC3339")
+			}
+		}
+		filter += " AND " + column + " >= ?"
+		args = append(args, from)
+	}
+
+	if toStr != "" {
+		to, err := time.ParseInLocation("2006-01-02", toStr, loc)
+		if err == nil {
+			filter += " AND " + column + " < ?"
+			args = append(args, to.AddDate(0, 0, 1))
+		} else {
+			to, err = time.Parse(time.RFC3339, toStr)
+			if err != nil {
+				return "", nil, invalidField("to", "Invalid 'to' parameter, gunakan format 2006-01-02 atau RFC3339")
+			}
+			filter += " AND " + column + " <= ?"
+			args = append(args, to)
+		}
+	}
+
+	return filter, args, nil
+}
+
+func CreateStore(w http.ResponseWriter, r *http.Request) {
+	if !isHeadOffice(r) {
+		responses.ErrorResponse(w, "Hanya pengguna kantor pusat yang dapat membuat store", http.StatusForbidden)
+		return
+	}
+
+	var store struct {
+		Name     string  `json:"name" validate:"required,max=255"`
+		Address  *string `json:"address" validate:"max=1000"`
+		Timezone string  `json:"timezone" validate:"max=64"`
+	}
+
+	if !decodeRequest(w, r, &store) {
+		return
+	}
+
+	if store.Timezone == "" {
+		store.Timezone = defaultStoreTimezone
+	}
+	if _, err := time.LoadLocation(store.Timezone); err != nil {
+		responses.FieldErrorResponse(w, "timezone", "timezone tidak valid")
+		return
+	}
+
+	currentTime := time.Now()
+
+	result, err := config.DB.Exec("INSERT INTO stores (name, address, timezone, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		store.Name, store.Address, store.Timezone, currentTime, currentTime)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan store ke database: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	lastInsertID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID store yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	storeData := struct {
+		ID        int64     `json:"id"`
+		Name      string    `json:"name"`
+		Address   *string   `json:"address"`
+		Timezone  string    `json:"timezone"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}{
+		ID:        lastInsertID,
+		Name:      store.Name,
+		Address:   store.Address,
+		Timezone:  store.Timezone,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	}
+
+	responses.SuccessResponse(w, "Success", storeData, http.StatusCreated)
+}
+
+func ListStores(w http.ResponseWriter, r *http.Request) {
+	type Store struct {
+		ID       int64   `json:"id"`
+		Name     string  `json:"name"`
+		Address  *string `json:"address"`
+		Timezone string  `json:"timezone"`
+	}
+
+	rows, err := config.DB.Query("SELECT id, name, address, timezone FROM stores ORDER BY id")
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	var stores []Store
+
+	for rows.Next() {
+		var store Store
+		if err := rows.Scan(&store.ID, &store.Name, &store.Address, &store.Timezone); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		stores = append(stores, store)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta": map[string]interface{}{
+				"total": len(stores),
+			},
+			"stores": stores,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+func DetailStores(w http.ResponseWriter, r *http.Request) {
+	type Store struct {
+		ID        int64   `json:"id"`
+		Name      string  `json:"name"`
+		Address   *string `json:"address"`
+		Timezone  string  `json:"timezone"`
+		CreatedAt string  `json:"created_at"`
+		UpdatedAt string  `json:"updated_at"`
+	}
+
+	storeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID store tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	if !canAccessStore(r, storeID) {
+		responses.ErrorResponse(w, "Tidak memiliki akses ke store ini", http.StatusForbidden)
+		return
+	}
+
+	var store Store
+
+	err = config.DB.QueryRow("SELECT id, name, address, timezone, created_at, updated_at FROM stores WHERE id=?", storeID).
+		Scan(&store.ID, &store.Name, &store.Address, &store.Timezone, &store.CreatedAt, &store.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Store tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", store, http.StatusOK)
+}
+
+func UpdateStores(w http.ResponseWriter, r *http.Request) {
+	storeID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID store tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	if !isHeadOffice(r) {
+		responses.ErrorResponse(w, "Hanya pengguna kantor pusat yang dapat mengubah store", http.StatusForbidden)
+		return
+	}
+
+	var updatedStore struct {
+		Name     string  `json:"name" validate:"required,max=255"`
+		Address  *string `json:"address" validate:"max=1000"`
+		Timezone string  `json:"timezone" validate:"max=64"`
+	}
+
+	if !decodeRequest(w, r, &updatedStore) {
+		return
+	}
+
+	if updatedStore.Timezone == "" {
+		updatedStore.Timezone = defaultStoreTimezone
+	}
+	if _, err := time.LoadLocation(updatedStore.Timezone); err != nil {
+		responses.FieldErrorResponse(w, "timezone", "timezone tidak valid")
+		return
+	}
+
+	result, err := config.DB.Exec("UPDATE stores SET name=?, address=?, timezone=?, updated_at=NOW() WHERE id=?",
+		updatedStore.Name, updatedStore.Address, updatedStore.Timezone, storeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		responses.ErrorResponse(w, "Store tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	storeData := struct {
+		ID        int64   `json:"id"`
+		Name      string  `json:"name"`
+		Address   *string `json:"address"`
+		Timezone  string  `json:"timezone"`
+		UpdatedAt string  `json:"updated_at"`
+	}{
+		ID:        storeID,
+		Name:      updatedStore.Name,
+		Address:   updatedStore.Address,
+		Timezone:  updatedStore.Timezone,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	responses.SuccessResponse(w, "Store berhasil diperbarui", storeData, http.StatusOK)
+}
+
+// AssignUserStore menghubungkan pengguna ke sebuah store, atau menjadikannya pengguna kantor
+// pusat yang dapat melihat semua store dengan head_office true. Perubahan berlaku setelah
+// pengguna login ulang.
+func AssignUserStore(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID pengguna tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	// pengguna store tidak boleh memindahkan dirinya atau pengguna lain, termasuk menjadi kantor pusat
+	if !isHeadOffice(r) {
+		responses.ErrorResponse(w, "Hanya pengguna kantor pusat yang dapat mengatur store pengguna", http.StatusForbidden)
+		return
+	}
+
+	var request struct {
+		StoreID    *int64 `json:"store_id" validate:"unless=head_office,required,exists=stores"`
+		HeadOffice bool   `json:"head_office"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	// pengguna kantor pusat tidak terikat ke satu store
+	if request.HeadOffice {
+		request.StoreID = nil
+	}
+
+	result, err := config.DB.Exec("UPDATE users SET store_id = ?, head_office = ?, updated_at = NOW() WHERE id = ? AND deleted_at IS NULL",
+		request.StoreID, request.HeadOffice, userID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		responses.ErrorResponse(w, "User tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	responseData := struct {
+		ID         int64  `json:"id"`
+		StoreID    *int64 `json:"store_id"`
+		HeadOffice bool   `json:"head_office"`
+	}{
+		ID:         userID,
+		StoreID:    request.StoreID,
+		HeadOffice: request.HeadOffice,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
+
+// UpdateStorePrice mengatur harga khusus produk di store yang aktif. price null
+// menghapus harga khusus sehingga produk kembali memakai harga dari products.
+func UpdateStorePrice(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	storeID, err := requireStore(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	var request struct {
+		Price *int `json:"price" validate:"min=0"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	var count int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND deleted_at IS NULL", productID).Scan(&count)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if count == 0 {
+		responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// harga khusus lama dibaca lebih dulu untuk riwayat harga
+	var oldPrice *int
+	err = tx.QueryRow("SELECT price FROM product_stocks WHERE store_id = ? AND product_id = ? FOR UPDATE", storeID, productID).Scan(&oldPrice)
+	if err != nil && err != sql.ErrNoRows {
+		responses.InternalError(w, err)
+		return
+	}
+
+	_, err = tx.Exec(`
+	    INSERT INTO product_stocks (store_id, product_id, stock, price, price_updated_at, updated_at) VALUES (?, ?, 0, ?, NOW(), NOW())
+	    ON DUPLICATE KEY UPDATE price_updated_at = IF(price <=> VALUES(price), price_updated_at, NOW()), price = VALUES(price), updated_at = NOW()`,
+		storeID, productID, request.Price)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	err = recordPriceHistory(tx, priceHistoryEntry{
+		ProductID: productID,
+		StoreID:   &storeID,
+		OldPrice:  oldPrice,
+		Price:     request.Price,
+		Source:    priceSourceStore,
+		UserID:    currentUserID(r),
+	})
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ProductID int64 `json:"product_id"`
+		StoreID   int64 `json:"store_id"`
+		Price     *int  `json:"price"`
+	}{
+		ProductID: productID,
+		StoreID:   storeID,
+		Price:     request.Price,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusOK)
+}
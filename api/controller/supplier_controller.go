@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var supplier struct {
+		Name    string  `json:"name" validate:"required,max=255"`
+		Phone   *string `json:"phone" validate:"max=50"`
+		Email   *string `json:"email" validate:"email,max=255"`
+		Address *string `json:"address" validate:"max=1000"`
+	}
+
+	if !decodeRequest(w, r, &supplier) {
+		return
+	}
+
+	// Waktu saat ini
+	currentTime := time.Now()
+
+	result, err := config.DB.Exec("INSERT INTO suppliers (name, phone, email, address, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		supplier.Name, supplier.Phone, supplier.Email, supplier.Address, currentTime, currentTime)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menyimpan supplier ke database: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	// Mendapatkan ID yang baru saja ditambahkan ke database
+	lastInsertID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID supplier yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	supplierData := struct {
+		ID        int64     `json:"id"`
+		Name      string    `json:"name"`
+		Phone     *string   `json:"phone"`
+		Email     *string   `json:"email"`
+		Address   *string   `json:"address"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}{
+		ID:        lastInsertID,
+		Name:      supplier.Name,
+		Phone:     supplier.Phone,
+		Email:     supplier.Email,
+		Address:   supplier.Address,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	}
+
+	responses.SuccessResponse(w, "Success", supplierData, http.StatusCreated)
+}
+
+func ListSuppliers(w http.ResponseWriter, r *http.Request) {
+	type Supplier struct {
+		ID      int64   `json:"id"`
+		Name    string  `json:"name"`
+		Phone   *string `json:"phone"`
+		Email   *string `json:"email"`
+		Address *string `json:"address"`
+	}
+
+	// Parse query parameters
+	searchQuery := r.URL.Query().Get("q")
+
+	page, err := parsePagination(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	query := ""
+	args := []interface{}{}
+
+	if searchQuery != "" {
+		query += " AND name LIKE ?"
+		args = append(args, "%"+searchQuery+"%")
+	}
+
+	var total int
+	err = config.DB.QueryRow("SELECT COUNT(*) FROM suppliers WHERE 1=1"+query, args...).Scan(&total)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	keyset, keysetArgs, err := page.keyset("id", "id", "id", false, nil)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+	limitSQL, limitArgs := page.limitSQL()
+	args = append(append(args, keysetArgs...), limitArgs...)
+
+	rows, err := config.DB.Query("SELECT id, name, phone, email, address FROM suppliers WHERE 1=1"+query+keyset+" ORDER BY id"+limitSQL, args...)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	suppliers := []Supplier{}
+
+	for rows.Next() {
+		var supplier Supplier
+		err := rows.Scan(&supplier.ID, &supplier.Name, &supplier.Phone, &supplier.Email, &supplier.Address)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		suppliers = append(suppliers, supplier)
+	}
+
+	var next *string
+	if page.hasMore(len(suppliers)) {
+		suppliers = suppliers[:page.Limit]
+		next = nextCursor("id", "", suppliers[len(suppliers)-1].ID)
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta":      page.meta(total, next),
+			"suppliers": suppliers,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
+
+func DetailSuppliers(w http.ResponseWriter, r *http.Request) {
+	type Supplier struct {
+		ID        int64   `json:"id"`
+		Name      string  `json:"name"`
+		Phone     *string `json:"phone"`
+		Email     *string `json:"email"`
+		Address   *string `json:"address"`
+		CreatedAt string  `json:"created_at"`
+		UpdatedAt string  `json:"updated_at"`
+	}
+
+	vars := mux.Vars(r)
+	supplierID := vars["id"]
+
+	if supplierID == "" {
+		responses.ErrorResponse(w, "ID supplier harus diisi", http.StatusBadRequest)
+		return
+	}
+
+	var supplier Supplier
+
+	err := config.DB.QueryRow("SELECT id, name, phone, email, address, created_at, updated_at FROM suppliers WHERE id=?", supplierID).
+		Scan(&supplier.ID, &supplier.Name, &supplier.Phone, &supplier.Email, &supplier.Address, &supplier.CreatedAt, &supplier.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Supplier tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", supplier, http.StatusOK)
+}
+
+func UpdateSuppliers(w http.ResponseWriter, r *http.Request) {
+	supplierID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID supplier tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var updatedSupplier struct {
+		Name    string  `json:"name" validate:"required,max=255"`
+		Phone   *string `json:"phone" validate:"max=50"`
+		Email   *string `json:"email" validate:"email,max=255"`
+		Address *string `json:"address" validate:"max=1000"`
+	}
+
+	if !decodeRequest(w, r, &updatedSupplier) {
+		return
+	}
+
+	result, err := config.DB.Exec("UPDATE suppliers SET name=?, phone=?, email=?, address=?, updated_at=NOW() WHERE id=?",
+		updatedSupplier.Name, updatedSupplier.Phone, updatedSupplier.Email, updatedSupplier.Address, supplierID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		responses.ErrorResponse(w, "Supplier tidak ditemukan", http.StatusNotFound)
+		return
+	}
+
+	supplierData := struct {
+		ID        int64   `json:"id"`
+		Name      string  `json:"name"`
+		Phone     *string `json:"phone"`
+		Email     *string `json:"email"`
+		Address   *string `json:"address"`
+		UpdatedAt string  `json:"updated_at"`
+	}{
+		ID:        supplierID,
+		Name:      updatedSupplier.Name,
+		Phone:     updatedSupplier.Phone,
+		Email:     updatedSupplier.Email,
+		Address:   updatedSupplier.Address,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+
+	responses.SuccessResponse(w, "Supplier berhasil diperbarui", supplierData, http.StatusOK)
+}
+
+func DeleteSuppliers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	supplierID := vars["id"]
+	if supplierID == "" {
+		responses.ErrorResponse(w, "ID supplier harus disertakan", http.StatusBadRequest)
+		return
+	}
+
+	// Supplier yang sudah memiliki purchase order tidak boleh dihapus
+	var count int
+	err := config.DB.QueryRow("SELECT COUNT(*) FROM purchase_orders WHERE supplier_id = ?", supplierID).Scan(&count)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if count > 0 {
+		responses.ErrorResponse(w, "Supplier masih memiliki purchase order", http.StatusConflict)
+		return
+	}
+
+	_, err = config.DB.Exec("DELETE FROM suppliers WHERE id=?", supplierID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responses.OtherResponses(w, "Success", http.StatusOK)
+}
@@ -0,0 +1,333 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultPurgeRetentionDays adalah lama baris yang di-soft delete disimpan sebelum dihapus permanen.
+const defaultPurgeRetentionDays = 30
+
+// purgeInterval adalah jeda antar eksekusi job purge.
+const purgeInterval = 24 * time.Hour
+
+// purgeResult merangkum hasil purge satu tabel. Kept adalah baris yang tidak dapat dihapus
+// karena masih direferensikan riwayat transaksi, baris tersebut tetap dalam keadaan terhapus.
+type purgeResult struct {
+	Purged int `json:"purged"`
+	Kept   int `json:"kept"`
+}
+
+// sqlExecer dipenuhi oleh *sql.DB maupun *sql.Tx.
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// softDeleteRow menandai baris sebagai terhapus. Mengembalikan false jika baris tidak ada
+// atau sudah terhapus sebelumnya.
+func softDeleteRow(db sqlExecer, table string, id int64) (bool, error) {
+	result, err := db.Exec("UPDATE "+table+" SET deleted_at = NOW() WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// restoreRow mengembalikan baris yang sudah di-soft delete. Mengembalikan false jika baris
+// tidak ada atau tidak sedang terhapus.
+func restoreRow(db sqlExecer, table string, id int64) (bool, error) {
+	result, err := db.Exec("UPDATE "+table+" SET deleted_at = NULL, updated_at = NOW() WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// restoreResponse menulis respons untuk endpoint restore.
+func restoreResponse(w http.ResponseWriter, restored bool, err error, label string, id int64) {
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if !restored {
+		responses.ErrorResponse(w, label+" dengan ID "+strconv.FormatInt(id, 10)+" tidak ditemukan di antara data yang terhapus", http.StatusNotFound)
+		return
+	}
+
+	responseData := struct {
+		ID int64 `json:"id"`
+	}{
+		ID: id,
+	}
+	responses.SuccessResponse(w, label+" berhasil dipulihkan", responseData, http.StatusOK)
+}
+
+// RestoreProducts memulihkan produk yang sudah dihapus. Varian hanya dapat dipulihkan
+// jika produk induknya tidak sedang terhapus.
+func RestoreProducts(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var parentDeleted bool
+	err = config.DB.QueryRow(`
+	    SELECT COUNT(*) > 0 FROM products p
+	    JOIN products parent ON p.parent_id = parent.id
+	    WHERE p.id = ? AND parent.deleted_at IS NOT NULL`, productID).Scan(&parentDeleted)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if parentDeleted {
+		responses.ErrorResponse(w, "Produk induk dari varian ini masih terhapus, pulihkan produk induknya terlebih dahulu", http.StatusConflict)
+		return
+	}
+
+	restored, err := restoreRow(config.DB, "products", productID)
+	restoreResponse(w, restored, err, "Produk", productID)
+}
+
+// RestoreCategories memulihkan kategori yang sudah dihapus. Jika kategori induknya juga
+// terhapus, kategori dipulihkan sebagai kategori teratas.
+func RestoreCategories(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID kategori tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	restored, err := restoreRow(tx, "categories", categoryID)
+	if err == nil && restored {
+		_, err = tx.Exec(`
+		    UPDATE categories c JOIN categories parent ON c.parent_id = parent.id
+		    SET c.parent_id = NULL
+		    WHERE c.id = ? AND parent.deleted_at IS NOT NULL`, categoryID)
+		if err == nil {
+			err = tx.Commit()
+		}
+	}
+	restoreResponse(w, restored, err, "Kategori", categoryID)
+}
+
+// RestorePayments memulihkan metode pembayaran yang sudah dihapus.
+func RestorePayments(w http.ResponseWriter, r *http.Request) {
+	paymentID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID payment tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := restoreRow(config.DB, "payments", paymentID)
+	restoreResponse(w, restored, err, "Payment", paymentID)
+}
+
+// RestoreUser memulihkan pengguna yang sudah dihapus sehingga dapat login kembali.
+func RestoreUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID pengguna tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	restored, err := restoreRow(config.DB, "users", userID)
+	restoreResponse(w, restored, err, "Pengguna", userID)
+}
+
+// purgeRetentionDays membaca PURGE_RETENTION_DAYS, default 30 hari.
+func purgeRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("PURGE_RETENTION_DAYS"))
+	if err != nil || days < 0 {
+		return defaultPurgeRetentionDays
+	}
+	return days
+}
+
+// purgeRows menghapus permanen baris yang di-soft delete sebelum cutoff. Setiap baris dihapus
+// di transaksinya sendiri bersama data turunannya; jika masih direferensikan (misalnya oleh
+// order_products atau stock_movements) foreign key menolak penghapusan dan baris dibiarkan.
+func purgeRows(query string, cutoff time.Time, deleteRow func(tx *sql.Tx, id int64) error) (purgeResult, error) {
+	var result purgeResult
+
+	rows, err := config.DB.Query(query, cutoff)
+	if err != nil {
+		return result, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return result, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, id := range ids {
+		tx, err := config.DB.Begin()
+		if err != nil {
+			return result, err
+		}
+		if err := deleteRow(tx, id); err != nil {
+			tx.Rollback()
+			result.Kept++
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			return result, err
+		}
+		result.Purged++
+	}
+	return result, nil
+}
+
+// execAll menjalankan beberapa statement dengan argumen yang sama secara berurutan.
+func execAll(tx *sql.Tx, id int64, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeDeleted menghapus permanen semua produk, kategori, payment dan user yang sudah
+// di-soft delete lebih lama dari retentionDays.
+func purgeDeleted(retentionDays int) (map[string]purgeResult, error) {
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+	results := map[string]purgeResult{}
+
+	// varian diproses lebih dulu agar produk induknya dapat ikut terhapus
+	result, err := purgeRows("SELECT id FROM products WHERE deleted_at < ? ORDER BY parent_id IS NULL, id", cutoff, func(tx *sql.Tx, id int64) error {
+		return execAll(tx, id,
+			"DELETE FROM product_variant_values WHERE variant_id = ?",
+			"DELETE ov FROM product_option_values ov JOIN product_options o ON ov.option_id = o.id WHERE o.product_id = ?",
+			"DELETE FROM product_options WHERE product_id = ?",
+			"DELETE FROM product_components WHERE product_id = ?",
+			"DELETE FROM modifier_group_links WHERE product_id = ?",
+			"DELETE FROM product_stocks WHERE product_id = ?",
+			"DELETE FROM cost_layers WHERE product_id = ?",
+			"DELETE FROM product_tags WHERE product_id = ?",
+			"DELETE FROM price_histories WHERE product_id = ?",
+			"DELETE FROM price_change_items WHERE product_id = ?",
+			"DELETE FROM products WHERE id = ?",
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	results["products"] = result
+
+	result, err = purgeRows("SELECT id FROM categories WHERE deleted_at < ? ORDER BY id", cutoff, func(tx *sql.Tx, id int64) error {
+		return execAll(tx, id,
+			"UPDATE products SET category_id = NULL WHERE category_id = ?",
+			"DELETE FROM modifier_group_links WHERE category_id = ?",
+			"DELETE FROM categories WHERE id = ?",
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	results["categories"] = result
+
+	result, err = purgeRows("SELECT id FROM payments WHERE deleted_at < ? ORDER BY id", cutoff, func(tx *sql.Tx, id int64) error {
+		return execAll(tx, id, "DELETE FROM payments WHERE id = ?")
+	})
+	if err != nil {
+		return nil, err
+	}
+	results["payments"] = result
+
+	result, err = purgeRows("SELECT id FROM users WHERE deleted_at < ? ORDER BY id", cutoff, func(tx *sql.Tx, id int64) error {
+		return execAll(tx, id, "DELETE FROM users WHERE id = ?")
+	})
+	if err != nil {
+		return nil, err
+	}
+	results["users"] = result
+
+	return results, nil
+}
+
+// StartPurgeWorker menjalankan job latar belakang yang setiap hari menghapus permanen data
+// yang sudah di-soft delete lebih lama dari PURGE_RETENTION_DAYS.
+func StartPurgeWorker() {
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			results, err := purgeDeleted(purgeRetentionDays())
+			if err != nil {
+				log.Printf("Gagal menjalankan purge data terhapus: %v\n", err)
+				continue
+			}
+			for table, result := range results {
+				if result.Purged > 0 {
+					log.Printf("Purge %s: %d dihapus permanen, %d dipertahankan\n", table, result.Purged, result.Kept)
+				}
+			}
+		}
+	}()
+}
+
+// PurgeDeleted menjalankan purge secara langsung. Hanya pengguna kantor pusat yang boleh
+// menjalankannya. Parameter olderThanDays menggantikan PURGE_RETENTION_DAYS.
+func PurgeDeleted(w http.ResponseWriter, r *http.Request) {
+	if !isHeadOffice(r) {
+		responses.ErrorResponse(w, "Hanya pengguna kantor pusat yang dapat menjalankan purge", http.StatusForbidden)
+		return
+	}
+
+	retentionDays := purgeRetentionDays()
+	if olderThanStr := r.URL.Query().Get("olderThanDays"); olderThanStr != "" {
+		olderThan, err := strconv.Atoi(olderThanStr)
+		if err != nil || olderThan < 0 {
+			responses.FieldErrorResponse(w, "olderThanDays", "Invalid 'olderThanDays' parameter")
+			return
+		}
+		retentionDays = olderThan
+	}
+
+	results, err := purgeDeleted(retentionDays)
+	if err != nil {
+		errorMessage := fmt.Sprintf("Gagal menjalankan purge: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"older_than_days": retentionDays,
+		"results":         results,
+	}
+
+	responses.SuccessResponse(w, "Purge selesai", response, http.StatusOK)
+}
@@ -3,28 +3,63 @@ package controller
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"golang-api/api/middleware"
 	"golang-api/api/responses"
 	"golang-api/config"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// CreateUser membuat pengguna baru. Hanya pengguna kantor pusat yang boleh membuat pengguna,
+// kecuali pengguna pertama pada instalasi baru yang otomatis menjadi kantor pusat. Pengguna
+// selain kantor pusat wajib terhubung ke sebuah store.
 func CreateUser(w http.ResponseWriter, r *http.Request) {
 	// Inisialisasi koneksi ke database
 	var user struct {
-		Id       int64  // Gunakan tipe data int64 untuk ID
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Id         int64  // Gunakan tipe data int64 untuk ID
+		Name       string `json:"name" validate:"required,max=255"`
+		Email      string `json:"email" validate:"required,email,max=255"`
+		Password   string `json:"password" validate:"required,min=8,max=72"`
+		StoreID    *int64 `json:"store_id" validate:"unless=head_office,required,exists=stores"`
+		HeadOffice bool   `json:"head_office"`
+	}
+
+	bootstrap := false
+	if claims := middleware.CurrentUser(r); claims == nil {
+		var count int
+		if err := config.DB.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if count > 0 {
+			responses.ErrorResponse(w, "Unauthorized: Missing token", http.StatusUnauthorized)
+			return
+		}
+		bootstrap = true
+	} else if !claims.HeadOffice {
+		responses.ErrorResponse(w, "Hanya pengguna kantor pusat yang dapat membuat pengguna", http.StatusForbidden)
+		return
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		// Mengembalikan respons JSON jika gagal membaca data dari permintaan
-		responses.ErrorResponse(w, "Gagal membaca data pengguna dari permintaan", http.StatusBadRequest)
+		errorMessage := fmt.Sprintf("Gagal membaca data dari permintaan: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+		return
+	}
+	if bootstrap {
+		user.HeadOffice = true
+	}
+	if user.HeadOffice {
+		user.StoreID = nil
+	}
+
+	// Mengembalikan respons JSON jika data pengguna tidak valid
+	if !validateRequest(w, &user) {
 		return
 	}
 
@@ -38,17 +73,15 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 	currentTime := time.Now()
 
 	// Simpan pengguna ke database dengan menggunakan data yang telah Anda validasi
-	result, err := config.DB.Exec("INSERT INTO users (name, email, password, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
-		user.Name, user.Email, hashedPassword, currentTime, currentTime) // Mengganti user.Username menjadi user.Name
+	result, err := config.DB.Exec("INSERT INTO users (name, email, password, store_id, head_office, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		user.Name, user.Email, hashedPassword, user.StoreID, user.HeadOffice, currentTime, currentTime) // Mengganti user.Username menjadi user.Name
 	if err != nil {
 		// Menangani kesalahan jika gagal menyimpan pengguna ke database
-		errorMessage := "Gagal menyimpan pengguna ke database"
-		if strings.Contains(err.Error(), "Duplicate entry") {
-			errorMessage = "Email sudah digunakan. Silakan gunakan email lain."
-		} else {
-			errorMessage = "Terjadi kesalahan saat menyimpan pengguna ke database."
+		if isDuplicateEntry(err) {
+			responses.FieldErrorResponse(w, "email", "Email sudah digunakan. Silakan gunakan email lain.")
+			return
 		}
-		responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -64,17 +97,21 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	// Membuat objek data pengguna untuk dikirim dalam respons
 	userData := struct {
-		Id        int64     `json:"id"` // Gunakan ID yang telah diisi
-		Name      string    `json:"name"`
-		Email     string    `json:"email"`
-		CreatedAt time.Time `json:"created_at"`
-		UpdatedAt time.Time `json:"updated_at"`
+		Id         int64     `json:"id"` // Gunakan ID yang telah diisi
+		Name       string    `json:"name"`
+		Email      string    `json:"email"`
+		StoreID    *int64    `json:"store_id"`
+		HeadOffice bool      `json:"head_office"`
+		CreatedAt  time.Time `json:"created_at"`
+		UpdatedAt  time.Time `json:"updated_at"`
 	}{
-		Id:        user.Id,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: currentTime,
-		UpdatedAt: currentTime,
+		Id:         user.Id,
+		Name:       user.Name,
+		Email:      user.Email,
+		StoreID:    user.StoreID,
+		HeadOffice: user.HeadOffice,
+		CreatedAt:  currentTime,
+		UpdatedAt:  currentTime,
 	}
 
 	responses.SuccessResponse(w, "Success", userData, http.StatusCreated)
@@ -101,14 +138,14 @@ func GetUser(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// Query database menggunakan prepared statement untuk menghindari SQL injection
-	err := config.DB.QueryRow("SELECT name, email, created_at, updated_at FROM users WHERE id=?", userID).
+	err := config.DB.QueryRow("SELECT name, email, created_at, updated_at FROM users WHERE id=? AND deleted_at IS NULL", userID).
 		Scan(&name, &email, &created_at, &updated_at)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			responses.ErrorResponse(w, "User tidak ditemukan", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 
@@ -140,13 +177,13 @@ func FetchUser(w http.ResponseWriter, r *http.Request) {
 		created_at string
 		updated_at string
 	)
-	err := config.DB.QueryRow("SELECT * FROM users").Scan(&id, &username, &email, &password, &created_at, &updated_at)
+	err := config.DB.QueryRow("SELECT id, name, email, password, created_at, updated_at FROM users WHERE deleted_at IS NULL").Scan(&id, &username, &email, &password, &created_at, &updated_at)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			responses.ErrorResponse(w, "tidak ada data dalam tabel", http.StatusNotFound)
 			return
 		}
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 		return
 	}
 	// Membuat objek data pengguna untuk dikirim dalam respons
@@ -181,13 +218,12 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	// Mendapatkan data pengguna dari body permintaan
 	var updatedUser struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Name     string `json:"name" validate:"required,max=255"`
+		Email    string `json:"email" validate:"required,email,max=255"`
+		Password string `json:"password" validate:"required,min=8,max=72"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
-		responses.ErrorResponse(w, "Gagal membaca data pengguna dari permintaan", http.StatusBadRequest)
+	if !decodeRequest(w, r, &updatedUser) {
 		return
 	}
 
@@ -199,9 +235,13 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Memperbarui pengguna di database
-	_, err = config.DB.Exec("UPDATE users SET name=?, email=?, password=?,  updated_at=NOW()  WHERE id=?", updatedUser.Name, updatedUser.Email, hashedPassword, userID) // Mengganti userID menjadi updatedUser.Name
+	_, err = config.DB.Exec("UPDATE users SET name=?, email=?, password=?,  updated_at=NOW()  WHERE id=? AND deleted_at IS NULL", updatedUser.Name, updatedUser.Email, hashedPassword, userID) // Mengganti userID menjadi updatedUser.Name
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		if isDuplicateEntry(err) {
+			responses.FieldErrorResponse(w, "email", "Email sudah digunakan. Silakan gunakan email lain.")
+			return
+		}
+		responses.InternalError(w, err)
 		return
 	}
 	// Membuat objek data pengguna untuk dikirim dalam respons
@@ -232,13 +272,23 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Menghapus pengguna dari database
-	_, err := config.DB.Exec("DELETE FROM users WHERE id=?", userID)
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID pengguna tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	// Pengguna hanya ditandai terhapus karena masih direferensikan oleh order dan stocktake
+	deleted, err := softDeleteRow(config.DB, "users", id)
 	if err != nil {
-		responses.ErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		responses.InternalError(w, err)
 
 		return
 	}
+	if !deleted {
+		responses.ErrorResponse(w, "User tidak ditemukan", http.StatusNotFound)
+		return
+	}
 
 	responses.OtherResponses(w, "Success", http.StatusCreated)
 }
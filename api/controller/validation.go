@@ -0,0 +1,307 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Aturan validasi ditulis di tag `validate` pada field DTO request, dipisahkan koma:
+//
+//	required     wajib diisi: string tidak kosong, pointer tidak nil, slice tidak kosong, angka bukan 0
+//	email        alamat email yang valid
+//	min=N, max=N panjang string, jumlah item slice, atau nilai angka
+//	positive     angka lebih dari 0
+//	oneof=a b    salah satu dari nilai yang dipisahkan spasi
+//	exists=tabel ID ada di tabel tersebut (dan belum dihapus)
+//	dive         aturan sesudahnya berlaku untuk setiap item slice, misalnya "dive,exists=products"
+//	unless=f     aturan field dilewati jika field f di struct yang sama diisi, misalnya qty pada
+//	             baris dengan barcode timbangan
+//
+// Field yang tidak dikirim (nil, string atau slice kosong) dan tidak required dilewati. Struct,
+// pointer ke struct dan slice struct di dalam DTO ikut divalidasi, dengan nama field seperti
+// products[0].qty.
+
+// existsQueries adalah tabel yang boleh dipakai aturan exists beserta query pemeriksaannya.
+var existsQueries = map[string]string{
+	"categories":      "SELECT COUNT(*) FROM categories WHERE id = ? AND deleted_at IS NULL",
+	"products":        "SELECT COUNT(*) FROM products WHERE id = ? AND deleted_at IS NULL",
+	"payments":        "SELECT COUNT(*) FROM payments WHERE id = ? AND deleted_at IS NULL",
+	"users":           "SELECT COUNT(*) FROM users WHERE id = ? AND deleted_at IS NULL",
+	"stores":          "SELECT COUNT(*) FROM stores WHERE id = ?",
+	"suppliers":       "SELECT COUNT(*) FROM suppliers WHERE id = ?",
+	"modifier_groups": "SELECT COUNT(*) FROM modifier_groups WHERE id = ?",
+}
+
+// decodeRequest membaca body JSON ke dst lalu menjalankan aturan validasinya. Jika gagal,
+// respons error sudah ditulis dan false dikembalikan.
+func decodeRequest(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		errorMessage := fmt.Sprintf("Gagal membaca data dari permintaan: %v", err)
+		responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+		return false
+	}
+	return validateRequest(w, dst)
+}
+
+// validateRequest menjalankan aturan validasi DTO yang sudah terisi, misalnya dari form.
+// Semua kesalahan dilaporkan sekaligus sebagai kesalahan per field.
+func validateRequest(w http.ResponseWriter, dto interface{}) bool {
+	fields, err := validateStruct(dto)
+	if err != nil {
+		responses.InternalError(w, err)
+		return false
+	}
+	if len(fields) > 0 {
+		responses.ValidationErrorResponse(w, fields)
+		return false
+	}
+	return true
+}
+
+// validateStruct mengembalikan kesalahan validasi untuk dto (struct atau pointer ke struct).
+func validateStruct(dto interface{}) ([]responses.FieldError, error) {
+	fields := []responses.FieldError{}
+	err := validateValue(reflect.ValueOf(dto), "", &fields)
+	return fields, err
+}
+
+func validateValue(value reflect.Value, path string, fields *[]responses.FieldError) error {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		valueType := value.Type()
+		for i := 0; i < valueType.NumField(); i++ {
+			field := valueType.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := fieldName(field)
+			if path != "" {
+				name = path + "." + name
+			}
+
+			rules, elementRules, _ := strings.Cut(field.Tag.Get("validate"), ",dive")
+			if other, rest, ok := cutUnless(rules); ok {
+				rules = rest
+				if sibling, found := fieldByName(value, other); found && !isEmptyValue(sibling) {
+					rules, elementRules = "", ""
+				}
+			}
+			elementRules = strings.TrimPrefix(elementRules, ",")
+			if strings.HasPrefix(rules, "dive") {
+				rules, elementRules = "", strings.TrimPrefix(strings.TrimPrefix(rules, "dive"), ",")
+			}
+			if rules != "" {
+				fieldErr, err := checkRules(value.Field(i), name, rules)
+				if err != nil {
+					return err
+				}
+				if fieldErr != nil {
+					*fields = append(*fields, *fieldErr)
+					continue
+				}
+			}
+			// aturan setelah dive berlaku untuk setiap item slice
+			if elementRules != "" && value.Field(i).Kind() == reflect.Slice {
+				for j := 0; j < value.Field(i).Len(); j++ {
+					fieldErr, err := checkRules(value.Field(i).Index(j), fmt.Sprintf("%s[%d]", name, j), elementRules)
+					if err != nil {
+						return err
+					}
+					if fieldErr != nil {
+						*fields = append(*fields, *fieldErr)
+					}
+				}
+			}
+			if err := validateValue(value.Field(i), name, fields); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := validateValue(value.Index(i), fmt.Sprintf("%s[%d]", path, i), fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fieldName mengambil nama field dari tag json atau form, sama seperti yang dikirim klien.
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		if name := strings.Split(field.Tag.Get(key), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// cutUnless memisahkan aturan unless=f di awal daftar aturan dari aturan lainnya.
+func cutUnless(rules string) (string, string, bool) {
+	if !strings.HasPrefix(rules, "unless=") {
+		return "", rules, false
+	}
+	other, rest, _ := strings.Cut(strings.TrimPrefix(rules, "unless="), ",")
+	return other, rest, true
+}
+
+// fieldByName mencari field struct berdasarkan nama json atau form-nya.
+func fieldByName(value reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).PkgPath == "" && fieldName(value.Type().Field(i)) == name {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// checkRules memeriksa aturan satu field dan mengembalikan kesalahan pertama yang ditemukan.
+func checkRules(value reflect.Value, name string, rules string) (*responses.FieldError, error) {
+	fail := func(code string, format string, args ...interface{}) (*responses.FieldError, error) {
+		return &responses.FieldError{Field: name, Code: code, Message: name + " " + fmt.Sprintf(format, args...)}, nil
+	}
+
+	ruleList := strings.Split(rules, ",")
+	for _, rule := range ruleList {
+		if rule == "required" && isEmptyValue(value) {
+			return fail("required", "harus diisi")
+		}
+	}
+	// field yang tidak dikirim tidak diperiksa lebih lanjut; angka 0 tetap diperiksa
+	if isAbsent(value) {
+		return nil, nil
+	}
+
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	for _, rule := range ruleList {
+		ruleName, param, _ := strings.Cut(rule, "=")
+		switch ruleName {
+		case "required":
+
+		case "email":
+			address, err := mail.ParseAddress(value.String())
+			if err != nil || address.Address != value.String() {
+				return fail("email", "harus berupa alamat email yang valid")
+			}
+
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return nil, fmt.Errorf("aturan %s pada %s tidak valid", rule, name)
+			}
+			size, unit := measure(value)
+			if ruleName == "min" && size < limit {
+				return fail("min", "minimal %s%s", param, unit)
+			}
+			if ruleName == "max" && size > limit {
+				return fail("max", "maksimal %s%s", param, unit)
+			}
+
+		case "positive":
+			if size, _ := measure(value); size <= 0 {
+				return fail("positive", "harus lebih dari 0")
+			}
+
+		case "oneof":
+			options := strings.Fields(param)
+			found := false
+			for _, option := range options {
+				if fmt.Sprint(value.Interface()) == option {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fail("oneof", "harus salah satu dari %s", strings.Join(options, ", "))
+			}
+
+		case "exists":
+			query, ok := existsQueries[param]
+			if !ok {
+				return nil, fmt.Errorf("tabel %s pada aturan exists tidak dikenal", param)
+			}
+			var count int
+			if err := config.DB.QueryRow(query, value.Interface()).Scan(&count); err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return fail("exists", "tidak ditemukan")
+			}
+
+		default:
+			return nil, fmt.Errorf("aturan validasi %s tidak dikenal", ruleName)
+		}
+	}
+	return nil, nil
+}
+
+// isEmptyValue memeriksa apakah field tidak diisi: pointer nil, string kosong (setelah spasi
+// dibuang), slice kosong atau angka 0.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	}
+	return false
+}
+
+// isAbsent memeriksa apakah field tidak dikirim: pointer nil, string kosong atau slice kosong.
+// Pointer ke string kosong juga dianggap tidak dikirim karena dipakai untuk menghapus nilai.
+func isAbsent(value reflect.Value) bool {
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return isEmptyValue(value)
+	}
+	return false
+}
+
+// measure mengembalikan ukuran yang dibandingkan oleh min/max beserta satuannya di pesan error:
+// panjang string, jumlah item slice, atau nilai angka.
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " karakter"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), " item"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
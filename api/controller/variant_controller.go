@@ -0,0 +1,581 @@
+package controller
+
+import (
+	"database/sql"
+	"fmt"
+	"golang-api/api/responses"
+	"golang-api/config"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// variantOption adalah pasangan opsi dan nilai yang dimiliki sebuah varian, misalnya Ukuran: M.
+type variantOption struct {
+	OptionID int64  `json:"option_id"`
+	Option   string `json:"option"`
+	ValueID  int64  `json:"value_id"`
+	Value    string `json:"value"`
+}
+
+// productVariant adalah varian produk. Varian disimpan sebagai baris products dengan parent_id
+// sehingga memiliki SKU, barcode, harga dan stok sendiri.
+type productVariant struct {
+	ID       int64           `json:"id"`
+	ParentID int64           `json:"parent_id"`
+	SKU      string          `json:"sku"`
+	Barcode  *string         `json:"barcode"`
+	Name     string          `json:"name"`
+	Stock    string          `json:"stock"`
+	Price    string          `json:"price"`
+	Options  []variantOption `json:"options"`
+}
+
+// loadVariants mengambil varian dari produk induk yang diberikan, dikelompokkan per produk induk.
+// Jika storeID diisi, stok dan harga diambil dari store tersebut.
+func loadVariants(parentIDs []int64, storeID *int64) (map[int64][]productVariant, error) {
+	variants := map[int64][]productVariant{}
+	if len(parentIDs) == 0 {
+		return variants, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(parentIDs)), ",")
+	args := []interface{}{}
+
+	query := "SELECT p.id, p.parent_id, p.sku, p.barcode, p.name, p.stock, p.price FROM products p WHERE p.parent_id IN (" + placeholders + ") AND p.deleted_at IS NULL ORDER BY p.id"
+	if storeID != nil {
+		query = `
+		    SELECT p.id, p.parent_id, p.sku, p.barcode, p.name, ` + qtyText("COALESCE(ps.stock, 0)") + `, COALESCE(ps.price, p.price)
+		    FROM products p
+		    LEFT JOIN product_stocks ps ON ps.product_id = p.id AND ps.store_id = ?
+		    WHERE p.parent_id IN (` + placeholders + `) AND p.deleted_at IS NULL ORDER BY p.id`
+		args = append(args, *storeID)
+	}
+	for _, id := range parentIDs {
+		args = append(args, id)
+	}
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// posisi setiap varian di map variants, dipakai saat mengisi opsi varian
+	type variantPosition struct {
+		ParentID int64
+		Index    int
+	}
+
+	var variantIDs []interface{}
+	positions := map[int64]variantPosition{}
+	for rows.Next() {
+		var variant productVariant
+		if err := rows.Scan(&variant.ID, &variant.ParentID, &variant.SKU, &variant.Barcode, &variant.Name, &variant.Stock, &variant.Price); err != nil {
+			return nil, err
+		}
+		variant.Options = []variantOption{}
+		positions[variant.ID] = variantPosition{ParentID: variant.ParentID, Index: len(variants[variant.ParentID])}
+		variants[variant.ParentID] = append(variants[variant.ParentID], variant)
+		variantIDs = append(variantIDs, variant.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(variantIDs) == 0 {
+		return variants, nil
+	}
+
+	optionRows, err := config.DB.Query(`
+	    SELECT vv.variant_id, o.id, o.name, ov.id, ov.value
+	    FROM product_variant_values vv
+	    JOIN product_option_values ov ON vv.option_value_id = ov.id
+	    JOIN product_options o ON ov.option_id = o.id
+	    WHERE vv.variant_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(variantIDs)), ",")+`)
+	    ORDER BY o.id`, variantIDs...)
+	if err != nil {
+		return nil, err
+	}
+	defer optionRows.Close()
+
+	for optionRows.Next() {
+		var variantID int64
+		var option variantOption
+		if err := optionRows.Scan(&variantID, &option.OptionID, &option.Option, &option.ValueID, &option.Value); err != nil {
+			return nil, err
+		}
+		position := positions[variantID]
+		variant := &variants[position.ParentID][position.Index]
+		variant.Options = append(variant.Options, option)
+	}
+
+	return variants, optionRows.Err()
+}
+
+// CreateProductOptions menambahkan jenis opsi beserta nilainya ke produk induk.
+func CreateProductOptions(w http.ResponseWriter, r *http.Request) {
+	type OptionValue struct {
+		ID    int64  `json:"id"`
+		Value string `json:"value"`
+	}
+
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		Name   string   `json:"name" validate:"required,max=255"`
+		Values []string `json:"values" validate:"required,dive,required,max=255"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+
+	// opsi hanya boleh ditambahkan ke produk induk, bukan ke varian
+	var parentID sql.NullInt64
+	err = config.DB.QueryRow("SELECT parent_id FROM products WHERE id = ? AND deleted_at IS NULL", productID).Scan(&parentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+	if parentID.Valid {
+		responses.ErrorResponse(w, "Opsi tidak dapat ditambahkan ke varian", http.StatusBadRequest)
+		return
+	}
+
+	currentTime := time.Now()
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO product_options (product_id, name, created_at, updated_at) VALUES (?, ?, ?, ?)",
+		productID, request.Name, currentTime, currentTime)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			responses.ErrorResponse(w, "Nama opsi "+request.Name+" sudah dipakai", http.StatusConflict)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	optionID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID opsi yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	var values []OptionValue
+	for _, value := range request.Values {
+		value = strings.TrimSpace(value)
+
+		valueResult, err := tx.Exec("INSERT INTO product_option_values (option_id, value, created_at, updated_at) VALUES (?, ?, ?, ?)",
+			optionID, value, currentTime, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Nilai opsi %s duplikat: %v", value, err)
+			responses.ErrorResponse(w, errorMessage, http.StatusConflict)
+			return
+		}
+
+		valueID, err := valueResult.LastInsertId()
+		if err != nil {
+			responses.ErrorResponse(w, "Gagal mendapatkan ID nilai opsi yang baru", http.StatusInternalServerError)
+			return
+		}
+		values = append(values, OptionValue{ID: valueID, Value: value})
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	responseData := struct {
+		ID        int64         `json:"id"`
+		ProductID int64         `json:"product_id"`
+		Name      string        `json:"name"`
+		Values    []OptionValue `json:"values"`
+	}{
+		ID:        optionID,
+		ProductID: productID,
+		Name:      request.Name,
+		Values:    values,
+	}
+
+	responses.SuccessResponse(w, "Success", responseData, http.StatusCreated)
+}
+
+// ListProductOptions menampilkan opsi produk induk beserta nilainya.
+func ListProductOptions(w http.ResponseWriter, r *http.Request) {
+	type OptionValue struct {
+		ID    int64  `json:"id"`
+		Value string `json:"value"`
+	}
+
+	type Option struct {
+		ID     int64         `json:"id"`
+		Name   string        `json:"name"`
+		Values []OptionValue `json:"values"`
+	}
+
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := config.DB.Query(`
+	    SELECT o.id, o.name, ov.id, ov.value
+	    FROM product_options o
+	    LEFT JOIN product_option_values ov ON ov.option_id = o.id
+	    WHERE o.product_id = ?
+	    ORDER BY o.id, ov.id`, productID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer rows.Close()
+
+	var options []Option
+	for rows.Next() {
+		var optionID int64
+		var optionName string
+		var valueID sql.NullInt64
+		var value sql.NullString
+		if err := rows.Scan(&optionID, &optionName, &valueID, &value); err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+
+		if len(options) == 0 || options[len(options)-1].ID != optionID {
+			options = append(options, Option{ID: optionID, Name: optionName, Values: []OptionValue{}})
+		}
+		if valueID.Valid {
+			last := &options[len(options)-1]
+			last.Values = append(last.Values, OptionValue{ID: valueID.Int64, Value: value.String})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", options, http.StatusOK)
+}
+
+// CreateProductVariants membuat varian baru dari produk induk. Setiap varian harus memilih
+// tepat satu nilai untuk setiap opsi produk induk, dan kombinasinya tidak boleh sama dengan
+// varian lain.
+func CreateProductVariants(w http.ResponseWriter, r *http.Request) {
+	parentID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	var request struct {
+		SKU            string  `json:"sku" validate:"max=255"`
+		Barcode        *string `json:"barcode"`
+		Price          *int    `json:"price" validate:"min=0"`
+		Stock          float64 `json:"stock" validate:"min=0"`
+		OptionValueIDs []int64 `json:"option_value_ids" validate:"required"`
+	}
+	if !decodeRequest(w, r, &request) {
+		return
+	}
+
+	if request.Barcode != nil {
+		if !validBarcode(*request.Barcode) {
+			responses.ErrorResponse(w, "Barcode tidak valid", http.StatusBadRequest)
+			return
+		}
+		taken, err := barcodeTaken(*request.Barcode, 0)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+		if taken {
+			responses.ErrorResponse(w, "Barcode "+*request.Barcode+" sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+	}
+
+	var parent struct {
+		Name         string
+		SKU          string
+		Price        string
+		Image        string
+		CategoryID   sql.NullInt64
+		ParentID     sql.NullInt64
+		Unit         string
+		QtyPrecision int
+		Type         string
+	}
+	err = config.DB.QueryRow("SELECT name, sku, price, image, category_id, parent_id, unit, qty_precision, type FROM products WHERE id = ? AND deleted_at IS NULL", parentID).
+		Scan(&parent.Name, &parent.SKU, &parent.Price, &parent.Image, &parent.CategoryID, &parent.ParentID, &parent.Unit, &parent.QtyPrecision, &parent.Type)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responses.ErrorResponse(w, "Produk tidak ditemukan", http.StatusNotFound)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+	if parent.ParentID.Valid {
+		responses.ErrorResponse(w, "Varian tidak dapat memiliki varian", http.StatusBadRequest)
+		return
+	}
+	if parent.Type == productTypeComposite {
+		responses.ErrorResponse(w, "Produk komposit tidak dapat memiliki varian", http.StatusBadRequest)
+		return
+	}
+	if !validQtyPrecision(request.Stock, parent.QtyPrecision) {
+		responses.ErrorResponse(w, fmt.Sprintf("Stok maksimal %d angka desimal", parent.QtyPrecision), http.StatusBadRequest)
+		return
+	}
+
+	// Ambil semua opsi produk induk untuk memastikan setiap opsi dipilih tepat satu kali
+	rows, err := config.DB.Query(`
+	    SELECT ov.id, ov.option_id, ov.value
+	    FROM product_option_values ov
+	    JOIN product_options o ON ov.option_id = o.id
+	    WHERE o.product_id = ?`, parentID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	valueOption := map[int64]int64{}
+	valueName := map[int64]string{}
+	options := map[int64]bool{}
+	for rows.Next() {
+		var valueID, optionID int64
+		var value string
+		if err := rows.Scan(&valueID, &optionID, &value); err != nil {
+			rows.Close()
+			responses.InternalError(w, err)
+			return
+		}
+		valueOption[valueID] = optionID
+		valueName[valueID] = value
+		options[optionID] = true
+	}
+	rows.Close()
+
+	if len(options) == 0 {
+		responses.ErrorResponse(w, "Produk belum memiliki opsi varian", http.StatusBadRequest)
+		return
+	}
+
+	chosen := map[int64]bool{}
+	for _, valueID := range request.OptionValueIDs {
+		optionID, ok := valueOption[valueID]
+		if !ok {
+			errorMessage := fmt.Sprintf("Nilai opsi dengan ID %d bukan milik produk ini", valueID)
+			responses.ErrorResponse(w, errorMessage, http.StatusBadRequest)
+			return
+		}
+		if chosen[optionID] {
+			responses.ErrorResponse(w, "Setiap opsi hanya boleh dipilih satu nilai", http.StatusBadRequest)
+			return
+		}
+		chosen[optionID] = true
+	}
+	if len(chosen) != len(options) {
+		responses.ErrorResponse(w, "Setiap opsi produk harus dipilih satu nilai", http.StatusBadRequest)
+		return
+	}
+
+	// Kombinasi nilai opsi yang sama tidak boleh dipakai dua varian
+	sort.Slice(request.OptionValueIDs, func(i, j int) bool { return request.OptionValueIDs[i] < request.OptionValueIDs[j] })
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(request.OptionValueIDs)), ",")
+	args := []interface{}{parentID}
+	for _, valueID := range request.OptionValueIDs {
+		args = append(args, valueID)
+	}
+	args = append(args, len(request.OptionValueIDs))
+
+	var duplicates int
+	err = config.DB.QueryRow(`
+	    SELECT COUNT(*) FROM (
+	        SELECT vv.variant_id
+	        FROM product_variant_values vv
+	        JOIN products v ON vv.variant_id = v.id
+	        WHERE v.parent_id = ? AND vv.option_value_id IN (`+placeholders+`)
+	        GROUP BY vv.variant_id
+	        HAVING COUNT(*) = ?
+	    ) matched`, args...).Scan(&duplicates)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	if duplicates > 0 {
+		responses.ErrorResponse(w, "Varian dengan kombinasi opsi tersebut sudah ada", http.StatusConflict)
+		return
+	}
+
+	// stok awal varian dicatat di store yang aktif
+	var storeID int64
+	if request.Stock > 0 {
+		storeID, err = requireStore(r)
+		if err != nil {
+			badRequest(w, err)
+			return
+		}
+	}
+
+	var valueNames []string
+	for _, valueID := range request.OptionValueIDs {
+		valueNames = append(valueNames, valueName[valueID])
+	}
+	name := parent.Name + " - " + strings.Join(valueNames, " / ")
+
+	price := parent.Price
+	if request.Price != nil {
+		price = strconv.Itoa(*request.Price)
+	}
+
+	var categoryID *int64
+	if parent.CategoryID.Valid {
+		categoryID = &parent.CategoryID.Int64
+	}
+
+	currentTime := time.Now()
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	defer tx.Rollback()
+
+	// varian mengikuti satuan jual produk induknya
+	result, err := tx.Exec("INSERT INTO products (parent_id, category_id, name, sku, barcode, unit, qty_precision, price, stock, image, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)",
+		parentID, categoryID, name, request.SKU, request.Barcode, parent.Unit, parent.QtyPrecision, price, parent.Image, currentTime, currentTime)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			responses.ErrorResponse(w, "Barcode varian sudah dipakai produk lain", http.StatusConflict)
+			return
+		}
+		responses.InternalError(w, err)
+		return
+	}
+
+	variantID, err := result.LastInsertId()
+	if err != nil {
+		responses.ErrorResponse(w, "Gagal mendapatkan ID varian yang baru", http.StatusInternalServerError)
+		return
+	}
+
+	if priceInt, err := strconv.Atoi(price); err == nil {
+		err = recordPriceHistory(tx, priceHistoryEntry{
+			ProductID: variantID,
+			Price:     &priceInt,
+			Source:    priceSourceCreate,
+			UserID:    currentUserID(r),
+		})
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	// SKU default mengikuti SKU induk dan ID varian
+	if request.SKU == "" {
+		request.SKU = fmt.Sprintf("%s-%d", parent.SKU, variantID)
+		_, err = tx.Exec("UPDATE products SET sku = ? WHERE id = ?", request.SKU, variantID)
+		if err != nil {
+			responses.InternalError(w, err)
+			return
+		}
+	}
+
+	for _, valueID := range request.OptionValueIDs {
+		_, err := tx.Exec("INSERT INTO product_variant_values (variant_id, option_value_id, created_at) VALUES (?, ?, ?)", variantID, valueID, currentTime)
+		if err != nil {
+			errorMessage := fmt.Sprintf("Gagal menyimpan nilai opsi varian: %v", err)
+			responses.ErrorResponse(w, errorMessage, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if request.Stock > 0 {
+		err = postStockMovement(tx, stockMovement{
+			StoreID:   storeID,
+			ProductID: variantID,
+			Type:      movementInitialStock,
+			Qty:       request.Stock,
+			Note:      "Stok awal",
+		})
+		if err != nil {
+			responses.ErrorResponse(w, "Gagal menyimpan stok awal varian", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	variants, err := loadVariants([]int64{parentID}, nil)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+	for _, variant := range variants[parentID] {
+		if variant.ID == variantID {
+			responses.SuccessResponse(w, "Success", variant, http.StatusCreated)
+			return
+		}
+	}
+
+	responses.ErrorResponse(w, "Varian tidak ditemukan", http.StatusInternalServerError)
+}
+
+// ListProductVariants menampilkan semua varian dari produk induk.
+func ListProductVariants(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		responses.ErrorResponse(w, "ID produk tidak valid", http.StatusBadRequest)
+		return
+	}
+
+	storeID, err := storeScope(r)
+	if err != nil {
+		badRequest(w, err)
+		return
+	}
+
+	variants, err := loadVariants([]int64{productID}, storeID)
+	if err != nil {
+		responses.InternalError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": map[string]interface{}{
+			"meta": map[string]interface{}{
+				"total": len(variants[productID]),
+			},
+			"variants": variants[productID],
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	responses.SuccessResponse(w, "Success", response, http.StatusOK)
+}
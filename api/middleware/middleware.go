@@ -1,15 +1,42 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"golang-api/api/responses"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+// UserClaims adalah klaim yang disimpan di dalam token JWT saat login.
+// HeadOffice menandai pengguna kantor pusat yang tidak terikat ke satu store; pengguna lain
+// selalu memiliki StoreId.
+type UserClaims struct {
+	Username   string `json:"username"`
+	UserId     int    `json:"user_id"`
+	StoreId    *int64 `json:"store_id"`
+	HeadOffice bool   `json:"head_office"`
+	jwt.StandardClaims
+}
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// CurrentUser mengembalikan klaim pengguna yang sudah diverifikasi oleh AuthMiddleware,
+// atau nil jika request tidak melewati middleware.
+func CurrentUser(r *http.Request) *UserClaims {
+	claims, _ := r.Context().Value(userContextKey).(*UserClaims)
+	return claims
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
@@ -25,23 +52,70 @@ func AuthMiddleware(next http.Handler) http.Handler {
 		secretKey := []byte(secretKeyString)
 
 		// Parse token dengan kunci rahasia
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
 			// Periksa metode tanda tangan token
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				errorMessage := fmt.Sprintf("Unexpected signing method: %v", token.Header["alg"])
-				responses.ErrorResponse(w, errorMessage, http.StatusUnauthorized)
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
 			return secretKey, nil
 		})
 
 		if err != nil || !token.Valid {
-			errorMessage := fmt.Sprintf("Unauthorized: %v", err)
-			responses.ErrorResponse(w, errorMessage, http.StatusUnauthorized)
+			// token kedaluwarsa diberi kode sendiri agar klien tahu harus login ulang
+			var validationErr *jwt.ValidationError
+			if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+				responses.CodedErrorResponse(w, responses.CodeTokenExpired, "Unauthorized: Token sudah kedaluwarsa", http.StatusUnauthorized)
+				return
+			}
+			responses.ErrorResponse(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		claims, ok := token.Claims.(*UserClaims)
+		if !ok {
+			responses.ErrorResponse(w, "Unauthorized: Invalid token claims", http.StatusUnauthorized)
+			return
+		}
+
+		// token lama tanpa store maupun tanda kantor pusat tidak lagi diberi akses ke semua store
+		if claims.StoreId == nil && !claims.HeadOffice {
+			responses.ErrorResponse(w, "Unauthorized: Token tidak terhubung ke store, silakan login ulang", http.StatusUnauthorized)
 			return
 		}
 
-		// Token valid, lanjutkan ke handler berikutnya
+		// Token valid, simpan klaim di context lalu lanjutkan ke handler berikutnya
+		ctx := context.WithValue(r.Context(), userContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// OptionalAuth memverifikasi token seperti AuthMiddleware jika header Authorization dikirim,
+// dan meneruskan request tanpa klaim jika tidak.
+func OptionalAuth(next http.Handler) http.Handler {
+	authenticated := AuthMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+// requestIDPattern membatasi request id dari klien agar aman ditulis ke log dan header.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID memberi setiap request sebuah id yang dikirim di header X-Request-ID dan disertakan
+// di setiap respons error. Id dari klien dipakai ulang jika formatnya valid.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(responses.RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			raw := make([]byte, 16)
+			rand.Read(raw)
+			requestID = hex.EncodeToString(raw)
+		}
+		w.Header().Set(responses.RequestIDHeader, requestID)
 		next.ServeHTTP(w, r)
 	})
 }
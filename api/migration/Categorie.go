@@ -48,3 +48,14 @@ func CategorieMigrate(db *sql.DB) {
 	// Pesan sukses jika migrasi berhasil
 	log.Println("Migrasi tabel berhasil")
 }
+
+// CategorieColumnMigrate menambahkan kolom baru pada tabel categories.
+func CategorieColumnMigrate(db *sql.DB) {
+	// kategori bersarang: parent_id menunjuk ke kategori induk, NULL untuk kategori teratas
+	if addColumn(db, "categories", "parent_id", "INT NULL") {
+		_, err := db.Exec("ALTER TABLE categories ADD FOREIGN KEY (parent_id) REFERENCES categories(id)")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+)
+
+// addColumn menambahkan kolom ke tabel yang sudah ada jika kolom tersebut belum ada.
+// Mengembalikan true jika kolom baru saja ditambahkan.
+func addColumn(db *sql.DB, table string, column string, definition string) bool {
+	// SQL statement untuk memeriksa apakah kolom sudah ada
+	checkColumnSQL := `
+		SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+	  `
+
+	var columnCount int
+	err := db.QueryRow(checkColumnSQL, table, column).Scan(&columnCount)
+	if err != nil {
+		log.Fatal(err)
+		return false
+	}
+
+	if columnCount > 0 {
+		return false
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition)
+	if err != nil {
+		log.Fatal(err)
+		return false
+	}
+
+	log.Printf("Migrasi kolom %s.%s berhasil\n", table, column)
+	return true
+}
+
+// modifyColumn mengubah definisi kolom yang sudah ada jika tipe datanya belum sama dengan dataType.
+// Mengembalikan true jika kolom baru saja diubah.
+func modifyColumn(db *sql.DB, table string, column string, dataType string, definition string) bool {
+	var currentType string
+	err := db.QueryRow(`
+		SELECT data_type FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?
+	  `, table, column).Scan(&currentType)
+	if err != nil {
+		log.Fatal(err)
+		return false
+	}
+
+	if strings.EqualFold(currentType, dataType) {
+		return false
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " MODIFY COLUMN " + column + " " + definition)
+	if err != nil {
+		log.Fatal(err)
+		return false
+	}
+
+	log.Printf("Migrasi tipe kolom %s.%s berhasil\n", table, column)
+	return true
+}
+
+// addIndex menambahkan index ke tabel yang sudah ada jika index dengan nama tersebut belum ada.
+// definition adalah bagian setelah ALTER TABLE ... ADD, misalnya "INDEX idx_nama (kolom)".
+// Mengembalikan true jika index baru saja ditambahkan.
+func addIndex(db *sql.DB, table string, index string, definition string) bool {
+	var indexCount int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?
+	  `, table, index).Scan(&indexCount)
+	if err != nil {
+		log.Fatal(err)
+		return false
+	}
+
+	if indexCount > 0 {
+		return false
+	}
+
+	_, err = db.Exec("ALTER TABLE " + table + " ADD " + definition)
+	if err != nil {
+		log.Fatal(err)
+		return false
+	}
+
+	log.Printf("Migrasi index %s.%s berhasil\n", table, index)
+	return true
+}
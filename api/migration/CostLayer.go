@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// CostLayerMigration digunakan untuk menjalankan migrasi tabel.
+func CostLayerMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel cost_layers sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'cost_layers'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel cost_layers
+	// setiap stok masuk menjadi satu lapisan biaya per store; qty_remaining berkurang saat stok
+	// keluar dengan urutan FIFO (lapisan terlama lebih dulu)
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS cost_layers (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            store_id INT NOT NULL,
+            product_id INT NOT NULL,
+            qty DECIMAL(15,3) NOT NULL,
+            qty_remaining DECIMAL(15,3) NOT NULL,
+            unit_cost DECIMAL(15,2) NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            INDEX idx_cost_layers_open (store_id, product_id, qty_remaining),
+            FOREIGN KEY (store_id) REFERENCES stores(id),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Stok yang sudah ada menjadi lapisan pembuka dengan cost_price produk saat ini
+	_, err = db.Exec(`
+        INSERT INTO cost_layers (store_id, product_id, qty, qty_remaining, unit_cost, created_at)
+        SELECT ps.store_id, ps.product_id, ps.stock, ps.stock, p.cost_price, NOW()
+        FROM product_stocks ps JOIN products p ON ps.product_id = p.id
+        WHERE ps.stock > 0
+    `)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ModifierMigration digunakan untuk menjalankan migrasi tabel.
+func ModifierMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel modifiers sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'modifiers'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel modifiers
+	// pilihan dalam kelompok modifier beserta tambahan harganya, misalnya Extra shot +5000
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS modifiers (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            modifier_group_id INT NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            price_delta INT NOT NULL DEFAULT 0,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_modifiers_name (modifier_group_id, name),
+            FOREIGN KEY (modifier_group_id) REFERENCES modifier_groups(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,56 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ModifierGroupLinkMigration digunakan untuk menjalankan migrasi tabel.
+func ModifierGroupLinkMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel modifier_group_links sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'modifier_group_links'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel modifier_group_links
+	// kelompok modifier berlaku untuk produk tertentu atau semua produk dalam sebuah kategori
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS modifier_group_links (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            modifier_group_id INT NOT NULL,
+            product_id INT NULL,
+            category_id INT NULL,
+            created_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_modifier_group_links_product (modifier_group_id, product_id),
+            UNIQUE KEY uq_modifier_group_links_category (modifier_group_id, category_id),
+            FOREIGN KEY (modifier_group_id) REFERENCES modifier_groups(id),
+            FOREIGN KEY (product_id) REFERENCES products(id),
+            FOREIGN KEY (category_id) REFERENCES categories(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// NotificationMigration digunakan untuk menjalankan migrasi tabel.
+func NotificationMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel notifications sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'notifications'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel notifications
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS notifications (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            type VARCHAR(50) NOT NULL,
+            product_id INT NULL,
+            message VARCHAR(1000) NOT NULL,
+            read_at TIMESTAMP NULL,
+            created_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -57,3 +57,10 @@ func OrderMigration(db *sql.DB) {
 	// Pesan sukses jika migrasi berhasil
 	log.Println("Migrasi tabel berhasil")
 }
+
+// OrderColumnMigrate menambahkan kolom baru pada tabel orders.
+func OrderColumnMigrate(db *sql.DB) {
+	// semua order yang tersimpan adalah penjualan yang sudah selesai
+	addColumn(db, "orders", "status", "VARCHAR(20) NOT NULL DEFAULT 'completed'")
+	addColumn(db, "orders", "customer_name", "VARCHAR(255) NULL")
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// OrderProductModifierMigration digunakan untuk menjalankan migrasi tabel.
+func OrderProductModifierMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel order_product_modifiers sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'order_product_modifiers'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel order_product_modifiers
+	// modifier yang dipilih pada baris order, nama dan harga disalin agar struk tidak berubah
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS order_product_modifiers (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            order_product_id INT NOT NULL,
+            modifier_id INT NOT NULL,
+            group_name VARCHAR(255) NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            price_delta INT NOT NULL DEFAULT 0,
+            created_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (order_product_id) REFERENCES order_products(id),
+            FOREIGN KEY (modifier_id) REFERENCES modifiers(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -52,3 +52,36 @@ func OrderProductMigration(db *sql.DB) {
 	// Pesan sukses jika migrasi berhasil
 	log.Println("Migrasi tabel berhasil")
 }
+
+// OrderProductColumnMigrate menambahkan snapshot produk pada baris order. Nama, SKU, satuan dan
+// harga disalin saat penjualan agar struk lama tidak berubah ketika produk diubah atau dihapus.
+func OrderProductColumnMigrate(db *sql.DB) {
+	addColumn(db, "order_products", "sku", "VARCHAR(255) NULL")
+	addColumn(db, "order_products", "unit", "VARCHAR(20) NULL")
+	addColumn(db, "order_products", "unit_price", "INT NULL")
+	addColumn(db, "order_products", "tax", "INT NOT NULL DEFAULT 0")
+	addColumn(db, "order_products", "discount", "INT NOT NULL DEFAULT 0")
+	addColumn(db, "order_products", "unit_cost", "DECIMAL(15,2) NULL")
+
+	// baris order lama diisi dari data produk saat ini, karena harga saat penjualan sudah tidak diketahui
+	if addColumn(db, "order_products", "product_name", "VARCHAR(255) NULL") {
+		_, err := db.Exec(`
+			UPDATE order_products op JOIN products p ON op.product_id = p.id
+			SET op.product_name = p.name, op.sku = p.sku, op.unit = p.unit, op.unit_price = CAST(p.price AS SIGNED)
+			WHERE op.product_name IS NULL`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// harga pokok order lama tidak tercatat, sehingga diperkirakan dari cost_price produk saat ini
+	if addColumn(db, "order_products", "total_cost", "DECIMAL(15,2) NULL") {
+		_, err := db.Exec(`
+			UPDATE order_products op JOIN products p ON op.product_id = p.id
+			SET op.unit_cost = p.cost_price, op.total_cost = ROUND(op.qty * p.cost_price, 2)
+			WHERE op.total_cost IS NULL`)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
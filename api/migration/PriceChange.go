@@ -0,0 +1,56 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// PriceChangeMigration digunakan untuk menjalankan migrasi tabel.
+func PriceChangeMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel price_changes sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'price_changes'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel price_changes
+	// perubahan harga massal; status: scheduled -> applied, atau cancelled sebelum berlaku
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS price_changes (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+            effective_at TIMESTAMP NOT NULL,
+            applied_at TIMESTAMP NULL,
+            user_id INT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            INDEX idx_price_changes_due (status, effective_at),
+            FOREIGN KEY (user_id) REFERENCES users(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// PriceChangeItemMigration digunakan untuk menjalankan migrasi tabel.
+func PriceChangeItemMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel price_change_items sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'price_change_items'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel price_change_items
+	// harga baru per produk; old_price adalah harga saat perubahan dibuat
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS price_change_items (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            price_change_id INT NOT NULL,
+            product_id INT NOT NULL,
+            old_price INT NOT NULL,
+            new_price INT NOT NULL,
+            FOREIGN KEY (price_change_id) REFERENCES price_changes(id),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// PriceHistoryMigration digunakan untuk menjalankan migrasi tabel.
+func PriceHistoryMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel price_histories sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'price_histories'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel price_histories
+	// setiap perubahan harga produk dicatat di sini; store_id NULL berarti harga dasar di products,
+	// selain itu harga khusus store (price NULL berarti harga khusus dihapus)
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS price_histories (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            product_id INT NOT NULL,
+            store_id INT NULL,
+            old_price INT NULL,
+            price INT NULL,
+            source VARCHAR(20) NOT NULL,
+            price_change_id INT NULL,
+            user_id INT NULL,
+            created_at TIMESTAMP NOT NULL,
+            INDEX idx_price_histories_product (product_id, store_id, created_at),
+            FOREIGN KEY (product_id) REFERENCES products(id),
+            FOREIGN KEY (store_id) REFERENCES stores(id),
+            FOREIGN KEY (user_id) REFERENCES users(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Harga yang berlaku saat ini menjadi riwayat pertama sejak harga terakhir diubah
+	_, err = db.Exec(`
+        INSERT INTO price_histories (product_id, store_id, old_price, price, source, created_at)
+        SELECT id, NULL, NULL, CAST(price AS SIGNED), 'initial', COALESCE(price_updated_at, created_at)
+        FROM products
+    `)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	_, err = db.Exec(`
+        INSERT INTO price_histories (product_id, store_id, old_price, price, source, created_at)
+        SELECT product_id, store_id, NULL, price, 'initial', COALESCE(price_updated_at, updated_at)
+        FROM product_stocks WHERE price IS NOT NULL
+    `)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
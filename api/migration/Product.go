@@ -53,3 +53,44 @@ func ProductMigrate(db *sql.DB) {
 	// Pesan sukses jika migrasi berhasil
 	log.Println("Migrasi tabel berhasil")
 }
+
+// ProductColumnMigrate menambahkan kolom baru pada tabel products.
+func ProductColumnMigrate(db *sql.DB) {
+	addColumn(db, "products", "cost_price", "DECIMAL(15,2) NOT NULL DEFAULT 0")
+	// metode harga pokok penjualan: average (rata-rata tertimbang) atau fifo
+	addColumn(db, "products", "costing_method", "VARCHAR(10) NOT NULL DEFAULT 'average'")
+	addColumn(db, "products", "reorder_point", "INT NOT NULL DEFAULT 0")
+	addColumn(db, "products", "reorder_qty", "INT NOT NULL DEFAULT 0")
+
+	// varian disimpan sebagai baris products yang menunjuk ke produk induk lewat parent_id
+	if addColumn(db, "products", "parent_id", "INT NULL") {
+		_, err := db.Exec("ALTER TABLE products ADD FOREIGN KEY (parent_id) REFERENCES products(id)")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if addColumn(db, "products", "barcode", "VARCHAR(64) NULL") {
+		_, err := db.Exec("ALTER TABLE products ADD UNIQUE KEY uq_products_barcode (barcode)")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// dipakai label rak: kapan harga terakhir berubah dan isi kemasan untuk harga satuan
+	addColumn(db, "products", "price_updated_at", "TIMESTAMP NULL")
+	addColumn(db, "products", "content_qty", "DECIMAL(15,3) NULL")
+	addColumn(db, "products", "content_unit", "VARCHAR(20) NULL")
+
+	// satuan jual dan jumlah desimal qty yang diizinkan, plu adalah kode 5 digit pada barcode timbangan
+	addColumn(db, "products", "unit", "VARCHAR(20) NOT NULL DEFAULT 'pcs'")
+	addColumn(db, "products", "qty_precision", "TINYINT NOT NULL DEFAULT 0")
+	if addColumn(db, "products", "plu", "VARCHAR(5) NULL") {
+		_, err := db.Exec("ALTER TABLE products ADD UNIQUE KEY uq_products_plu (plu)")
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// jenis produk: standard memiliki stok sendiri, composite mengurangi stok komponennya
+	addColumn(db, "products", "type", "VARCHAR(20) NOT NULL DEFAULT 'standard'")
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ProductComponentMigration digunakan untuk menjalankan migrasi tabel.
+func ProductComponentMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel product_components sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'product_components'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel product_components
+	// daftar bahan (bill of materials) produk komposit: bundel, kit atau resep
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS product_components (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            product_id INT NOT NULL,
+            component_id INT NOT NULL,
+            qty DECIMAL(15,3) NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_product_components (product_id, component_id),
+            FOREIGN KEY (product_id) REFERENCES products(id),
+            FOREIGN KEY (component_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ProductOptionMigration digunakan untuk menjalankan migrasi tabel.
+func ProductOptionMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel product_options sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'product_options'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel product_options
+	// jenis opsi varian milik produk induk, misalnya Ukuran atau Warna
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS product_options (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            product_id INT NOT NULL,
+            name VARCHAR(255) NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_product_options_name (product_id, name),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ProductOptionValueMigration digunakan untuk menjalankan migrasi tabel.
+func ProductOptionValueMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel product_option_values sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'product_option_values'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel product_option_values
+	// nilai dari sebuah opsi, misalnya S, M, L untuk opsi Ukuran
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS product_option_values (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            option_id INT NOT NULL,
+            value VARCHAR(255) NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_product_option_values_value (option_id, value),
+            FOREIGN KEY (option_id) REFERENCES product_options(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ProductTagMigration digunakan untuk menjalankan migrasi tabel.
+func ProductTagMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel product_tags sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'product_tags'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel product_tags
+	// tag bebas untuk mengelompokkan produk, misalnya "promo" atau "impor"
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS product_tags (
+            product_id INT NOT NULL,
+            tag VARCHAR(50) NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            PRIMARY KEY (product_id, tag),
+            INDEX idx_product_tags_tag (tag),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
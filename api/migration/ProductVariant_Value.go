@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ProductVariantValueMigration digunakan untuk menjalankan migrasi tabel.
+func ProductVariantValueMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel product_variant_values sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'product_variant_values'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel product_variant_values
+	// varian adalah baris products dengan parent_id, tabel ini menyimpan nilai opsi setiap varian
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS product_variant_values (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            variant_id INT NOT NULL,
+            option_value_id INT NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_product_variant_values (variant_id, option_value_id),
+            FOREIGN KEY (variant_id) REFERENCES products(id),
+            FOREIGN KEY (option_value_id) REFERENCES product_option_values(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
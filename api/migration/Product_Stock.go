@@ -0,0 +1,70 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// ProductStockMigration digunakan untuk menjalankan migrasi tabel.
+func ProductStockMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel product_stocks sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'product_stocks'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel product_stocks
+	// stok per store; price NULL berarti memakai harga di products
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS product_stocks (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            store_id INT NOT NULL,
+            product_id INT NOT NULL,
+            stock DECIMAL(15,3) NOT NULL DEFAULT 0,
+            price INT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_product_stocks_store_product (store_id, product_id),
+            FOREIGN KEY (store_id) REFERENCES stores(id),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Stok lama dipindahkan ke store pertama
+	_, err = db.Exec(`
+        INSERT INTO product_stocks (store_id, product_id, stock, updated_at)
+        SELECT (SELECT MIN(id) FROM stores), id, CAST(stock AS SIGNED), NOW() FROM products
+    `)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
+
+// ProductStockColumnMigrate menambahkan kolom baru pada tabel product_stocks.
+func ProductStockColumnMigrate(db *sql.DB) {
+	addColumn(db, "product_stocks", "price_updated_at", "TIMESTAMP NULL")
+}
@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// PurchaseOrderMigration digunakan untuk menjalankan migrasi tabel.
+func PurchaseOrderMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel purchase_orders sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'purchase_orders'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel purchase_orders
+	// status: draft -> sent -> partially_received -> received
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS purchase_orders (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            supplier_id INT NOT NULL,
+            user_id INT NULL,
+            code VARCHAR(255) NOT NULL,
+            status VARCHAR(50) NOT NULL DEFAULT 'draft',
+            notes VARCHAR(1000) NULL,
+            expected_total DECIMAL(15,2) NOT NULL DEFAULT 0,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (supplier_id) REFERENCES suppliers(id),
+            FOREIGN KEY (user_id) REFERENCES users(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
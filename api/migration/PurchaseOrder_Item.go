@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// PurchaseOrderItemMigration digunakan untuk menjalankan migrasi tabel.
+func PurchaseOrderItemMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel purchase_order_items sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'purchase_order_items'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel purchase_order_items
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS purchase_order_items (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            purchase_order_id INT NOT NULL,
+            product_id INT NOT NULL,
+            qty DECIMAL(15,3) NOT NULL,
+            qty_received DECIMAL(15,3) NOT NULL DEFAULT 0,
+            expected_cost DECIMAL(15,2) NOT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (purchase_order_id) REFERENCES purchase_orders(id),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,22 @@
+package migration
+
+import (
+	"database/sql"
+)
+
+// QuantityColumnMigrate mengubah kolom qty dan stok menjadi desimal agar produk timbangan
+// (kg, liter, meter) dapat dijual dan distok dalam pecahan.
+func QuantityColumnMigrate(db *sql.DB) {
+	modifyColumn(db, "order_products", "qty", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "product_stocks", "stock", "decimal", "DECIMAL(15,3) NOT NULL DEFAULT 0")
+	modifyColumn(db, "stock_movements", "qty", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "purchase_order_items", "qty", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "purchase_order_items", "qty_received", "decimal", "DECIMAL(15,3) NOT NULL DEFAULT 0")
+	modifyColumn(db, "stocktake_items", "system_qty", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "stocktake_items", "counted_qty", "decimal", "DECIMAL(15,3) NULL")
+	modifyColumn(db, "stock_transfer_items", "qty", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "stock_transfer_items", "qty_received", "decimal", "DECIMAL(15,3) NULL")
+	modifyColumn(db, "stock_transfer_discrepancies", "qty_sent", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "stock_transfer_discrepancies", "qty_received", "decimal", "DECIMAL(15,3) NOT NULL")
+	modifyColumn(db, "stock_transfer_discrepancies", "difference", "decimal", "DECIMAL(15,3) NOT NULL")
+}
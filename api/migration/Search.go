@@ -0,0 +1,13 @@
+package migration
+
+import (
+	"database/sql"
+)
+
+// SearchIndexMigrate menambahkan index FULLTEXT untuk pencarian produk. Parser ngram memecah teks
+// menjadi potongan dua huruf sehingga "kopisusu" tetap cocok dengan "kopi susu" dan salah ketik
+// kecil masih menemukan produk yang mirip.
+func SearchIndexMigrate(db *sql.DB) {
+	addIndex(db, "products", "ft_products_search", "FULLTEXT INDEX ft_products_search (name, sku, barcode) WITH PARSER ngram")
+	addIndex(db, "categories", "ft_categories_name", "FULLTEXT INDEX ft_categories_name (name) WITH PARSER ngram")
+}
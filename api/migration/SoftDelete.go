@@ -0,0 +1,15 @@
+package migration
+
+import (
+	"database/sql"
+)
+
+// SoftDeleteColumnMigrate menambahkan kolom deleted_at untuk soft delete. Baris yang dihapus
+// hanya ditandai agar riwayat order tetap dapat menampilkan produk, kategori, user dan payment
+// yang direferensikannya.
+func SoftDeleteColumnMigrate(db *sql.DB) {
+	addColumn(db, "products", "deleted_at", "TIMESTAMP NULL")
+	addColumn(db, "categories", "deleted_at", "TIMESTAMP NULL")
+	addColumn(db, "users", "deleted_at", "TIMESTAMP NULL")
+	addColumn(db, "payments", "deleted_at", "TIMESTAMP NULL")
+}
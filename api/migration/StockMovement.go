@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// StockMovementMigration digunakan untuk menjalankan migrasi tabel.
+func StockMovementMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel stock_movements sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stock_movements'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel stock_movements
+	// qty bernilai positif untuk stok masuk dan negatif untuk stok keluar
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS stock_movements (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            product_id INT NOT NULL,
+            type VARCHAR(50) NOT NULL,
+            qty DECIMAL(15,3) NOT NULL,
+            unit_cost DECIMAL(15,2) NULL,
+            reference_type VARCHAR(50) NULL,
+            reference_id INT NULL,
+            note VARCHAR(1000) NULL,
+            created_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (product_id) REFERENCES products(id),
+            INDEX idx_stock_movements_reference (reference_type, reference_id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
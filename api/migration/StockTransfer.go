@@ -0,0 +1,62 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// StockTransferMigration digunakan untuk menjalankan migrasi tabel.
+func StockTransferMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel stock_transfers sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stock_transfers'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel stock_transfers
+	// status: draft -> dispatched -> in_transit -> received
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS stock_transfers (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            code VARCHAR(255) NOT NULL,
+            from_store_id INT NOT NULL,
+            to_store_id INT NOT NULL,
+            status VARCHAR(50) NOT NULL DEFAULT 'draft',
+            notes VARCHAR(1000) NULL,
+            user_id INT NULL,
+            dispatched_at TIMESTAMP NULL,
+            received_by INT NULL,
+            received_at TIMESTAMP NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (from_store_id) REFERENCES stores(id),
+            FOREIGN KEY (to_store_id) REFERENCES stores(id),
+            FOREIGN KEY (user_id) REFERENCES users(id),
+            FOREIGN KEY (received_by) REFERENCES users(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// StockTransferDiscrepancyMigration digunakan untuk menjalankan migrasi tabel.
+func StockTransferDiscrepancyMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel stock_transfer_discrepancies sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stock_transfer_discrepancies'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel stock_transfer_discrepancies
+	// difference = qty_received - qty_sent, negatif berarti barang hilang di perjalanan
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS stock_transfer_discrepancies (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            stock_transfer_id INT NOT NULL,
+            stock_transfer_item_id INT NOT NULL,
+            product_id INT NOT NULL,
+            qty_sent DECIMAL(15,3) NOT NULL,
+            qty_received DECIMAL(15,3) NOT NULL,
+            difference DECIMAL(15,3) NOT NULL,
+            unit_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+            note VARCHAR(1000) NULL,
+            created_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (stock_transfer_id) REFERENCES stock_transfers(id),
+            FOREIGN KEY (stock_transfer_item_id) REFERENCES stock_transfer_items(id),
+            FOREIGN KEY (product_id) REFERENCES products(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
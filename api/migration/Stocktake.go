@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// StocktakeMigration digunakan untuk menjalankan migrasi tabel.
+func StocktakeMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel stocktakes sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stocktakes'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel stocktakes
+	// status: open -> approved atau cancelled
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS stocktakes (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            code VARCHAR(255) NOT NULL,
+            category_id INT NULL,
+            status VARCHAR(50) NOT NULL DEFAULT 'open',
+            notes VARCHAR(1000) NULL,
+            user_id INT NULL,
+            approved_by INT NULL,
+            approved_at TIMESTAMP NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            FOREIGN KEY (category_id) REFERENCES categories(id),
+            FOREIGN KEY (user_id) REFERENCES users(id),
+            FOREIGN KEY (approved_by) REFERENCES users(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
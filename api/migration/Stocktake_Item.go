@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// StocktakeItemMigration digunakan untuk menjalankan migrasi tabel.
+func StocktakeItemMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel stocktake_items sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stocktake_items'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel stocktake_items
+	// system_qty adalah stok sistem saat sesi dibuka, counted_qty NULL berarti belum dihitung
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS stocktake_items (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            stocktake_id INT NOT NULL,
+            product_id INT NOT NULL,
+            system_qty DECIMAL(15,3) NOT NULL,
+            counted_qty DECIMAL(15,3) NULL,
+            unit_cost DECIMAL(15,2) NOT NULL DEFAULT 0,
+            counted_by INT NULL,
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL,
+            UNIQUE KEY uq_stocktake_items_product (stocktake_id, product_id),
+            FOREIGN KEY (stocktake_id) REFERENCES stocktakes(id),
+            FOREIGN KEY (product_id) REFERENCES products(id),
+            FOREIGN KEY (counted_by) REFERENCES users(id)
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
@@ -0,0 +1,82 @@
+package migration
+
+import (
+	"database/sql"
+	"log"
+)
+
+// StoreMigration digunakan untuk menjalankan migrasi tabel.
+func StoreMigration(db *sql.DB) {
+	// SQL statement untuk memeriksa apakah tabel stores sudah ada
+	checkTableSQL := `
+		SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'stores'
+	  `
+
+	// Menjalankan perintah SQL untuk memeriksa apakah tabel sudah ada
+	var tableCount int
+	err := db.QueryRow(checkTableSQL).Scan(&tableCount)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat memeriksa tabel
+		log.Fatal(err)
+		return
+	}
+
+	if tableCount > 0 {
+		// Jika tabel sudah ada, tampilkan pesan
+		log.Println("Tabel sudah di migrasi")
+		return
+	}
+	// SQL statement untuk membuat tabel stores
+	createTableSQL := `
+        CREATE TABLE IF NOT EXISTS stores (
+            id INT AUTO_INCREMENT PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            address VARCHAR(1000) NULL,
+            timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Jakarta',
+            created_at TIMESTAMP NOT NULL,
+            updated_at TIMESTAMP NOT NULL
+        )
+    `
+
+	// Menjalankan perintah SQL untuk membuat tabel
+	_, err = db.Exec(createTableSQL)
+	if err != nil {
+		// Menangani kesalahan jika terjadi kesalahan saat migrasi
+		log.Fatal(err)
+		return
+	}
+
+	// Data lama dianggap milik satu toko utama
+	_, err = db.Exec("INSERT INTO stores (name, created_at, updated_at) VALUES ('Toko Utama', NOW(), NOW())")
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	// Pesan sukses jika migrasi berhasil
+	log.Println("Migrasi tabel berhasil")
+}
+
+// StoreColumnMigrate menambahkan kolom store_id pada tabel yang dibatasi per store.
+// Data yang sudah ada dipindahkan ke store pertama.
+func StoreColumnMigrate(db *sql.DB) {
+	tables := []string{"users", "orders", "stock_movements", "purchase_orders", "stocktakes", "notifications"}
+
+	for _, table := range tables {
+		if !addColumn(db, table, "store_id", "INT NULL") {
+			continue
+		}
+
+		_, err := db.Exec("ALTER TABLE " + table + " ADD FOREIGN KEY (store_id) REFERENCES stores(id)")
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+
+		_, err = db.Exec("UPDATE " + table + " SET store_id = (SELECT MIN(id) FROM stores) WHERE store_id IS NULL")
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+	}
+}
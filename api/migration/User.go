@@ -50,3 +50,22 @@ func UserMigrate(db *sql.DB) {
 	// Pesan sukses jika migrasi berhasil
 	log.Println("Migrasi tabel users berhasil")
 }
+
+// HeadOfficeColumnMigrate menambahkan tanda head_office pada users. Sebelumnya store_id kosong
+// dianggap kantor pusat, sehingga pengguna yang mendaftar sendiri ikut mendapat akses ke semua
+// store. Saat kolom ditambahkan hanya pengguna aktif pertama yang dijadikan kantor pusat;
+// pengguna tanpa store lainnya harus diatur ulang lewat PUT /users/{id}/store.
+func HeadOfficeColumnMigrate(db *sql.DB) {
+	if !addColumn(db, "users", "head_office", "BOOLEAN NOT NULL DEFAULT FALSE") {
+		return
+	}
+
+	_, err := db.Exec(`
+		UPDATE users u
+		JOIN (SELECT MIN(id) AS id FROM users WHERE deleted_at IS NULL) first ON first.id = u.id
+		SET u.head_office = TRUE, u.store_id = NULL`)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+}
@@ -0,0 +1,126 @@
+package responses
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// RequestIDHeader adalah header yang membawa request id. Middleware RequestID mengisinya di
+// response sebelum handler berjalan, sehingga setiap respons error dapat menyertakannya.
+const RequestIDHeader = "X-Request-ID"
+
+// kode error yang stabil untuk dibaca klien; pesan error dapat berubah, kode tidak
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidationFailed = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeTokenExpired     = "token_expired"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeUnprocessable    = "unprocessable"
+	CodeInternal         = "internal_error"
+)
+
+// internalErrorMessage menggantikan pesan error 5xx agar detail internal seperti error SQL tidak
+// sampai ke klien. Pesan aslinya dicatat di log bersama request id.
+const internalErrorMessage = "Terjadi kesalahan pada server, hubungi admin dengan menyertakan request_id"
+
+// FieldError adalah kesalahan validasi pada satu field request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError adalah isi objek error pada respons gagal.
+type APIError struct {
+	Code      string       `json:"code"`
+	Status    int          `json:"status"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// codeForStatus mengembalikan kode error bawaan untuk status HTTP.
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// Error menulis respons error dengan format {"success": false, "message": ..., "error": {...}}.
+// Untuk status 5xx pesan diganti dengan pesan umum dan pesan aslinya hanya dicatat di log.
+func Error(w http.ResponseWriter, apiError APIError) {
+	apiError.RequestID = w.Header().Get(RequestIDHeader)
+	if apiError.Code == "" {
+		apiError.Code = codeForStatus(apiError.Status)
+	}
+	if apiError.Status >= http.StatusInternalServerError {
+		log.Printf("[%s] %d %s: %s\n", apiError.RequestID, apiError.Status, apiError.Code, apiError.Message)
+		apiError.Message = internalErrorMessage
+	}
+
+	type Response struct {
+		Success bool     `json:"success"`
+		Message string   `json:"message"`
+		Error   APIError `json:"error"`
+	}
+
+	responseJSON, err := json.Marshal(Response{
+		Success: false,
+		Message: apiError.Message,
+		Error:   apiError,
+	})
+	if err != nil {
+		http.Error(w, internalErrorMessage, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiError.Status)
+	w.Write(responseJSON)
+}
+
+// CodedErrorResponse mengembalikan respons error dengan kode yang lebih spesifik dari kode
+// bawaan statusnya.
+func CodedErrorResponse(w http.ResponseWriter, code string, message string, status int) {
+	Error(w, APIError{Code: code, Status: status, Message: message})
+}
+
+// InternalError mengembalikan respons 500. Detail err hanya dicatat di log.
+func InternalError(w http.ResponseWriter, err error) {
+	Error(w, APIError{Code: CodeInternal, Status: http.StatusInternalServerError, Message: err.Error()})
+}
+
+// ValidationErrorResponse mengembalikan respons 400 berisi kesalahan per field. Pesan utama
+// diambil dari kesalahan pertama.
+func ValidationErrorResponse(w http.ResponseWriter, fields []FieldError) {
+	message := "Data permintaan tidak valid"
+	if len(fields) > 0 {
+		message = fields[0].Message
+	}
+	Error(w, APIError{Code: CodeValidationFailed, Status: http.StatusBadRequest, Message: message, Fields: fields})
+}
+
+// FieldErrorResponse mengembalikan respons validasi untuk satu field dengan kode "invalid".
+func FieldErrorResponse(w http.ResponseWriter, field string, message string) {
+	ValidationErrorResponse(w, []FieldError{{Field: field, Code: "invalid", Message: message}})
+}
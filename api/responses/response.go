@@ -5,26 +5,9 @@ import (
 	"net/http"
 )
 
-// ErrorResponse mengembalikan respons JSON berupa pesan kesalahan.
+// ErrorResponse mengembalikan respons error dengan kode bawaan sesuai status HTTP.
 func ErrorResponse(w http.ResponseWriter, message string, status int) {
-	type Response struct {
-		Error   bool   `json:"Error"`
-		Message string `json:"message"`
-	}
-
-	response := Response{
-		Error:   false,
-		Message: message,
-	}
-	responseJSON, err := json.Marshal(response)
-	if err != nil {
-		ErrorResponse(w, "Gagal membuat respons JSON", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	w.Write(responseJSON)
+	Error(w, APIError{Status: status, Message: message})
 }
 
 func SuccessResponse(w http.ResponseWriter, message string, data interface{}, status int) {
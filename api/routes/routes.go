@@ -3,6 +3,7 @@ package routes
 import (
 	"golang-api/api/controller"
 	"golang-api/api/middleware"
+	"golang-api/api/responses"
 	"golang-api/config"
 	"net/http"
 
@@ -11,9 +12,19 @@ import (
 
 func SetupRoutes() *mux.Router {
 	r := mux.NewRouter()
+	r.Use(middleware.RequestID)
 
-	// Rute yang tidak memerlukan otentikasi
-	r.HandleFunc("/users", controller.CreateUser).Methods("POST")
+	// rute yang tidak dikenal juga dijawab dengan format error yang sama
+	r.NotFoundHandler = middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responses.ErrorResponse(w, "Endpoint tidak ditemukan", http.StatusNotFound)
+	}))
+	r.MethodNotAllowedHandler = middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responses.ErrorResponse(w, "Metode tidak diizinkan untuk endpoint ini", http.StatusMethodNotAllowed)
+	}))
+
+	// Rute yang tidak memerlukan otentikasi. Pengguna baru hanya dapat dibuat oleh kantor pusat,
+	// kecuali pengguna pertama saat instalasi baru.
+	r.Handle("/users", middleware.OptionalAuth(http.HandlerFunc(controller.CreateUser))).Methods("POST")
 	r.HandleFunc("/users/login", controller.LoginUser).Methods("POST")
 
 	// Router untuk rute dengan dua middleware
@@ -26,20 +37,55 @@ func SetupRoutes() *mux.Router {
 	protectedRoutes.HandleFunc("/users/{id}", controller.DeleteUser).Methods("DELETE")
 	protectedRoutes.HandleFunc("/users/{id}", controller.GetUser).Methods("GET")
 	protectedRoutes.HandleFunc("/users", controller.FetchUser).Methods("GET")
+	protectedRoutes.HandleFunc("/users/{id}/restore", controller.RestoreUser).Methods("POST")
 
 	// Products API
 	protectedRoutes.HandleFunc("/products", controller.CreateProduct).Methods("POST")
 	protectedRoutes.HandleFunc("/products", controller.ListProducts).Methods("GET")
+	protectedRoutes.HandleFunc("/products/search", controller.SearchProducts).Methods("GET")
+	protectedRoutes.HandleFunc("/products/import", controller.ImportProducts).Methods("POST")
 	protectedRoutes.HandleFunc("/products/{id}", controller.DetailProducts).Methods("GET")
 	protectedRoutes.HandleFunc("/products/{id}", controller.UpdateProducts).Methods("PUT")
 	protectedRoutes.HandleFunc("/products/{id}", controller.DeleteProducts).Methods("DELETE")
+	protectedRoutes.HandleFunc("/products/{id}/restore", controller.RestoreProducts).Methods("POST")
+	protectedRoutes.HandleFunc("/products/{id}/options", controller.CreateProductOptions).Methods("POST")
+	protectedRoutes.HandleFunc("/products/{id}/options", controller.ListProductOptions).Methods("GET")
+	protectedRoutes.HandleFunc("/products/{id}/variants", controller.CreateProductVariants).Methods("POST")
+	protectedRoutes.HandleFunc("/products/{id}/variants", controller.ListProductVariants).Methods("GET")
+	protectedRoutes.HandleFunc("/products/{id}/components", controller.SetProductComponents).Methods("PUT")
+	protectedRoutes.HandleFunc("/products/{id}/components", controller.ListProductComponents).Methods("GET")
+	protectedRoutes.HandleFunc("/products/{id}/cost-rollup", controller.RollUpProductCost).Methods("POST")
+	protectedRoutes.HandleFunc("/products/{id}/modifier-groups", controller.ListProductModifierGroups).Methods("GET")
+	protectedRoutes.HandleFunc("/products/by-barcode/{code}", controller.LookupProductBarcode).Methods("GET")
+	protectedRoutes.HandleFunc("/products/{id}/barcode", controller.GenerateProductBarcode).Methods("POST")
+	protectedRoutes.HandleFunc("/products/{id}/barcode", controller.ProductBarcodeLabel).Methods("GET")
+	protectedRoutes.HandleFunc("/products/{id}/tags", controller.SetProductTags).Methods("PUT")
+	protectedRoutes.HandleFunc("/products/{id}/price-history", controller.ProductPriceHistory).Methods("GET")
+
+	// Price Changes API
+	protectedRoutes.HandleFunc("/price-changes", controller.BulkUpdatePrices).Methods("POST")
+	protectedRoutes.HandleFunc("/price-changes", controller.ListPriceChanges).Methods("GET")
+	protectedRoutes.HandleFunc("/price-changes/{id}", controller.DetailPriceChange).Methods("GET")
+	protectedRoutes.HandleFunc("/price-changes/{id}/cancel", controller.CancelPriceChange).Methods("POST")
+
+	// Labels API
+	protectedRoutes.HandleFunc("/labels/templates", controller.ListLabelTemplates).Methods("GET")
+	protectedRoutes.HandleFunc("/labels/shelf", controller.PrintShelfLabels).Methods("POST")
+
+	// Modifiers API
+	protectedRoutes.HandleFunc("/modifier-groups", controller.CreateModifierGroups).Methods("POST")
+	protectedRoutes.HandleFunc("/modifier-groups", controller.ListModifierGroups).Methods("GET")
+	protectedRoutes.HandleFunc("/modifier-groups/{id}/links", controller.LinkModifierGroups).Methods("PUT")
 
 	// Categories API
 	protectedRoutes.HandleFunc("/categories", controller.CreateCategories).Methods("POST")
 	protectedRoutes.HandleFunc("/categories", controller.ListCategories).Methods("GET")
+	protectedRoutes.HandleFunc("/categories/tree", controller.CategoryTree).Methods("GET")
 	protectedRoutes.HandleFunc("/categories/{id}", controller.DetailCategories).Methods("GET")
 	protectedRoutes.HandleFunc("/categories/{id}", controller.UpdateCategories).Methods("PUT")
 	protectedRoutes.HandleFunc("/categories/{id}", controller.DeleteCategories).Methods("DELETE")
+	protectedRoutes.HandleFunc("/categories/{id}/move", controller.MoveCategories).Methods("PUT")
+	protectedRoutes.HandleFunc("/categories/{id}/restore", controller.RestoreCategories).Methods("POST")
 
 	// Payments API
 	protectedRoutes.HandleFunc("/payments", controller.CreatePayment).Methods("POST")
@@ -47,16 +93,76 @@ func SetupRoutes() *mux.Router {
 	protectedRoutes.HandleFunc("/payments/{id}", controller.DetailPayments).Methods("GET")
 	protectedRoutes.HandleFunc("/payments/{id}", controller.UpdatePayments).Methods("PUT")
 	protectedRoutes.HandleFunc("/payments/{id}", controller.DeletePayments).Methods("DELETE")
+	protectedRoutes.HandleFunc("/payments/{id}/restore", controller.RestorePayments).Methods("POST")
 	// Orders API
 	protectedRoutes.HandleFunc("/orders", controller.CreateOrders).Methods("POST")
 	protectedRoutes.HandleFunc("/orders", controller.ListOrders).Methods("GET")
 	protectedRoutes.HandleFunc("/orders/{id}", controller.DetailOrders).Methods("GET")
 
+	// Suppliers API
+	protectedRoutes.HandleFunc("/suppliers", controller.CreateSupplier).Methods("POST")
+	protectedRoutes.HandleFunc("/suppliers", controller.ListSuppliers).Methods("GET")
+	protectedRoutes.HandleFunc("/suppliers/{id}", controller.DetailSuppliers).Methods("GET")
+	protectedRoutes.HandleFunc("/suppliers/{id}", controller.UpdateSuppliers).Methods("PUT")
+	protectedRoutes.HandleFunc("/suppliers/{id}", controller.DeleteSuppliers).Methods("DELETE")
+
+	// Purchase Orders API
+	protectedRoutes.HandleFunc("/purchase-orders", controller.CreatePurchaseOrders).Methods("POST")
+	protectedRoutes.HandleFunc("/purchase-orders", controller.ListPurchaseOrders).Methods("GET")
+	protectedRoutes.HandleFunc("/purchase-orders/{id}", controller.DetailPurchaseOrders).Methods("GET")
+	protectedRoutes.HandleFunc("/purchase-orders/{id}/send", controller.SendPurchaseOrders).Methods("PUT")
+	protectedRoutes.HandleFunc("/purchase-orders/{id}/receive", controller.ReceivePurchaseOrders).Methods("POST")
+
+	// Inventory API
+	protectedRoutes.HandleFunc("/inventory/movements", controller.ListStockMovements).Methods("GET")
+	protectedRoutes.HandleFunc("/inventory/low-stock", controller.ListLowStock).Methods("GET")
+	protectedRoutes.HandleFunc("/products/{id}/reorder", controller.UpdateReorderSettings).Methods("PUT")
+
+	// Stocktakes API
+	protectedRoutes.HandleFunc("/stocktakes", controller.CreateStocktakes).Methods("POST")
+	protectedRoutes.HandleFunc("/stocktakes", controller.ListStocktakes).Methods("GET")
+	protectedRoutes.HandleFunc("/stocktakes/{id}", controller.DetailStocktakes).Methods("GET")
+	protectedRoutes.HandleFunc("/stocktakes/{id}/counts", controller.SubmitStocktakeCounts).Methods("POST")
+	protectedRoutes.HandleFunc("/stocktakes/{id}/approve", controller.ApproveStocktakes).Methods("POST")
+	protectedRoutes.HandleFunc("/stocktakes/{id}/cancel", controller.CancelStocktakes).Methods("POST")
+	protectedRoutes.HandleFunc("/stocktakes/{id}/variance-report", controller.StocktakeVarianceReport).Methods("GET")
+
+	// Stores API
+	protectedRoutes.HandleFunc("/stores", controller.CreateStore).Methods("POST")
+	protectedRoutes.HandleFunc("/stores", controller.ListStores).Methods("GET")
+	protectedRoutes.HandleFunc("/stores/{id}", controller.DetailStores).Methods("GET")
+	protectedRoutes.HandleFunc("/stores/{id}", controller.UpdateStores).Methods("PUT")
+	protectedRoutes.HandleFunc("/users/{id}/store", controller.AssignUserStore).Methods("PUT")
+	protectedRoutes.HandleFunc("/products/{id}/store-price", controller.UpdateStorePrice).Methods("PUT")
+
+	// Stock Transfers API
+	protectedRoutes.HandleFunc("/stock-transfers", controller.CreateStockTransfers).Methods("POST")
+	protectedRoutes.HandleFunc("/stock-transfers", controller.ListStockTransfers).Methods("GET")
+	protectedRoutes.HandleFunc("/stock-transfers/{id}", controller.DetailStockTransfers).Methods("GET")
+	protectedRoutes.HandleFunc("/stock-transfers/{id}/dispatch", controller.DispatchStockTransfers).Methods("POST")
+	protectedRoutes.HandleFunc("/stock-transfers/{id}/in-transit", controller.InTransitStockTransfers).Methods("PUT")
+	protectedRoutes.HandleFunc("/stock-transfers/{id}/receive", controller.ReceiveStockTransfers).Methods("POST")
+
+	// Notifications API
+	protectedRoutes.HandleFunc("/notifications", controller.ListNotifications).Methods("GET")
+	protectedRoutes.HandleFunc("/notifications/{id}/read", controller.ReadNotifications).Methods("PUT")
+
+	// Reports API
+	protectedRoutes.HandleFunc("/reports/sales", controller.SalesReport).Methods("GET")
+	protectedRoutes.HandleFunc("/reports/sales/heatmap", controller.SalesHeatmap).Methods("GET")
+	protectedRoutes.HandleFunc("/reports/margin", controller.MarginReport).Methods("GET")
+
+	// Admin API
+	protectedRoutes.HandleFunc("/admin/purge", controller.PurgeDeleted).Methods("POST")
+
 	return r
 }
 
 func RunServer() {
 	config.InitDB()
+	controller.StartLowStockWorker()
+	controller.StartPurgeWorker()
+	controller.StartPriceChangeWorker()
 	router := SetupRoutes()
 
 	// Mulai server HTTP dengan router yang telah dikonfigurasi
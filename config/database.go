@@ -40,12 +40,47 @@ func InitDB() *sql.DB {
 	log.Info().Msg("Terhubung ke database!")
 
 	// Panggil fungsi migrate untuk inisialisasi migrasi database
+	migration.StoreMigration(db)   // Store -> User, Order, ProductStock
 	migration.UserMigrate(db)      // User -> Order
 	migration.PaymentMigration(db) // Payment -> Order
 	migration.CategorieMigrate(db) // Categories -> Product
 	migration.ProductMigrate(db)   // Product -> OrderProduct
 	migration.OrderMigration(db)   // Order -> OrderProduct
 	migration.OrderProductMigration(db)
+	migration.ProductColumnMigrate(db)
+	migration.SupplierMigration(db)          // Supplier -> PurchaseOrder
+	migration.PurchaseOrderMigration(db)     // PurchaseOrder -> PurchaseOrderItem
+	migration.PurchaseOrderItemMigration(db) // PurchaseOrderItem
+	migration.StockMovementMigration(db)
+	migration.NotificationMigration(db)
+	migration.StocktakeMigration(db)     // Stocktake -> StocktakeItem
+	migration.StocktakeItemMigration(db) // StocktakeItem
+	migration.ProductStockMigration(db)
+	migration.StoreColumnMigrate(db)
+	migration.StockTransferMigration(db)            // StockTransfer -> StockTransferItem
+	migration.StockTransferItemMigration(db)        // StockTransferItem -> StockTransferDiscrepancy
+	migration.StockTransferDiscrepancyMigration(db) // StockTransferDiscrepancy
+	migration.ProductOptionMigration(db)            // ProductOption -> ProductOptionValue
+	migration.ProductOptionValueMigration(db)       // ProductOptionValue -> ProductVariantValue
+	migration.ProductVariantValueMigration(db)      // ProductVariantValue
+	migration.ProductStockColumnMigrate(db)
+	migration.QuantityColumnMigrate(db)
+	migration.ProductComponentMigration(db)
+	migration.ModifierGroupMigration(db)        // ModifierGroup -> Modifier, ModifierGroupLink
+	migration.ModifierMigration(db)             // Modifier -> OrderProductModifier
+	migration.ModifierGroupLinkMigration(db)    // ModifierGroupLink
+	migration.OrderProductModifierMigration(db) // OrderProductModifier
+	migration.CategorieColumnMigrate(db)
+	migration.SoftDeleteColumnMigrate(db)
+	migration.OrderProductColumnMigrate(db)
+	migration.OrderColumnMigrate(db)
+	migration.SearchIndexMigrate(db)
+	migration.CostLayerMigration(db)
+	migration.ProductTagMigration(db)
+	migration.PriceHistoryMigration(db)
+	migration.PriceChangeMigration(db)     // PriceChange -> PriceChangeItem
+	migration.PriceChangeItemMigration(db) // PriceChangeItem
+	migration.HeadOfficeColumnMigrate(db)
 
 	DB = db
 